@@ -53,5 +53,5 @@ func printNextSteps(answers *installer.Answers) {
 	fmt.Printf("Go ahead and run\n\n")
 	fmt.Printf("    %schapper run%s\n", constants.ColorGreen, constants.ColorReset)
 	fmt.Printf("    %schapper run --config path/to/config%s\n\n", constants.ColorGreen, constants.ColorReset)
-	fmt.Printf("Visit https://%s/register to register your new account!\n", answers.InstanceDomain)
+	fmt.Printf("Visit https://%s/login and log in with your admin account %s%s%s!\n", answers.InstanceDomain, constants.ColorCyan, answers.AdminUsername, constants.ColorReset)
 }
@@ -0,0 +1,129 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/constants"
+	"chapper.dev/server/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateUpSteps   int
+	migrateDownSteps int
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate applies, reverts or lists database schema migrations",
+	Long:  ``,
+}
+
+// migrateUpCmd represents the migrate up command
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Applies pending migrations",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		db := openStore()
+
+		applied, err := db.MigrateUp(migrateUpSteps)
+		for _, migration := range applied {
+			fmt.Printf("%s>%s Applied %03d_%s\n", constants.ColorGreen, constants.ColorReset, migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if len(applied) == 0 {
+			fmt.Println("Nothing to migrate, the database schema is up to date")
+		}
+	},
+}
+
+// migrateDownCmd represents the migrate down command
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Reverts applied migrations",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		db := openStore()
+
+		reverted, err := db.MigrateDown(migrateDownSteps)
+		for _, migration := range reverted {
+			fmt.Printf("%s>%s Reverted %03d_%s\n", constants.ColorGreen, constants.ColorReset, migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if len(reverted) == 0 {
+			fmt.Println("Nothing to revert")
+		}
+	},
+}
+
+// migrateStatusCmd represents the migrate status command
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Lists all migrations and if they are applied",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		db := openStore()
+
+		states, err := db.MigrationStatus()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		for _, state := range states {
+			if state.Applied {
+				fmt.Printf("%03d_%-30s %sapplied%s  %s\n", state.Version, state.Name, constants.ColorGreen, constants.ColorReset, state.AppliedAt.Format("2006-01-02 15:04:05"))
+				continue
+			}
+			fmt.Printf("%03d_%-30s %spending%s\n", state.Version, state.Name, constants.ColorCyan, constants.ColorReset)
+		}
+	},
+}
+
+func init() {
+	migrateCmd.PersistentFlags().StringVarP(&configFilePath, "config", "c", "", "Path to your config file")
+	cobra.MarkFlagRequired(migrateCmd.PersistentFlags(), "config")
+
+	migrateUpCmd.Flags().IntVarP(&migrateUpSteps, "steps", "s", 0, "Number of migrations to apply, 0 applies all")
+	migrateDownCmd.Flags().IntVarP(&migrateDownSteps, "steps", "s", 1, "Number of migrations to revert")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// openStore reads the config and opens the configured store. It exits if either fails
+func openStore() *store.SQL {
+	cfg := config.New()
+	err := cfg.Read(configFilePath)
+	if err != nil {
+		fmt.Printf("Failed to read config file: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := store.New(cfg.Store.Type, cfg.Store)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return db
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/constants"
+	"chapper.dev/server/internal/modules/hash"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tuneHashTarget      time.Duration
+	tuneHashMaxMemory   uint32
+	tuneHashParallelism uint8
+	tuneHashWrite       bool
+)
+
+// tuneHashCmd represents the tune-hash command
+var tuneHashCmd = &cobra.Command{
+	Use:   "tune-hash",
+	Short: "Tune-hash benchmarks Argon2 on this machine and picks the password hash parameters",
+	Long: `Tune-hash benchmarks Argon2id on this machine. It picks the most memory up to
+--max-memory and then the most iterations which still hash a password within --target.
+The parameters are printed and written into the [hash] section of the config file
+with --write. Existing password hashes are upgraded on the next login.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if tuneHashWrite && configFilePath == "" {
+			fmt.Println("The config file path is required to write the parameters, use --config")
+			os.Exit(1)
+		}
+
+		if tuneHashTarget <= 0 || tuneHashMaxMemory == 0 || tuneHashParallelism == 0 {
+			fmt.Println("The target, max memory and parallelism have to be greater than 0")
+			os.Exit(1)
+		}
+
+		fmt.Printf("Benchmarking Argon2id with a target of %s, at most %d MiB memory and a parallelism of %d...\n",
+			tuneHashTarget, tuneHashMaxMemory, tuneHashParallelism)
+
+		c, took := hash.TuneArgon2(tuneHashTarget, tuneHashMaxMemory*constants.Argon2Kibibyte, tuneHashParallelism)
+		if took > tuneHashTarget {
+			fmt.Printf("Hashing with the smallest parameters already takes %s, consider a higher target\n", took.Round(time.Millisecond))
+		}
+
+		fmt.Printf("%s>%s One hash takes %s with these parameters:\n\n", constants.ColorGreen, constants.ColorReset, took.Round(time.Millisecond))
+		fmt.Println("[hash]")
+		fmt.Printf("ARGON2_MEMORY      = %d # KiB\n", c.Memory)
+		fmt.Printf("ARGON2_ITERATIONS  = %d\n", c.Iterations)
+		fmt.Printf("ARGON2_PARALLELISM = %d\n", c.Parallelism)
+
+		if !tuneHashWrite {
+			return
+		}
+
+		err := config.WriteHash(configFilePath, config.HashOptions{
+			Argon2Memory:      c.Memory,
+			Argon2Iterations:  c.Iterations,
+			Argon2Parallelism: c.Parallelism,
+		})
+		if err != nil {
+			fmt.Printf("Failed to write config file: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n%s>%s Wrote parameters to %s\n", constants.ColorGreen, constants.ColorReset, configFilePath)
+	},
+}
+
+func init() {
+	tuneHashCmd.Flags().StringVarP(&configFilePath, "config", "c", "", "Path to your config file")
+	tuneHashCmd.Flags().DurationVarP(&tuneHashTarget, "target", "t", 250*time.Millisecond, "Target duration of one password hash")
+	tuneHashCmd.Flags().Uint32VarP(&tuneHashMaxMemory, "max-memory", "m", 1024, "Maximum memory of one password hash in MiB")
+	tuneHashCmd.Flags().Uint8VarP(&tuneHashParallelism, "parallelism", "p", hash.DefaultArgon2Config.Parallelism, "Number of threads of one password hash")
+	tuneHashCmd.Flags().BoolVarP(&tuneHashWrite, "write", "w", false, "Write the parameters into the config file")
+
+	rootCmd.AddCommand(tuneHashCmd)
+}
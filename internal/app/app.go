@@ -9,12 +9,16 @@ package app
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"chapper.dev/server/internal/config"
 	"chapper.dev/server/internal/log"
+	"chapper.dev/server/internal/modules/jwt"
 	"chapper.dev/server/internal/router"
+	"chapper.dev/server/internal/router/authz"
 	"chapper.dev/server/internal/router/handlers"
 	"chapper.dev/server/internal/store"
+	"chapper.dev/server/internal/store/memory"
 	"chapper.dev/server/internal/transport/turn"
 )
 
@@ -22,11 +26,13 @@ var appCtx = log.NewContext("app")
 
 // App wraps all dependencies to start the server
 type App struct {
-	config *config.Config
-	logger *log.Logger
-	store  *store.Store
-	router *router.Router
-	turn   *turn.TURN
+	config  *config.Config
+	logger  *log.Logger
+	store   store.Store
+	router  *router.Router
+	handler *handlers.Handler
+	turn    *turn.TURN
+	cancel  context.CancelFunc
 }
 
 // New returns a new app
@@ -42,21 +48,21 @@ func New(configFilePath string) (*App, error) {
 		return nil, fmt.Errorf("[E] [%s] failed to setup logger: %v", appCtx, err)
 	}
 
-	db, err := store.New("mysql", cfg.Store)
+	db, err := newStore(cfg.Store)
 	if err != nil {
 		logger.Errorc(appCtx, err)
 		return nil, err
 	}
 
-	err = db.Migrate()
+	keys, err := jwt.NewKeyring(db, cfg.Router.KeyringOptions())
 	if err != nil {
 		logger.Errorc(appCtx, err)
 		return nil, err
 	}
 
 	rauter := router.New(cfg, logger)
-	handle := handlers.New(db, cfg, logger)
-	rauter.AddRoutes(handle)
+	handle := handlers.New(db, cfg, keys, logger)
+	rauter.AddRoutes(handle, authz.New(db, logger))
 
 	turnServer, err := turn.New(cfg.Turn.PublicIP, cfg.Router.Domain, "udp4", cfg.Turn.Port)
 	if err != nil {
@@ -65,14 +71,37 @@ func New(configFilePath string) (*App, error) {
 	}
 
 	return &App{
-		config: cfg,
-		logger: logger,
-		store:  db,
-		router: rauter,
-		turn:   turnServer,
+		config:  cfg,
+		logger:  logger,
+		store:   db,
+		router:  rauter,
+		handler: handle,
+		turn:    turnServer,
 	}, nil
 }
 
+// newStore returns the configured store. The in-memory store is used when the store type
+// is 'memory', which runs the server without a database (demo mode)
+func newStore(options config.StoreOptions) (store.Store, error) {
+	if strings.ToLower(options.Type) == "memory" {
+		return memory.New(), nil
+	}
+
+	db, err := store.New(options.Type, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse to start with an outdated schema, migrations are run explicitly via the
+	// 'migrate' command
+	err = db.CheckSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
 // Run runs the app, more specifically the turn server and router
 func (a *App) Run() error {
 	err := a.turn.Run()
@@ -81,12 +110,20 @@ func (a *App) Run() error {
 		return err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.handler.RunJobs(ctx)
+
 	a.router.Run()
 	return nil
 }
 
 // Stop gracefully stops the app
 func (a *App) Stop(ctx context.Context) error {
+	if a.cancel != nil {
+		a.cancel()
+	}
+
 	err := a.turn.Close()
 	if err != nil {
 		a.logger.Errorc(appCtx, err)
@@ -10,7 +10,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"runtime"
+	"time"
 
+	"chapper.dev/server/internal/modules/hash"
+	"chapper.dev/server/internal/modules/jwt"
+	"chapper.dev/server/internal/modules/ldap"
+	"chapper.dev/server/internal/modules/limit"
+	"chapper.dev/server/internal/modules/mail"
+	"chapper.dev/server/internal/modules/oidc"
+	"chapper.dev/server/internal/modules/twofa"
 	"chapper.dev/server/internal/utils"
 
 	"github.com/BurntSushi/toml"
@@ -21,6 +29,11 @@ type Config struct {
 	Turn    TurnOptions
 	Store   StoreOptions
 	Router  RouterOptions
+	Mail    MailOptions
+	Limit   LimitOptions
+	Hash    HashOptions
+	OIDC    OIDCOptions
+	LDAP    LDAPOptions
 	General GeneralOptions
 }
 
@@ -36,27 +49,258 @@ type TurnOptions struct {
 }
 
 type StoreOptions struct {
+	Type     string `toml:"TYPE"`
 	User     string `toml:"USER"`
 	Password string `toml:"PASSWORD"`
 	Database string `toml:"DATABASE"`
 	Host     string `toml:"HOST"`
 	Port     int    `toml:"PORT"`
+	SSLMode  string `toml:"SSL_MODE"`
+	Path     string `toml:"PATH"`
 }
 
 type RouterOptions struct {
-	Port       int    `toml:"PORT"`
-	Domain     string `toml:"DOMAIN"`
-	WebPath    string `toml:"WEB_PATH"`
-	AvatarPath string `toml:"AVATAR_PATH"`
-	JWTSecret  string `toml:"JWT_SIGNING_KEY"`
-	OTPIssuer  string `toml:"OTP_ISSUER"`
-	EnableGZIP bool   `toml:"ENABLE_GZIP"`
+	Port            int      `toml:"PORT"`
+	Domain          string   `toml:"DOMAIN"`
+	WebPath         string   `toml:"WEB_PATH"`
+	AvatarPath      string   `toml:"AVATAR_PATH"`
+	JWTAlgorithm    string   `toml:"JWT_ALGORITHM"`
+	JWTSecret       string   `toml:"JWT_SIGNING_KEY"`
+	JWTKeyRotation  Duration `toml:"JWT_KEY_ROTATION"`
+	AccessTokenTTL  Duration `toml:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL Duration `toml:"REFRESH_TOKEN_TTL"`
+	OTPIssuer       string   `toml:"OTP_ISSUER"`
+	OTPAlgorithm    string   `toml:"OTP_ALGORITHM"`
+	OTPDigits       int      `toml:"OTP_DIGITS"`
+	OTPPeriod       uint     `toml:"OTP_PERIOD"`
+	OTPSkew         uint     `toml:"OTP_SKEW"`
+	WebAuthnRPID    string   `toml:"WEBAUTHN_RP_ID"`
+	WebAuthnOrigin  string   `toml:"WEBAUTHN_ORIGIN"`
+	EnableGZIP      bool     `toml:"ENABLE_GZIP"`
+}
+
+const (
+	// DefaultAccessTokenTTL is the lifetime of access tokens if ACCESS_TOKEN_TTL is
+	// not set
+	DefaultAccessTokenTTL = 15 * time.Minute
+
+	// DefaultRefreshTokenTTL is the lifetime of refresh tokens if REFRESH_TOKEN_TTL
+	// is not set
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type MailOptions struct {
+	Driver   string `toml:"DRIVER"`
+	From     string `toml:"FROM"`
+	Host     string `toml:"HOST"`
+	Port     int    `toml:"PORT"`
+	User     string `toml:"USER"`
+	Password string `toml:"PASSWORD"`
+	TLS      bool   `toml:"TLS"`
+	Path     string `toml:"PATH"`
+	URL      string `toml:"URL"`
+}
+
+// MailerOptions returns the options of the mailer
+func (o MailOptions) MailerOptions() mail.Options {
+	return mail.Options{
+		Driver:   o.Driver,
+		From:     o.From,
+		Host:     o.Host,
+		Port:     o.Port,
+		User:     o.User,
+		Password: o.Password,
+		TLS:      o.TLS,
+		Path:     o.Path,
+	}
+}
+
+type LimitOptions struct {
+	AccountAttempts int      `toml:"ACCOUNT_ATTEMPTS"`
+	IPAttempts      int      `toml:"IP_ATTEMPTS"`
+	Delay           Duration `toml:"DELAY"`
+	Lockout         Duration `toml:"LOCKOUT"`
+	Reset           Duration `toml:"RESET"`
+}
+
+// DefaultIPAttempts is the number of failed attempts per IP address which are not
+// delayed if IP_ATTEMPTS is not set. Many users can share one address
+const DefaultIPAttempts = 20
+
+// AccountOptions returns the options of the limiter of failed attempts per account
+func (o LimitOptions) AccountOptions() limit.Options {
+	return limit.Options{
+		Attempts: o.AccountAttempts,
+		Delay:    o.Delay.Duration,
+		Lockout:  o.Lockout.Duration,
+		Reset:    o.Reset.Duration,
+	}
+}
+
+// IPOptions returns the options of the limiter of failed attempts per IP address
+func (o LimitOptions) IPOptions() limit.Options {
+	return limit.Options{
+		Attempts: o.IPAttempts,
+		Delay:    o.Delay.Duration,
+		Lockout:  o.Lockout.Duration,
+		Reset:    o.Reset.Duration,
+	}
+}
+
+type HashOptions struct {
+	Argon2Memory      uint32 `toml:"ARGON2_MEMORY"`
+	Argon2Iterations  uint32 `toml:"ARGON2_ITERATIONS"`
+	Argon2Parallelism uint8  `toml:"ARGON2_PARALLELISM"`
+}
+
+// Argon2Config returns the config of new password hashes. Existing hashes with other
+// parameters are replaced on the next login
+func (o HashOptions) Argon2Config() hash.Argon2Config {
+	return hash.Argon2Config{
+		Memory:      o.Argon2Memory,
+		Iterations:  o.Argon2Iterations,
+		Parallelism: o.Argon2Parallelism,
+		SaltLength:  hash.DefaultArgon2Config.SaltLength,
+		KeyLength:   hash.DefaultArgon2Config.KeyLength,
+	}
+}
+
+type OIDCOptions struct {
+	Issuer        string            `toml:"ISSUER"`
+	ClientID      string            `toml:"CLIENT_ID"`
+	ClientSecret  string            `toml:"CLIENT_SECRET"`
+	RedirectURL   string            `toml:"REDIRECT_URL"`
+	Scopes        []string          `toml:"SCOPES"`
+	UsernameClaim string            `toml:"USERNAME_CLAIM"`
+	RolesClaim    string            `toml:"ROLES_CLAIM"`
+	RoleMapping   map[string]string `toml:"ROLE_MAPPING"`
+}
+
+const (
+	// DefaultUsernameClaim is the claim of the ID token which becomes the username of
+	// new users if USERNAME_CLAIM is not set
+	DefaultUsernameClaim = "preferred_username"
+
+	// DefaultRolesClaim is the claim of the ID token which is mapped to roles if
+	// ROLES_CLAIM is not set
+	DefaultRolesClaim = "groups"
+)
+
+// DefaultOIDCScopes are requested from the identity provider if SCOPES is not set
+var DefaultOIDCScopes = []string{"openid", "profile", "email"}
+
+// Enabled returns if single sign-on is configured
+func (o OIDCOptions) Enabled() bool {
+	return o.Issuer != ""
+}
+
+// ProviderOptions returns the options of the identity provider
+func (o OIDCOptions) ProviderOptions() oidc.Options {
+	return oidc.Options{
+		Issuer:       o.Issuer,
+		ClientID:     o.ClientID,
+		ClientSecret: o.ClientSecret,
+		RedirectURL:  o.RedirectURL,
+		Scopes:       o.Scopes,
+	}
+}
+
+type LDAPOptions struct {
+	URL               string            `toml:"URL"`
+	StartTLS          bool              `toml:"START_TLS"`
+	BindDN            string            `toml:"BIND_DN"`
+	BindPassword      string            `toml:"BIND_PASSWORD"`
+	BaseDN            string            `toml:"BASE_DN"`
+	UserFilter        string            `toml:"USER_FILTER"`
+	UsernameAttribute string            `toml:"USERNAME_ATTRIBUTE"`
+	EmailAttribute    string            `toml:"EMAIL_ATTRIBUTE"`
+	GroupAttribute    string            `toml:"GROUP_ATTRIBUTE"`
+	RoleMapping       map[string]string `toml:"ROLE_MAPPING"`
+	SyncInterval      Duration          `toml:"SYNC_INTERVAL"`
+}
+
+const (
+	// DefaultLDAPUsernameAttribute holds the login name if USERNAME_ATTRIBUTE is not set
+	DefaultLDAPUsernameAttribute = "uid"
+
+	// DefaultLDAPSyncInterval is the time between two syncs of the directory if
+	// SYNC_INTERVAL is not set
+	DefaultLDAPSyncInterval = time.Hour
+
+	// DefaultMaxBots is the number of bots every user can create if MAX_BOTS is not set
+	DefaultMaxBots = 5
+)
+
+// Enabled returns if the LDAP directory is configured
+func (o LDAPOptions) Enabled() bool {
+	return o.URL != ""
+}
+
+// DirectoryOptions returns the options of the LDAP directory
+func (o LDAPOptions) DirectoryOptions() ldap.Options {
+	return ldap.Options{
+		URL:               o.URL,
+		StartTLS:          o.StartTLS,
+		BindDN:            o.BindDN,
+		BindPassword:      o.BindPassword,
+		BaseDN:            o.BaseDN,
+		UserFilter:        o.UserFilter,
+		UsernameAttribute: o.UsernameAttribute,
+		EmailAttribute:    o.EmailAttribute,
+		GroupAttribute:    o.GroupAttribute,
+	}
 }
 
 type GeneralOptions struct {
-	Name           string `toml:"NAME"`
-	EnableRegister bool   `toml:"ENABLE_REGISTER"`
-	DisableBanner  bool   `toml:"DISABLE_BANNER"`
+	Name                 string   `toml:"NAME"`
+	EnableRegister       bool     `toml:"ENABLE_REGISTER"`
+	Registration         string   `toml:"REGISTRATION"`
+	RequireVerifiedEmail bool     `toml:"REQUIRE_VERIFIED_EMAIL"`
+	Authenticators       []string `toml:"AUTHENTICATORS"`
+	DisablePasswordLogin bool     `toml:"DISABLE_PASSWORD_LOGIN"`
+	MaxBots              int      `toml:"MAX_BOTS"`
+	DisableBanner        bool     `toml:"DISABLE_BANNER"`
+}
+
+const (
+	// RegistrationOpen allows everyone to register
+	RegistrationOpen = "open"
+
+	// RegistrationClosed allows nobody to register
+	RegistrationClosed = "closed"
+
+	// RegistrationInvite allows users with a valid invite to register, they join the
+	// server of the invite
+	RegistrationInvite = "invite"
+)
+
+const (
+	// AuthenticatorLocal checks passwords against the password hashes of users
+	AuthenticatorLocal = "local"
+
+	// AuthenticatorLDAP checks passwords by binding as the user at the LDAP directory
+	AuthenticatorLDAP = "ldap"
+)
+
+// KeyringOptions returns the options of the keyring which signs access tokens. Retired
+// keys verify tokens as long as access tokens are valid
+func (o RouterOptions) KeyringOptions() jwt.Options {
+	return jwt.Options{
+		Algorithm: o.JWTAlgorithm,
+		Secret:    o.JWTSecret,
+		Rotation:  o.JWTKeyRotation.Duration,
+		Retention: o.AccessTokenTTL.Duration,
+	}
+}
+
+// OTPOptions returns the TOTP options used for new 2FA enrollments
+func (o RouterOptions) OTPOptions() twofa.Options {
+	return twofa.Options{
+		Algorithm: o.OTPAlgorithm,
+		Digits:    o.OTPDigits,
+		Period:    o.OTPPeriod,
+		Skew:      o.OTPSkew,
+	}
 }
 
 // New returns a new config struct
@@ -73,24 +317,52 @@ func NewDefault() *Config {
 				Prefix: "Chapper",
 			},
 			Store: StoreOptions{
+				Type:     "mysql",
 				User:     "root",
 				Password: "",
 				Database: "chapper",
 				Host:     "127.0.0.1",
 				Port:     3306,
+				SSLMode:  "disable",
+				Path:     "/var/lib/chapper/chapper.db",
 			},
 			Router: RouterOptions{
-				Port:       8080,
-				Domain:     "",
-				WebPath:    "/var/www/chapper/app",
-				AvatarPath: "/var/www/chapper/avatar",
-				JWTSecret:  "",
-				OTPIssuer:  "Chapper",
-				EnableGZIP: true,
+				Port:            8080,
+				Domain:          "",
+				WebPath:         "/var/www/chapper/app",
+				AvatarPath:      "/var/www/chapper/avatar",
+				JWTAlgorithm:    jwt.AlgorithmEdDSA,
+				JWTSecret:       "",
+				JWTKeyRotation:  Duration{jwt.DefaultRotation},
+				AccessTokenTTL:  Duration{DefaultAccessTokenTTL},
+				RefreshTokenTTL: Duration{DefaultRefreshTokenTTL},
+				OTPIssuer:       "Chapper",
+				OTPAlgorithm:    twofa.DefaultOptions.Algorithm,
+				OTPDigits:       twofa.DefaultOptions.Digits,
+				OTPPeriod:       twofa.DefaultOptions.Period,
+				OTPSkew:         twofa.DefaultOptions.Skew,
+				EnableGZIP:      true,
+			},
+			Mail: MailOptions{
+				Driver: mail.DriverNoop,
+			},
+			Limit: LimitOptions{
+				AccountAttempts: limit.DefaultOptions.Attempts,
+				IPAttempts:      DefaultIPAttempts,
+				Delay:           Duration{limit.DefaultOptions.Delay},
+				Lockout:         Duration{limit.DefaultOptions.Lockout},
+				Reset:           Duration{limit.DefaultOptions.Reset},
+			},
+			Hash: HashOptions{
+				Argon2Memory:      hash.DefaultArgon2Config.Memory,
+				Argon2Iterations:  hash.DefaultArgon2Config.Iterations,
+				Argon2Parallelism: hash.DefaultArgon2Config.Parallelism,
 			},
 			General: GeneralOptions{
 				Name:           "Chapper",
 				EnableRegister: true,
+				Registration:   RegistrationOpen,
+				MaxBots:        DefaultMaxBots,
 				DisableBanner:  false,
 			},
 		}
@@ -102,24 +374,52 @@ func NewDefault() *Config {
 			Prefix: "Chapper",
 		},
 		Store: StoreOptions{
+			Type:     "mysql",
 			User:     "",
 			Password: "",
 			Database: "",
 			Host:     "",
 			Port:     3306,
+			SSLMode:  "disable",
+			Path:     "chapper.db",
 		},
 		Router: RouterOptions{
-			Port:       8080,
-			Domain:     "",
-			WebPath:    "",
-			AvatarPath: "",
-			JWTSecret:  "",
-			OTPIssuer:  "Chapper",
-			EnableGZIP: true,
+			Port:            8080,
+			Domain:          "",
+			WebPath:         "",
+			AvatarPath:      "",
+			JWTAlgorithm:    jwt.AlgorithmEdDSA,
+			JWTSecret:       "",
+			JWTKeyRotation:  Duration{jwt.DefaultRotation},
+			AccessTokenTTL:  Duration{DefaultAccessTokenTTL},
+			RefreshTokenTTL: Duration{DefaultRefreshTokenTTL},
+			OTPIssuer:       "Chapper",
+			OTPAlgorithm:    twofa.DefaultOptions.Algorithm,
+			OTPDigits:       twofa.DefaultOptions.Digits,
+			OTPPeriod:       twofa.DefaultOptions.Period,
+			OTPSkew:         twofa.DefaultOptions.Skew,
+			EnableGZIP:      true,
+		},
+		Mail: MailOptions{
+			Driver: mail.DriverNoop,
+		},
+		Limit: LimitOptions{
+			AccountAttempts: limit.DefaultOptions.Attempts,
+			IPAttempts:      DefaultIPAttempts,
+			Delay:           Duration{limit.DefaultOptions.Delay},
+			Lockout:         Duration{limit.DefaultOptions.Lockout},
+			Reset:           Duration{limit.DefaultOptions.Reset},
+		},
+		Hash: HashOptions{
+			Argon2Memory:      hash.DefaultArgon2Config.Memory,
+			Argon2Iterations:  hash.DefaultArgon2Config.Iterations,
+			Argon2Parallelism: hash.DefaultArgon2Config.Parallelism,
 		},
 		General: GeneralOptions{
 			Name:           "Chapper",
 			EnableRegister: true,
+			Registration:   RegistrationOpen,
+			MaxBots:        DefaultMaxBots,
 			DisableBanner:  false,
 		},
 	}
@@ -132,11 +432,26 @@ func (c *Config) Read(path string) error {
 		return err
 	}
 
-	_, err = toml.DecodeFile(path, c)
+	md, err := toml.DecodeFile(path, c)
 	if err != nil {
 		return err
 	}
 
+	// A skew of 0 is valid, so the default can only be applied if it is not set
+	if !md.IsDefined("router", "OTP_SKEW") {
+		c.Router.OTPSkew = twofa.DefaultOptions.Skew
+	}
+
+	// A sync interval of 0 disables the sync
+	if !md.IsDefined("ldap", "SYNC_INTERVAL") {
+		c.LDAP.SyncInterval.Duration = DefaultLDAPSyncInterval
+	}
+
+	// A maximum of 0 bots disables bots
+	if !md.IsDefined("general", "MAX_BOTS") {
+		c.General.MaxBots = DefaultMaxBots
+	}
+
 	// Validate the config
 	return c.Validate()
 }
@@ -158,14 +473,20 @@ func (c *Config) Write(path string) error {
 
 // Validate validates the config
 func (c *Config) Validate() error {
-	if c.Router.JWTSecret == "" {
-		// TODO: Use logging
-		fmt.Println("WARNING [Config] JWT_SIGNING_KEY cannot be empty. Fallback to generated one")
-		key, err := utils.RandomCryptoString(32)
-		if err != nil {
-			return err
-		}
-		c.Router.JWTSecret = key
+	if c.Router.JWTAlgorithm == "" {
+		c.Router.JWTAlgorithm = jwt.AlgorithmEdDSA
+	}
+
+	if c.Router.JWTKeyRotation.Duration <= 0 {
+		c.Router.JWTKeyRotation.Duration = jwt.DefaultRotation
+	}
+
+	if c.Router.AccessTokenTTL.Duration <= 0 {
+		c.Router.AccessTokenTTL.Duration = DefaultAccessTokenTTL
+	}
+
+	if c.Router.RefreshTokenTTL.Duration <= 0 {
+		c.Router.RefreshTokenTTL.Duration = DefaultRefreshTokenTTL
 	}
 
 	if c.Router.OTPIssuer == "" {
@@ -174,6 +495,190 @@ func (c *Config) Validate() error {
 		c.Router.OTPIssuer = "Chapper"
 	}
 
+	if c.Router.OTPAlgorithm == "" {
+		c.Router.OTPAlgorithm = twofa.DefaultOptions.Algorithm
+	}
+
+	if c.Router.OTPDigits == 0 {
+		c.Router.OTPDigits = twofa.DefaultOptions.Digits
+	}
+
+	if c.Router.OTPPeriod == 0 {
+		c.Router.OTPPeriod = twofa.DefaultOptions.Period
+	}
+
+	if c.General.Name == "" {
+		c.General.Name = "Chapper"
+	}
+
+	if c.Router.WebAuthnRPID == "" {
+		// The relying party ID is the domain the client is served from
+		c.Router.WebAuthnRPID = c.Router.Domain
+		if c.Router.WebAuthnRPID == "" {
+			c.Router.WebAuthnRPID = "localhost"
+		}
+	}
+
+	if c.Router.WebAuthnOrigin == "" {
+		c.Router.WebAuthnOrigin = "https://" + c.Router.WebAuthnRPID
+	}
+
+	// A random key would log out all users on every restart, so HS256 requires a
+	// secret
+	err := c.Router.KeyringOptions().Validate()
+	if err != nil {
+		return fmt.Errorf("[Config] %w", err)
+	}
+
+	err = c.Router.OTPOptions().Validate()
+	if err != nil {
+		return fmt.Errorf("[Config] %w", err)
+	}
+
+	if c.Mail.Driver == "" {
+		c.Mail.Driver = mail.DriverNoop
+	}
+
+	if c.Mail.From == "" && c.Router.Domain != "" {
+		c.Mail.From = "noreply@" + c.Router.Domain
+	}
+
+	if c.Mail.URL == "" {
+		// Links in mails point to the web client
+		c.Mail.URL = fmt.Sprintf("http://localhost:%d", c.Router.Port)
+		if c.Router.Domain != "" {
+			c.Mail.URL = "https://" + c.Router.Domain
+		}
+	}
+
+	err = c.Mail.MailerOptions().Validate()
+	if err != nil {
+		return fmt.Errorf("[Config] %w", err)
+	}
+
+	if c.Limit.AccountAttempts <= 0 {
+		c.Limit.AccountAttempts = limit.DefaultOptions.Attempts
+	}
+
+	if c.Limit.IPAttempts <= 0 {
+		c.Limit.IPAttempts = DefaultIPAttempts
+	}
+
+	if c.Limit.Delay.Duration <= 0 {
+		c.Limit.Delay.Duration = limit.DefaultOptions.Delay
+	}
+
+	if c.Limit.Lockout.Duration < c.Limit.Delay.Duration {
+		c.Limit.Lockout.Duration = limit.DefaultOptions.Lockout
+		if c.Limit.Lockout.Duration < c.Limit.Delay.Duration {
+			c.Limit.Lockout.Duration = c.Limit.Delay.Duration
+		}
+	}
+
+	// Failed attempts must not be forgotten while the lockout lasts
+	if c.Limit.Reset.Duration < c.Limit.Lockout.Duration {
+		c.Limit.Reset.Duration = c.Limit.Lockout.Duration
+	}
+
+	if c.Hash.Argon2Memory == 0 {
+		c.Hash.Argon2Memory = hash.DefaultArgon2Config.Memory
+	}
+
+	if c.Hash.Argon2Iterations == 0 {
+		c.Hash.Argon2Iterations = hash.DefaultArgon2Config.Iterations
+	}
+
+	if c.Hash.Argon2Parallelism == 0 {
+		c.Hash.Argon2Parallelism = hash.DefaultArgon2Config.Parallelism
+	}
+
+	if c.OIDC.Enabled() {
+		if len(c.OIDC.Scopes) == 0 {
+			c.OIDC.Scopes = DefaultOIDCScopes
+		}
+
+		if c.OIDC.UsernameClaim == "" {
+			c.OIDC.UsernameClaim = DefaultUsernameClaim
+		}
+
+		if c.OIDC.RolesClaim == "" {
+			c.OIDC.RolesClaim = DefaultRolesClaim
+		}
+
+		if c.OIDC.RedirectURL == "" {
+			// The identity provider redirects to the web client, which passes the
+			// code to the server
+			c.OIDC.RedirectURL = c.Mail.URL + "/oidc/callback"
+		}
+
+		err = c.OIDC.ProviderOptions().Validate()
+		if err != nil {
+			return fmt.Errorf("[Config] %w", err)
+		}
+	}
+
+	// ENABLE_REGISTER predates the registration modes and is used if no mode is set
+	if c.General.Registration == "" {
+		c.General.Registration = RegistrationClosed
+		if c.General.EnableRegister {
+			c.General.Registration = RegistrationOpen
+		}
+	}
+
+	switch c.General.Registration {
+	case RegistrationOpen, RegistrationClosed, RegistrationInvite:
+	default:
+		return fmt.Errorf("[Config] unknown registration mode '%s'", c.General.Registration)
+	}
+
+	if c.General.MaxBots < 0 {
+		return fmt.Errorf("[Config] MAX_BOTS cannot be negative")
+	}
+
+	if c.LDAP.Enabled() {
+		if c.LDAP.UsernameAttribute == "" {
+			c.LDAP.UsernameAttribute = DefaultLDAPUsernameAttribute
+		}
+
+		err = c.LDAP.DirectoryOptions().Validate()
+		if err != nil {
+			return fmt.Errorf("[Config] %w", err)
+		}
+	}
+
+	if len(c.General.Authenticators) == 0 {
+		c.General.Authenticators = []string{AuthenticatorLocal}
+		if c.LDAP.Enabled() {
+			c.General.Authenticators = append(c.General.Authenticators, AuthenticatorLDAP)
+		}
+	}
+
+	for _, authenticator := range c.General.Authenticators {
+		switch authenticator {
+		case AuthenticatorLocal:
+		case AuthenticatorLDAP:
+			if !c.LDAP.Enabled() {
+				return fmt.Errorf("[Config] the authenticator '%s' requires the [ldap] URL", authenticator)
+			}
+		default:
+			return fmt.Errorf("[Config] unknown authenticator '%s'", authenticator)
+		}
+	}
+
+	// Nobody could login without local passwords, the directory and single sign-on
+	if c.General.DisablePasswordLogin && !c.OIDC.Enabled() && !c.LDAP.Enabled() {
+		return fmt.Errorf("[Config] DISABLE_PASSWORD_LOGIN requires single sign-on or LDAP, set the [oidc] ISSUER or the [ldap] URL")
+	}
+
+	if c.Store.Type == "" {
+		// Fallback to MySQL, which was the only supported database in the past
+		c.Store.Type = "mysql"
+	}
+
+	if c.Store.SSLMode == "" {
+		c.Store.SSLMode = "disable"
+	}
+
 	if c.Log.Prefix == "" {
 		c.Log.Prefix = "Chapper"
 	}
@@ -0,0 +1,29 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import "time"
+
+// Duration wraps time.Duration to read and write durations like '15m' or '720h' in the
+// config file
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses the duration from text
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	d.Duration = duration
+	return nil
+}
+
+// MarshalText returns the text representation of the duration
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
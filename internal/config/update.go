@@ -0,0 +1,125 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"chapper.dev/server/internal/utils"
+)
+
+// keyValue is a TOML key and its encoded value
+type keyValue struct {
+	key   string
+	value string
+}
+
+// WriteHash sets the [hash] options in the .toml config file at path. Unlike Write
+// it only changes these keys and keeps comments and all other options as they are
+func WriteHash(path string, o HashOptions) error {
+	return updateSection(path, "hash", []keyValue{
+		{"ARGON2_MEMORY", strconv.FormatUint(uint64(o.Argon2Memory), 10)},
+		{"ARGON2_ITERATIONS", strconv.FormatUint(uint64(o.Argon2Iterations), 10)},
+		{"ARGON2_PARALLELISM", strconv.FormatUint(uint64(o.Argon2Parallelism), 10)},
+	})
+}
+
+// updateSection replaces the values of keys in section of the .toml file at path.
+// Missing keys are added to the end of the section, a missing section to the end of
+// the file
+func updateSection(path, section string, values []keyValue) error {
+	path, err := utils.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	newline := strings.HasSuffix(string(content), "\n")
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	written := make(map[string]bool)
+
+	// start and end are the index of the section header and the index after the
+	// last key of the section, -1 if the section does not exist
+	start, end := -1, -1
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") {
+			if start != -1 {
+				break
+			}
+
+			name := strings.TrimSpace(strings.Trim(strings.SplitN(trimmed, "]", 2)[0], "["))
+			if strings.EqualFold(name, section) {
+				start, end = i, i+1
+			}
+			continue
+		}
+
+		if start == -1 || trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		end = i + 1
+
+		for _, kv := range values {
+			if !isKeyLine(trimmed, kv.key) {
+				continue
+			}
+
+			lines[i] = setValue(line, kv.value)
+			written[kv.key] = true
+		}
+	}
+
+	var missing []string
+	for _, kv := range values {
+		if !written[kv.key] {
+			missing = append(missing, kv.key+" = "+kv.value)
+		}
+	}
+
+	if start == -1 {
+		lines = append(lines, "", "["+section+"]")
+		lines = append(lines, missing...)
+	} else if len(missing) > 0 {
+		lines = append(lines[:end], append(missing, lines[end:]...)...)
+	}
+
+	updated := strings.Join(lines, "\n")
+	if newline {
+		updated += "\n"
+	}
+
+	return ioutil.WriteFile(path, []byte(updated), 0644)
+}
+
+// isKeyLine returns if line assigns a value to key
+func isKeyLine(line, key string) bool {
+	if !strings.HasPrefix(line, key) {
+		return false
+	}
+
+	return strings.HasPrefix(strings.TrimSpace(line[len(key):]), "=")
+}
+
+// setValue replaces the value of the key in line and keeps its alignment and trailing
+// comment
+func setValue(line, value string) string {
+	i := strings.Index(line, "=")
+	prefix, rest := line[:i+1], line[i+1:]
+
+	if c := strings.Index(rest, "#"); c != -1 {
+		return prefix + " " + value + " " + rest[c:]
+	}
+
+	return prefix + " " + value
+}
@@ -7,4 +7,13 @@ package constants
 const (
 	StoreParams       = "charset=utf8mb4&parseTime=True&loc=Local"
 	StoreTableOptions = "ENGINE=InnoDB CHARSET=utf8mb4 auto_increment=1"
+
+	StorePostgresParams = "connect_timeout=10"
+	StoreSQLiteParams   = "_foreign_keys=on&_busy_timeout=5000&_loc=auto"
+)
+
+const (
+	StoreDriverMySQL    = "mysql"
+	StoreDriverPostgres = "postgres"
+	StoreDriverSQLite   = "sqlite3"
 )
@@ -26,7 +26,7 @@ func AdvancedSteps() []*survey.Question {
 			Name: "database-type",
 			Prompt: &survey.Select{
 				Message: "Database | Type",
-				Options: []string{"MySQL"},
+				Options: []string{"MySQL", "PostgreSQL", "SQLite"},
 				Default: "MySQL",
 			},
 		},
@@ -46,7 +46,7 @@ func AdvancedSteps() []*survey.Question {
 		{
 			Name: "database-name",
 			Prompt: &survey.Input{
-				Message: "Database | Name:",
+				Message: "Database | Name (file path for SQLite):",
 				Default: "",
 			},
 		},
@@ -64,6 +64,27 @@ func AdvancedSteps() []*survey.Question {
 				Default: "3306",
 			},
 		},
+		{
+			Name: "admin-username",
+			Prompt: &survey.Input{
+				Message: "Admin | Username:",
+				Default: "admin",
+			},
+			Validate: survey.Required,
+		},
+		{
+			Name: "admin-email",
+			Prompt: &survey.Input{
+				Message: "Admin | E-Mail:",
+			},
+		},
+		{
+			Name: "admin-password",
+			Prompt: &survey.Password{
+				Message: "Admin | Password:",
+			},
+			Validate: survey.Required,
+		},
 		{
 			Name: "frontend-auto",
 			Prompt: &survey.Confirm{
@@ -25,22 +25,24 @@ type Installer struct {
 	answers  *Answers
 	spinner  *spinner.Spinner
 	config   *config.Config
-	store    *store.Store
+	store    *store.SQL
 }
 
 type Answers struct {
-	InstanceName       string `survey:"instance-name"`
-	InstanceDomain     string `survey:"instance-domain"`
-	DatabaseType       string `survey:"database-type"`
-	DatabaseUser       string `survey:"database-user"`
-	DatabasePassword   string `survey:"database-password"`
-	DatabaseName       string `survey:"database-name"`
-	DatabaseHost       string `survey:"database-host"`
-	DatabasePort       string `survey:"database-port"`
-	FrontendAuto       bool   `survey:"frontend-auto"`
-	FrontendPath       string `survey:"frontend-path"`
-	ConfigPath         string `survey:"config-path"`
-	AdminPanelPassword string `survey:"admin-panel-password"`
+	InstanceName     string `survey:"instance-name"`
+	InstanceDomain   string `survey:"instance-domain"`
+	DatabaseType     string `survey:"database-type"`
+	DatabaseUser     string `survey:"database-user"`
+	DatabasePassword string `survey:"database-password"`
+	DatabaseName     string `survey:"database-name"`
+	DatabaseHost     string `survey:"database-host"`
+	DatabasePort     string `survey:"database-port"`
+	FrontendAuto     bool   `survey:"frontend-auto"`
+	FrontendPath     string `survey:"frontend-path"`
+	ConfigPath       string `survey:"config-path"`
+	AdminUsername    string `survey:"admin-username"`
+	AdminEmail       string `survey:"admin-email"`
+	AdminPassword    string `survey:"admin-password"`
 }
 
 func New() *Installer {
@@ -103,5 +105,10 @@ func (i *Installer) InstallServer() error {
 		return err
 	}
 
+	err = i.createAdmin()
+	if err != nil {
+		return err
+	}
+
 	return i.writeConfiguration()
 }
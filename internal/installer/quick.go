@@ -26,15 +26,30 @@ func QuickSteps() []*survey.Question {
 			Name: "database-type",
 			Prompt: &survey.Select{
 				Message: "Database | Type",
-				Options: []string{"MySQL"},
+				Options: []string{"MySQL", "PostgreSQL", "SQLite"},
 				Default: "MySQL",
 			},
 		},
 		{
-			Name: "admin-panel-password",
+			Name: "admin-username",
+			Prompt: &survey.Input{
+				Message: "Admin | Username:",
+				Default: "admin",
+			},
+			Validate: survey.Required,
+		},
+		{
+			Name: "admin-email",
+			Prompt: &survey.Input{
+				Message: "Admin | E-Mail:",
+			},
+		},
+		{
+			Name: "admin-password",
 			Prompt: &survey.Password{
-				Message: "Please enter a password to protect the admin panel:",
+				Message: "Admin | Password:",
 			},
+			Validate: survey.Required,
 		},
 	}
 }
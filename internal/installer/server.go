@@ -6,9 +6,14 @@ package installer
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
 	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/constants"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/hash"
+	"chapper.dev/server/internal/services"
 	"chapper.dev/server/internal/store"
 )
 
@@ -19,6 +24,11 @@ func (i *Installer) defaultConfiguration() error {
 	cfg := config.NewDefault()
 	cfg.General.Name = i.answers.InstanceName
 	cfg.Router.Domain = i.answers.InstanceDomain
+	cfg.Store.Type = storeType(i.answers.DatabaseType)
+
+	if cfg.Store.Type == constants.StoreDriverPostgres {
+		cfg.Store.Port = 5432
+	}
 
 	if i.Type == "Advanced" {
 		port, err := strconv.Atoi(i.answers.DatabasePort)
@@ -27,11 +37,19 @@ func (i *Installer) defaultConfiguration() error {
 		}
 
 		cfg.Store = config.StoreOptions{
+			Type:     cfg.Store.Type,
 			User:     i.answers.DatabaseUser,
 			Password: i.answers.DatabasePassword,
 			Database: i.answers.DatabaseName,
 			Host:     i.answers.DatabaseHost,
 			Port:     port,
+			SSLMode:  cfg.Store.SSLMode,
+			Path:     cfg.Store.Path,
+		}
+
+		// SQLite has no database name, the name is the path to the database file
+		if cfg.Store.Type == constants.StoreDriverSQLite && i.answers.DatabaseName != "" {
+			cfg.Store.Path = i.answers.DatabaseName
 		}
 	}
 
@@ -42,7 +60,7 @@ func (i *Installer) defaultConfiguration() error {
 
 func (i *Installer) databaseConnection() error {
 	i.spinner.Suffix = " Connecting to database"
-	s, err := store.New(i.answers.DatabaseType, i.config.Store)
+	s, err := store.New(i.config.Store.Type, i.config.Store)
 	if err != nil {
 		return err
 	}
@@ -63,6 +81,40 @@ func (i *Installer) databaseMigration() error {
 	return nil
 }
 
+func (i *Installer) createAdmin() error {
+	i.spinner.Suffix = " Creating admin account"
+	password, err := hash.NewArgon2().Hash(i.answers.AdminPassword)
+	if err != nil {
+		return err
+	}
+
+	// The first account of an instance automatically becomes the superadmin
+	users := services.NewUserService(i.store, i.config)
+	err = users.CreateUser(models.PublicUser{
+		Username: i.answers.AdminUsername,
+		Password: password,
+		Email:    i.answers.AdminEmail,
+	})
+	if err != nil {
+		return err
+	}
+
+	settings, err := i.store.GetSettings()
+	if err != nil {
+		return err
+	}
+
+	settings.IsInstalled = true
+	settings.InstanceName = i.answers.InstanceName
+	err = i.store.SetSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	time.Sleep(time.Second)
+	return nil
+}
+
 func (i *Installer) writeConfiguration() error {
 	i.spinner.Suffix = " Saving configuration file"
 	// TODO <2020/09/09>: Don't hardcode this path
@@ -75,3 +127,16 @@ func (i *Installer) writeConfiguration() error {
 	i.spinner.Stop()
 	return nil
 }
+
+// storeType maps the database type selected in the installer to the store type used in
+// the config
+func storeType(selected string) string {
+	switch strings.ToLower(selected) {
+	case "postgresql":
+		return constants.StoreDriverPostgres
+	case "sqlite":
+		return constants.StoreDriverSQLite
+	default:
+		return constants.StoreDriverMySQL
+	}
+}
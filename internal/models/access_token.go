@@ -0,0 +1,113 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// Resources which personal access tokens can be scoped to. Every resource has a read
+// scope, like rooms:read, and a write scope, like rooms:write
+const (
+	ResourceServers  = "servers"
+	ResourceRooms    = "rooms"
+	ResourceMembers  = "members"
+	ResourceRoles    = "roles"
+	ResourceInvites  = "invites"
+	ResourceProfiles = "profiles"
+	ResourceMessages = "messages"
+)
+
+// TokenResources lists all resources personal access tokens can be scoped to
+var TokenResources = []string{
+	ResourceServers,
+	ResourceRooms,
+	ResourceMembers,
+	ResourceRoles,
+	ResourceInvites,
+	ResourceProfiles,
+	ResourceMessages,
+}
+
+// PersonalAccessToken is a long-lived token which lets scripts and bots use the API
+// without logging in. It only grants access to the resources of its scopes. Only the
+// hash of the token is stored
+type PersonalAccessToken struct {
+	ID         string    `json:"id" db:"id"`
+	Hash       string    `json:"-" db:"hash"`
+	Username   string    `json:"username" db:"username"`
+	Name       string    `json:"name" db:"name"`
+	Scopes     Scopes    `json:"scopes" db:"scopes"`
+	CreatedBy  string    `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt null.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt  null.Time `json:"expires_at" db:"expires_at"`
+}
+
+// IsExpired returns if the token expired. Tokens without expiry never expire
+func (t *PersonalAccessToken) IsExpired() bool {
+	return t.ExpiresAt.Valid && time.Now().UTC().After(t.ExpiresAt.Time)
+}
+
+// Scopes are the scopes of a personal access token. They are stored separated by
+// spaces, like the scope parameter of OAuth 2.0
+type Scopes []string
+
+// Scope returns the scope a token needs to access resource with the HTTP method.
+// Reading requires the read scope, everything else the write scope
+func Scope(resource, method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return resource + ":read"
+	}
+	return resource + ":write"
+}
+
+// ValidScope returns if scope is the read or write scope of a known resource
+func ValidScope(scope string) bool {
+	for _, resource := range TokenResources {
+		if scope == resource+":read" || scope == resource+":write" {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns if scope is one of the scopes
+func (s Scopes) Contains(scope string) bool {
+	for _, sc := range s {
+		if sc == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements driver.Valuer
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+// Scan implements sql.Scanner
+func (s *Scopes) Scan(src interface{}) error {
+	var value string
+	switch v := src.(type) {
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into scopes", src)
+	}
+
+	*s = strings.Fields(value)
+	return nil
+}
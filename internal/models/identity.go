@@ -0,0 +1,16 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import "time"
+
+// Identity links a user to the account at an external identity provider. The subject
+// identifies the account at the issuer and never changes, unlike the username
+type Identity struct {
+	Issuer    string    `json:"issuer" db:"issuer"`
+	Subject   string    `json:"-" db:"subject"`
+	Username  string    `json:"-" db:"username"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import "time"
+
+// Member describes the membership of a user in a virtual server
+type Member struct {
+	Server   string    `json:"server" db:"server"`
+	Username string    `json:"username" db:"username"`
+	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
+	Roles    []Role    `json:"roles" db:"-"`
+}
+
+// ServerAdmin returns the built-in admin role of a virtual server. It gets assigned to
+// the creator of the server
+func ServerAdmin() Role {
+	return Role{
+		Name:        "Admin",
+		Description: "Manages this server",
+		Privileges: Privileges{
+			CanDeleteServer:       true,
+			CanEditServer:         true,
+			CanCreateRoom:         true,
+			CanDeleteRoom:         true,
+			CanEditRoom:           true,
+			CanCreateInvite:       true,
+			CanDeleteInvite:       true,
+			CanKickUserFromRoom:   true,
+			CanKickUserFromServer: true,
+			CanBanUserFromRoom:    true,
+			CanBanUserFromServer:  true,
+			CanCreateRole:         true,
+			CanDeleteRole:         true,
+			CanAssignRoleToUser:   true,
+			CanRemoveRoleFromUser: true,
+		},
+	}
+}
+
+// ServerMember returns the built-in member role of a virtual server. It gets assigned
+// to every user joining the server
+func ServerMember() Role {
+	return Role{
+		Name:        "Member",
+		Description: "",
+	}
+}
+
+// IsAdmin returns if the role is the built-in superadmin role or the built-in admin
+// role of a virtual server. These roles can't be changed
+func (r *Role) IsAdmin() bool {
+	if !r.Builtin {
+		return false
+	}
+
+	if r.Server == "" {
+		return r.Name == Superadmin().Name
+	}
+	return r.Name == ServerAdmin().Name
+}
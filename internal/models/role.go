@@ -0,0 +1,61 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import "reflect"
+
+// IsEmpty returns if some required data is missing
+func (r *Role) IsEmpty() bool {
+	return r.Name == ""
+}
+
+// MergeRoles returns the effective privileges of multiple roles. A privilege is granted
+// if at least one role grants it
+func MergeRoles(roles []Role) Privileges {
+	var merged Privileges
+	for _, role := range roles {
+		merged = merged.Merge(role.Privileges)
+	}
+	return merged
+}
+
+// Merge returns the union of both privileges
+func (p Privileges) Merge(o Privileges) Privileges {
+	merged := Privileges{}
+	eachPrivilege(&merged, func(name string, v reflect.Value) {
+		v.SetBool(privilege(p, name) || privilege(o, name))
+	})
+	return merged
+}
+
+// Covers returns if p grants every privilege o grants. It is used to prevent users from
+// granting privileges they don't have themselves
+func (p Privileges) Covers(o Privileges) bool {
+	covers := true
+	eachPrivilege(&o, func(name string, v reflect.Value) {
+		if v.Bool() && !privilege(p, name) {
+			covers = false
+		}
+	})
+	return covers
+}
+
+// eachPrivilege calls fn for every privilege field of p
+func eachPrivilege(p *Privileges, fn func(name string, v reflect.Value)) {
+	v := reflect.ValueOf(p).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() != reflect.Bool {
+			continue
+		}
+		fn(t.Field(i).Name, v.Field(i))
+	}
+}
+
+// privilege returns the value of the privilege field 'name' of p
+func privilege(p Privileges, name string) bool {
+	return reflect.ValueOf(p).FieldByName(name).Bool()
+}
@@ -8,6 +8,7 @@ import "gopkg.in/guregu/null.v4"
 
 type Room struct {
 	Hash        string      `json:"hash" db:"hash"`
+	Server      string      `json:"server" db:"server"`
 	Name        string      `json:"name" db:"name"`
 	Type        null.String `json:"type" db:"type"`
 	Description null.String `json:"description" db:"description"`
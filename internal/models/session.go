@@ -0,0 +1,26 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import "time"
+
+// Session is one login of a user. The ID of the session is the family of the refresh
+// tokens which were issued for the login and is carried by all access tokens of it
+type Session struct {
+	ID         string    `json:"id" db:"id"`
+	Username   string    `json:"-" db:"username"`
+	Name       string    `json:"name" db:"name"`
+	IP         string    `json:"ip" db:"ip"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time `json:"-" db:"expires_at"`
+	Current    bool      `json:"current" db:"-"`
+}
+
+// IsExpired returns if the refresh tokens of the session expired
+func (s *Session) IsExpired() bool {
+	return time.Now().UTC().After(s.ExpiresAt)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// RefreshToken is a long-lived token which can be exchanged for a new access token.
+// Only the hash of the token is stored. Every refresh replaces the token with a new one
+// of the same family, so that the reuse of a replaced token can be detected
+type RefreshToken struct {
+	Hash       string    `json:"-" db:"hash"`
+	Family     string    `json:"-" db:"family"`
+	Username   string    `json:"-" db:"username"`
+	ReplacedBy string    `json:"-" db:"replaced_by"`
+	Revoked    bool      `json:"-" db:"revoked"`
+	CreatedAt  time.Time `json:"-" db:"created_at"`
+	ExpiresAt  time.Time `json:"-" db:"expires_at"`
+}
+
+// IsUsed returns if the token was already replaced or revoked
+func (t *RefreshToken) IsUsed() bool {
+	return t.ReplacedBy != "" || t.Revoked
+}
+
+// IsExpired returns if the token is expired
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().UTC().After(t.ExpiresAt)
+}
+
+// LoginChallenge is handed out to users with 2FA after they provided their password.
+// It is exchanged for tokens together with a valid 2FA code. Challenges of other kinds
+// carry the session of a WebAuthn ceremony. Only the hash of the challenge is stored
+type LoginChallenge struct {
+	Hash      string    `json:"-" db:"hash"`
+	Username  string    `json:"-" db:"username"`
+	Kind      string    `json:"-" db:"kind"`
+	Session   string    `json:"-" db:"session"`
+	ExpiresAt time.Time `json:"-" db:"expires_at"`
+}
+
+const (
+	// ChallengePassword is the kind of challenges handed out after the user provided
+	// the password. They are exchanged for tokens with a 2FA code or a WebAuthn
+	// assertion
+	ChallengePassword = "password"
+
+	// ChallengePasswordless is the kind of challenges of a login with a WebAuthn
+	// assertion only
+	ChallengePasswordless = "passwordless"
+
+	// ChallengeWebAuthnRegister is the kind of challenges of the registration of a new
+	// WebAuthn credential
+	ChallengeWebAuthnRegister = "webauthn-register"
+
+	// ChallengeOIDC is the kind of challenges of a single sign-on. The challenge is
+	// the state passed to the identity provider and carries the nonce and the PKCE
+	// code verifier
+	ChallengeOIDC = "oidc"
+)
+
+// IsExpired returns if the challenge is expired
+func (c *LoginChallenge) IsExpired() bool {
+	return time.Now().UTC().After(c.ExpiresAt)
+}
+
+// RecoveryCode is a single-use code which replaces the 2FA code if the user lost access
+// to the authenticator. Only the hash of the code is stored
+type RecoveryCode struct {
+	Username string `json:"-" db:"username"`
+	ID       string `json:"-" db:"id"`
+	Hash     string `json:"-" db:"hash"`
+}
+
+// MailToken is sent to users by mail to verify their email address or to reset their
+// password. It can only be used once. Only the hash of the token is stored
+type MailToken struct {
+	Hash      string    `json:"-" db:"hash"`
+	Username  string    `json:"-" db:"username"`
+	Kind      string    `json:"-" db:"kind"`
+	Email     string    `json:"-" db:"email"`
+	ExpiresAt time.Time `json:"-" db:"expires_at"`
+}
+
+const (
+	// MailVerifyEmail is the kind of tokens which verify the email address they were
+	// sent to
+	MailVerifyEmail = "verify-email"
+
+	// MailResetPassword is the kind of tokens which allow to set a new password
+	MailResetPassword = "reset-password"
+)
+
+// IsExpired returns if the token is expired
+func (t *MailToken) IsExpired() bool {
+	return time.Now().UTC().After(t.ExpiresAt)
+}
+
+// SigningKey is a key of the keyring which signs access tokens. The private key is
+// PKCS #8 encoded in PEM. Retired keys no longer sign tokens, but verify the tokens they
+// signed until these expired
+type SigningKey struct {
+	ID         string    `json:"-" db:"id"`
+	Algorithm  string    `json:"-" db:"algorithm"`
+	PrivateKey string    `json:"-" db:"private_key"`
+	CreatedAt  time.Time `json:"-" db:"created_at"`
+	RetiredAt  null.Time `json:"-" db:"retired_at"`
+}
@@ -12,17 +12,32 @@ import (
 )
 
 type User struct {
-	Username    string      `json:"username" db:"username"`
-	Password    string      `json:"-" db:"password"`
-	Email       null.String `json:"email" db:"email"`
-	PublicKey   string      `json:"-" db:"publickey"`
-	TwoFASecret null.String `json:"-" db:"twofa_secret"`
-	TwoFAVerify null.String `json:"-" db:"twofa_verify"`
+	Username      string      `json:"username" db:"username"`
+	Password      string      `json:"-" db:"password"`
+	Email         null.String `json:"email" db:"email"`
+	EmailVerified bool        `json:"email_verified" db:"email_verified"`
+	Disabled      bool        `json:"-" db:"disabled"`
+	Bot           bool        `json:"bot" db:"bot"`
+	BotOwner      null.String `json:"bot_owner" db:"bot_owner"`
+	PublicKey     string      `json:"-" db:"publickey"`
+	TwoFASecret   null.String `json:"-" db:"twofa_secret"`
+	TwoFAVerify   null.String `json:"-" db:"twofa_verify"`
+	TwoFALastStep uint64      `json:"-" db:"twofa_last_step"`
+	WebAuthnID    null.String `json:"-" db:"webauthn_id"`
+	TOTPParams
 	// Role           []Role
 	// Friends        []*User
 	// Servers        []*Server
 }
 
+// TOTPParams are the parameters of the TOTP enrollment of a user. They are saved with
+// the secret, because they have to match the authenticator app
+type TOTPParams struct {
+	Algorithm string `json:"-" db:"twofa_algorithm"`
+	Digits    int    `json:"-" db:"twofa_digits"`
+	Period    uint   `json:"-" db:"twofa_period"`
+}
+
 type PublicUser struct {
 	Username  string `json:"username"`
 	Password  string `json:"password"`
@@ -30,35 +45,38 @@ type PublicUser struct {
 	PublicKey string `json:"publickey"`
 }
 
-// Role specifies a role which is used for rights management
+// Role specifies a role which is used for rights management. Roles without a server
+// are global, roles with a server only apply to the members of that server
 type Role struct {
-	ID          uint       `json:"-"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Privileges  Privileges `json:"privileges"`
+	ID          uint       `json:"id" db:"id"`
+	Server      string     `json:"server,omitempty" db:"server"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	Builtin     bool       `json:"builtin" db:"builtin"`
+	Privileges  Privileges `json:"privileges" db:"privileges"`
 }
 
 // Privileges manages privileges which each role has
 type Privileges struct {
-	ID                    uint `json:"-"`
-	RoleID                uint `json:"-"`
-	CanCreateServer       bool `json:"canCreateServer"`
-	CanDeleteServer       bool `json:"canDeleteServer"`
-	CanEditServer         bool `json:"canEditServer"`
-	CanSeeAllServers      bool `json:"canSeeAllServers"`
-	CanCreateRoom         bool `json:"canCreateRoom"`
-	CanDeleteRoom         bool `json:"canDeleteRoom"`
-	CanEditRoom           bool `json:"canEditRoom"`
-	CanCreateInvite       bool `json:"canCreateInvite"`
-	CanDeleteInvite       bool `json:"canDeleteInvite"`
-	CanKickUserFromRoom   bool `json:"canKickUserFromRoom"`
-	CanKickUserFromServer bool `json:"canKickUserFromServer"`
-	CanBanUserFromRoom    bool `json:"canBanUserFromRoom"`
-	CanBanUserFromServer  bool `json:"canBanUserFromServer"`
-	CanCreateRole         bool `json:"canCreateRole"`
-	CanDeleteRole         bool `json:"canDeleteRole"`
-	CanAssignRoleToUser   bool `json:"canAssignRoleToUser"`
-	CanRemoveRoleFromUser bool `json:"canRemoveRoleRromUser"`
+	ID                    uint `json:"-" db:"id"`
+	RoleID                uint `json:"-" db:"role_id"`
+	CanCreateServer       bool `json:"canCreateServer" db:"can_create_server"`
+	CanDeleteServer       bool `json:"canDeleteServer" db:"can_delete_server"`
+	CanEditServer         bool `json:"canEditServer" db:"can_edit_server"`
+	CanSeeAllServers      bool `json:"canSeeAllServers" db:"can_see_all_servers"`
+	CanCreateRoom         bool `json:"canCreateRoom" db:"can_create_room"`
+	CanDeleteRoom         bool `json:"canDeleteRoom" db:"can_delete_room"`
+	CanEditRoom           bool `json:"canEditRoom" db:"can_edit_room"`
+	CanCreateInvite       bool `json:"canCreateInvite" db:"can_create_invite"`
+	CanDeleteInvite       bool `json:"canDeleteInvite" db:"can_delete_invite"`
+	CanKickUserFromRoom   bool `json:"canKickUserFromRoom" db:"can_kick_user_from_room"`
+	CanKickUserFromServer bool `json:"canKickUserFromServer" db:"can_kick_user_from_server"`
+	CanBanUserFromRoom    bool `json:"canBanUserFromRoom" db:"can_ban_user_from_room"`
+	CanBanUserFromServer  bool `json:"canBanUserFromServer" db:"can_ban_user_from_server"`
+	CanCreateRole         bool `json:"canCreateRole" db:"can_create_role"`
+	CanDeleteRole         bool `json:"canDeleteRole" db:"can_delete_role"`
+	CanAssignRoleToUser   bool `json:"canAssignRoleToUser" db:"can_assign_role_to_user"`
+	CanRemoveRoleFromUser bool `json:"canRemoveRoleRromUser" db:"can_remove_role_from_user"`
 }
 
 // Refer to https://github.com/go-playground/validator/blob/ea924ce89a4774b8017143b34b946db46add9df1/regexes.go#L18
@@ -136,7 +154,24 @@ func (u *User) Invalid() bool {
 	return false
 }
 
+// ValidUsername returns if name can be used as username. Usernames are part of URLs
+// and of the file names of avatars
+func ValidUsername(name string) bool {
+	return name != "" && len(name) <= 100 && !strings.ContainsAny(name, " \t\r\n/\\")
+}
+
+// ValidEmail returns if email is a valid email address
+func ValidEmail(email string) bool {
+	return emailRegex.MatchString(email)
+}
+
 // UsesTwoFA returns if the user uses 2FA
 func (u *User) UsesTwoFA() bool {
 	return u.TwoFASecret.String != ""
 }
+
+// EnrollsTwoFA returns if the user started the 2FA enrollment, but didn't confirm it
+// with a valid code yet
+func (u *User) EnrollsTwoFA() bool {
+	return u.TwoFAVerify.String != ""
+}
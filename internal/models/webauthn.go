@@ -0,0 +1,25 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// WebAuthnCredential is a public key credential of a WebAuthn authenticator like a
+// passkey or security key. The ID, public key and AAGUID are base64url encoded
+type WebAuthnCredential struct {
+	ID              string    `json:"id" db:"id"`
+	Username        string    `json:"-" db:"username"`
+	Name            string    `json:"name" db:"name"`
+	PublicKey       string    `json:"-" db:"public_key"`
+	AttestationType string    `json:"-" db:"attestation_type"`
+	AAGUID          string    `json:"-" db:"aaguid"`
+	SignCount       uint32    `json:"-" db:"sign_count"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt      null.Time `json:"last_used_at" db:"last_used_at"`
+}
@@ -127,6 +127,29 @@ func (a *Avatar) Save(base, size, name string) error {
 	return ioutil.WriteFile(f, a.ImageBuffer.Bytes(), 0777)
 }
 
+// Remove removes the avatar images of name in all sizes below base
+func Remove(base, name string) error {
+	sizes, err := ioutil.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, size := range sizes {
+		if !size.IsDir() {
+			continue
+		}
+
+		err = os.Remove(utils.Join(base, size.Name(), name+".jpg"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 func pickColor(data string, colors []color.RGBA, index int) color.RGBA {
 	l := len(colors)
 	s := hex.EncodeToString([]byte{data[index]})
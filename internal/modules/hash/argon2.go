@@ -7,10 +7,8 @@ package hash
 
 import (
 	"crypto/subtle"
-	"encoding/base64"
 	"errors"
-	"fmt"
-	"runtime"
+	"strconv"
 	"strings"
 
 	"chapper.dev/server/internal/constants"
@@ -24,6 +22,14 @@ var (
 	ErrIncompatibleVersion = errors.New("Incompatible version of argon2")
 )
 
+const (
+	// argon2ID is the PHC identifier of Argon2id, which is used for new hashes
+	argon2ID = "argon2id"
+
+	// argon2I is the PHC identifier of Argon2i. Hashes of it can be validated
+	argon2I = "argon2i"
+)
+
 type Argon2 struct {
 	name   string
 	config Argon2Config
@@ -37,11 +43,12 @@ type Argon2Config struct {
 	KeyLength   uint32
 }
 
-// DefaultArgon2Config is the default config when using NewArgon2
+// DefaultArgon2Config is the default config when using NewArgon2. The parallelism is
+// fixed, so that hashes don't depend on the machine which created them
 var DefaultArgon2Config = Argon2Config{
 	Memory:      64 * constants.Argon2Kibibyte,
 	Iterations:  5, // NOTE(Techassi): The RFC suggests 1 iteration, should we use 5?
-	Parallelism: uint8(runtime.NumCPU() / 4),
+	Parallelism: 4,
 	SaltLength:  16,
 	KeyLength:   32,
 }
@@ -51,8 +58,29 @@ func NewArgon2() Argon2 {
 	return NewArgon2WithConfig(DefaultArgon2Config)
 }
 
-// NewArgon2WithConfig returns a new Argon2 hasher with a custom config
+// NewArgon2WithConfig returns a new Argon2 hasher with a custom config. Parameters
+// which are 0 fall back to the default config
 func NewArgon2WithConfig(c Argon2Config) Argon2 {
+	if c.Memory == 0 {
+		c.Memory = DefaultArgon2Config.Memory
+	}
+
+	if c.Iterations == 0 {
+		c.Iterations = DefaultArgon2Config.Iterations
+	}
+
+	if c.Parallelism == 0 {
+		c.Parallelism = DefaultArgon2Config.Parallelism
+	}
+
+	if c.SaltLength == 0 {
+		c.SaltLength = DefaultArgon2Config.SaltLength
+	}
+
+	if c.KeyLength == 0 {
+		c.KeyLength = DefaultArgon2Config.KeyLength
+	}
+
 	return Argon2{
 		name:   "argon2",
 		config: c,
@@ -64,6 +92,11 @@ func (a Argon2) Name() string {
 	return a.name
 }
 
+// Config returns the config new hashes are created with
+func (a Argon2) Config() Argon2Config {
+	return a.config
+}
+
 // Hash hashes the payload
 func (a Argon2) Hash(payload string) (string, error) {
 	// Generate cryptographically secure random salt
@@ -82,28 +115,34 @@ func (a Argon2) Hash(payload string) (string, error) {
 	)
 
 	// Generate the encoded representation of the password
-	saltB64 := base64.RawStdEncoding.EncodeToString(salt)
-	passB64 := base64.RawStdEncoding.EncodeToString(hash)
-
-	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
-		argon2.Version,
-		a.config.Memory,
-		a.config.Iterations,
-		a.config.Parallelism,
-		saltB64,
-		passB64,
-	)
-	return encoded, nil
+	encoded := &PHC{
+		ID:      argon2ID,
+		Version: argon2.Version,
+		Params: []Param{
+			{Name: "m", Value: strconv.FormatUint(uint64(a.config.Memory), 10)},
+			{Name: "t", Value: strconv.FormatUint(uint64(a.config.Iterations), 10)},
+			{Name: "p", Value: strconv.FormatUint(uint64(a.config.Parallelism), 10)},
+		},
+		Salt: salt,
+		Hash: hash,
+	}
+	return encoded.String(), nil
 }
 
-// Valid compares the input to a hashed payload
+// Valid compares the input to a hashed payload. The hash is computed with the
+// parameters stored in the hashed payload
 func (a Argon2) Valid(input, hashed string) (bool, error) {
-	p, salt, hash, err := decodeHash(hashed)
+	id, p, salt, hash, err := decodeHash(hashed)
 	if err != nil {
 		return false, err
 	}
 
-	compareHash := argon2.IDKey([]byte(input), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	key := argon2.IDKey
+	if id == argon2I {
+		key = argon2.Key
+	}
+
+	compareHash := key([]byte(input), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
 	if subtle.ConstantTimeCompare(hash, compareHash) == 1 {
 		return true, nil
 	}
@@ -111,38 +150,69 @@ func (a Argon2) Valid(input, hashed string) (bool, error) {
 	return false, nil
 }
 
-func decodeHash(encodedHash string) (p *Argon2Config, salt, hash []byte, err error) {
-	vals := strings.Split(encodedHash, "$")
-	if len(vals) != 6 {
-		return nil, nil, nil, ErrInvalidHash
+// Matches returns if the hashed payload is an Argon2 hash
+func (a Argon2) Matches(hashed string) bool {
+	return strings.HasPrefix(hashed, "$"+argon2ID+"$") || strings.HasPrefix(hashed, "$"+argon2I+"$")
+}
+
+// NeedsRehash returns if the hashed payload was not created with the current config
+func (a Argon2) NeedsRehash(hashed string) bool {
+	id, p, _, _, err := decodeHash(hashed)
+	if err != nil {
+		return true
 	}
 
-	var version int
-	_, err = fmt.Sscanf(vals[2], "v=%d", &version)
+	return id != argon2ID ||
+		p.Memory != a.config.Memory ||
+		p.Iterations != a.config.Iterations ||
+		p.Parallelism != a.config.Parallelism ||
+		p.SaltLength < a.config.SaltLength ||
+		p.KeyLength != a.config.KeyLength
+}
+
+// decodeHash returns the variant, the parameters, the salt and the hash of an Argon2
+// hash in the PHC string format
+func decodeHash(encodedHash string) (id string, p *Argon2Config, salt, hash []byte, err error) {
+	phc, err := ParsePHC(encodedHash)
 	if err != nil {
-		return nil, nil, nil, err
+		return "", nil, nil, nil, err
+	}
+
+	if phc.ID != argon2ID && phc.ID != argon2I {
+		return "", nil, nil, nil, ErrInvalidHash
 	}
-	if version != argon2.Version {
-		return nil, nil, nil, ErrIncompatibleVersion
+
+	if phc.Version != argon2.Version {
+		return "", nil, nil, nil, ErrIncompatibleVersion
 	}
 
-	p = &Argon2Config{}
-	_, err = fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	memory, err := phc.Uint("m", 32)
 	if err != nil {
-		return nil, nil, nil, err
+		return "", nil, nil, nil, err
 	}
 
-	salt, err = base64.RawStdEncoding.DecodeString(vals[4])
+	iterations, err := phc.Uint("t", 32)
 	if err != nil {
-		return nil, nil, nil, err
+		return "", nil, nil, nil, err
 	}
-	p.SaltLength = uint32(len(salt))
 
-	hash, err = base64.RawStdEncoding.DecodeString(vals[5])
+	parallelism, err := phc.Uint("p", 8)
 	if err != nil {
-		return nil, nil, nil, err
+		return "", nil, nil, nil, err
+	}
+
+	// Parameters argon2 panics on
+	if iterations < 1 || parallelism < 1 || len(phc.Salt) == 0 || len(phc.Hash) == 0 {
+		return "", nil, nil, nil, ErrInvalidHash
+	}
+
+	p = &Argon2Config{
+		Memory:      uint32(memory),
+		Iterations:  uint32(iterations),
+		Parallelism: uint8(parallelism),
+		SaltLength:  uint32(len(phc.Salt)),
+		KeyLength:   uint32(len(phc.Hash)),
 	}
-	p.KeyLength = uint32(len(hash))
 
-	return p, salt, hash, nil
+	return phc.ID, p, phc.Salt, phc.Hash, nil
 }
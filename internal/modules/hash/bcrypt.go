@@ -0,0 +1,65 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package hash
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt hashes payloads with bcrypt. It is used to validate the passwords of users
+// which were imported from other systems. Bcrypt only uses the first 72 bytes of a
+// payload
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt returns a new bcrypt hasher. A cost of 0 uses the default cost
+func NewBcrypt(cost int) Bcrypt {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+
+	return Bcrypt{
+		cost: cost,
+	}
+}
+
+// Hash hashes the payload
+func (b Bcrypt) Hash(payload string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(payload), b.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Valid compares the input to a hashed payload
+func (b Bcrypt) Valid(input, hashed string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(input))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Matches returns if the hashed payload is a bcrypt hash
+func (b Bcrypt) Matches(hashed string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hashed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsRehash returns if the hashed payload was not created with the current cost
+func (b Bcrypt) NeedsRehash(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != b.cost
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package hash
+
+// Chain creates new hashes with one hash function and validates hashes of all hash
+// functions of the chain. It is used to migrate hashes to another hash function or to
+// other parameters
+type Chain struct {
+	primary Hash
+	others  []Hash
+}
+
+// NewChain returns a new chain which creates new hashes with primary and additionally
+// validates hashes of others
+func NewChain(primary Hash, others ...Hash) Chain {
+	return Chain{
+		primary: primary,
+		others:  others,
+	}
+}
+
+// Hash hashes the payload with the primary hash function
+func (c Chain) Hash(payload string) (string, error) {
+	return c.primary.Hash(payload)
+}
+
+// Valid compares the input to a hashed payload with the hash function which created
+// the hashed payload
+func (c Chain) Valid(input, hashed string) (bool, error) {
+	h := c.find(hashed)
+	if h == nil {
+		return false, ErrInvalidHash
+	}
+
+	return h.Valid(input, hashed)
+}
+
+// Matches returns if the hashed payload was created by one of the hash functions
+func (c Chain) Matches(hashed string) bool {
+	return c.find(hashed) != nil
+}
+
+// NeedsRehash returns if the hashed payload was not created by the primary hash
+// function or with other parameters
+func (c Chain) NeedsRehash(hashed string) bool {
+	return !c.primary.Matches(hashed) || c.primary.NeedsRehash(hashed)
+}
+
+// find returns the hash function which created the hashed payload, or nil if none of
+// the hash functions did
+func (c Chain) find(hashed string) Hash {
+	if c.primary.Matches(hashed) {
+		return c.primary
+	}
+
+	for _, h := range c.others {
+		if h.Matches(hashed) {
+			return h
+		}
+	}
+	return nil
+}
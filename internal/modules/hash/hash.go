@@ -8,4 +8,11 @@ package hash
 type Hash interface {
 	Hash(string) (string, error)
 	Valid(string, string) (bool, error)
+
+	// Matches returns if the hashed payload was created by this hash function
+	Matches(string) bool
+
+	// NeedsRehash returns if the hashed payload was created with other parameters
+	// than new hashes and should be replaced
+	NeedsRehash(string) bool
 }
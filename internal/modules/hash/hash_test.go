@@ -0,0 +1,171 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package hash
+
+import "testing"
+
+// Known answers of the reference implementations: Argon2 from the test vectors of the
+// reference implementation, bcrypt from the jBCrypt test vectors and scrypt from RFC
+// 7914
+const (
+	testArgon2id = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+	testArgon2i  = "$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA"
+	testBcrypt   = "$2a$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i"
+	testScrypt   = "$scrypt$ln=10,r=8,p=16$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA"
+)
+
+// testArgon2Config is cheap, so that hashes can be created quickly
+var testArgon2Config = Argon2Config{Memory: 64, Iterations: 1, Parallelism: 1}
+
+// newTestChain returns the chain the server uses, with cheap Argon2 parameters
+func newTestChain() Chain {
+	return NewChain(NewArgon2WithConfig(testArgon2Config), NewBcrypt(0), NewScrypt())
+}
+
+func TestValid(t *testing.T) {
+	chain := newTestChain()
+
+	tests := []struct {
+		name   string
+		input  string
+		hashed string
+		want   bool
+		err    error
+	}{
+		{"argon2id", "password", testArgon2id, true, nil},
+		{"argon2id wrong", "wrong", testArgon2id, false, nil},
+		{"argon2i", "password", testArgon2i, true, nil},
+		{"argon2i wrong", "wrong", testArgon2i, false, nil},
+		{"bcrypt 2a", "abc", testBcrypt, true, nil},
+		{"bcrypt 2b", "abc", "$2b$" + testBcrypt[4:], true, nil},
+		{"bcrypt 2y", "abc", "$2y$" + testBcrypt[4:], true, nil},
+		{"bcrypt wrong", "wrong", testBcrypt, false, nil},
+		{"scrypt", "password", testScrypt, true, nil},
+		{"scrypt wrong", "wrong", testScrypt, false, nil},
+		{"unknown function", "password", "$md5$c29tZXNhbHQ$aGFzaA", false, ErrInvalidHash},
+		{"plain text", "password", "password", false, ErrInvalidHash},
+		{"argon2 old version", "password", "$argon2id$v=16$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc", false, ErrIncompatibleVersion},
+		{"argon2 no version", "password", "$argon2id$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc", false, ErrIncompatibleVersion},
+		{"argon2 missing parameter", "password", "$argon2id$v=19$m=65536,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc", false, ErrInvalidHash},
+		{"argon2 no iterations", "password", "$argon2id$v=19$m=65536,t=0,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc", false, ErrInvalidHash},
+		{"argon2 parallelism overflow", "password", "$argon2id$v=19$m=65536,t=2,p=256$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc", false, ErrInvalidHash},
+		{"argon2 no hash", "password", "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ", false, ErrInvalidHash},
+		{"scrypt no cost", "password", "$scrypt$ln=0,r=8,p=16$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA", false, ErrInvalidHash},
+		{"scrypt cost overflow", "password", "$scrypt$ln=63,r=8,p=16$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA", false, ErrInvalidHash},
+		{"scrypt no salt", "password", "$scrypt$ln=10,r=8,p=16", false, ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chain.Valid(tt.input, tt.hashed)
+			if got != tt.want || err != tt.err {
+				t.Errorf("Valid() = %v, %v, want %v, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+
+	// Malformed bcrypt hashes are reported by the bcrypt package
+	valid, err := chain.Valid("abc", testBcrypt[:20])
+	if valid || err == nil {
+		t.Errorf("Valid() of a truncated bcrypt hash = %v, %v, want an error", valid, err)
+	}
+}
+
+func TestParsePHC(t *testing.T) {
+	for _, s := range []string{
+		testArgon2id,
+		testScrypt,
+		"$argon2id",
+		"$argon2id$v=19",
+		"$argon2id$m=65536,t=2,p=1",
+		"$argon2id$c29tZXNhbHQ",
+		"$argon2id$c29tZXNhbHQ$aGFzaA",
+	} {
+		p, err := ParsePHC(s)
+		if err != nil {
+			t.Errorf("ParsePHC(%q) error = %v", s, err)
+			continue
+		}
+
+		if p.String() != s {
+			t.Errorf("ParsePHC(%q).String() = %q", s, p.String())
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"argon2id",
+		"argon2id$v=19",
+		"$",
+		"$Argon2id",
+		"$argon2_id",
+		"$argon2idargon2idargon2idargon2id1",
+		"$argon2id$v=",
+		"$argon2id$v=x",
+		"$argon2id$v=-1",
+		"$argon2id$m=",
+		"$argon2id$m=65536,=1",
+		"$argon2id$m=65536,,t=2",
+		"$argon2id$M=65536",
+		"$argon2id$v=19$m=65536$c29tZXNhbHQ=$aGFzaA",
+		"$argon2id$v=19$m=65536$c29tZXNhbHQ$aGFzaA!",
+		"$argon2id$v=19$m=65536$c29tZXNhbHQ$aGFzaA$aGFzaA",
+	} {
+		_, err := ParsePHC(s)
+		if err != ErrInvalidHash {
+			t.Errorf("ParsePHC(%q) error = %v, want %v", s, err, ErrInvalidHash)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	chain := newTestChain()
+
+	current, err := chain.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shortSalt, err := NewArgon2WithConfig(Argon2Config{
+		Memory:      testArgon2Config.Memory,
+		Iterations:  testArgon2Config.Iterations,
+		Parallelism: testArgon2Config.Parallelism,
+		SaltLength:  8,
+	}).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hash   Hash
+		hashed string
+		want   bool
+	}{
+		{"current", chain, current, false},
+		{"argon2id other parameters", chain, testArgon2id, true},
+		{"argon2id shorter salt", chain, shortSalt, true},
+		{"argon2i", chain, testArgon2i, true},
+		{"bcrypt", chain, testBcrypt, true},
+		{"scrypt", chain, testScrypt, true},
+		{"malformed", chain, "$argon2id$v=19$m=64,t=1,p=1", true},
+		{"argon2id vector", NewArgon2WithConfig(Argon2Config{Memory: 65536, Iterations: 2, Parallelism: 1, KeyLength: 32, SaltLength: 8}), testArgon2id, false},
+		{"argon2i same parameters", NewArgon2WithConfig(Argon2Config{Memory: 65536, Iterations: 2, Parallelism: 1, KeyLength: 32, SaltLength: 8}), testArgon2i, true},
+		{"bcrypt same cost", NewBcrypt(6), testBcrypt, false},
+		{"bcrypt other cost", NewBcrypt(0), testBcrypt, true},
+		{"bcrypt malformed", NewBcrypt(6), "$2a$", true},
+		{"scrypt same parameters", NewScryptWithConfig(ScryptConfig{LogN: 10, R: 8, P: 16, SaltLength: 4, KeyLength: 64}), testScrypt, false},
+		{"scrypt other parameters", NewScrypt(), testScrypt, true},
+		{"scrypt shorter salt", NewScryptWithConfig(ScryptConfig{LogN: 10, R: 8, P: 16, SaltLength: 16, KeyLength: 64}), testScrypt, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hash.NeedsRehash(tt.hashed); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
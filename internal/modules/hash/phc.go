@@ -0,0 +1,142 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package hash
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PHC is a hash in the PHC string format
+// '$<id>[$v=<version>][$<param>=<value>(,<param>=<value>)*][$<salt>[$<hash>]]'. Salt
+// and hash are base64 encoded without padding
+type PHC struct {
+	ID      string
+	Version int // 0 if the hash has no version
+	Params  []Param
+	Salt    []byte
+	Hash    []byte
+}
+
+// Param is one parameter of a PHC string
+type Param struct {
+	Name  string
+	Value string
+}
+
+// ParsePHC parses a hash in the PHC string format. It returns ErrInvalidHash if the
+// hash is malformed
+func ParsePHC(s string) (*PHC, error) {
+	fields := strings.Split(s, "$")
+	if len(fields) < 2 || fields[0] != "" || !validPHCName(fields[1]) {
+		return nil, ErrInvalidHash
+	}
+
+	p := &PHC{ID: fields[1]}
+	fields = fields[2:]
+
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "v=") {
+		version, err := strconv.Atoi(strings.TrimPrefix(fields[0], "v="))
+		if err != nil || version < 0 {
+			return nil, ErrInvalidHash
+		}
+		p.Version = version
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 && strings.Contains(fields[0], "=") {
+		for _, param := range strings.Split(fields[0], ",") {
+			kv := strings.SplitN(param, "=", 2)
+			if len(kv) != 2 || !validPHCName(kv[0]) || kv[1] == "" {
+				return nil, ErrInvalidHash
+			}
+			p.Params = append(p.Params, Param{Name: kv[0], Value: kv[1]})
+		}
+		fields = fields[1:]
+	}
+
+	if len(fields) > 2 {
+		return nil, ErrInvalidHash
+	}
+
+	var err error
+	if len(fields) > 0 {
+		p.Salt, err = base64.RawStdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, ErrInvalidHash
+		}
+	}
+
+	if len(fields) > 1 {
+		p.Hash, err = base64.RawStdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, ErrInvalidHash
+		}
+	}
+
+	return p, nil
+}
+
+// Uint returns the value of the parameter with name as unsigned integer which fits into
+// bits. It returns ErrInvalidHash if the parameter is missing or invalid
+func (p *PHC) Uint(name string, bits int) (uint64, error) {
+	for _, param := range p.Params {
+		if param.Name != name {
+			continue
+		}
+
+		v, err := strconv.ParseUint(param.Value, 10, bits)
+		if err != nil {
+			return 0, ErrInvalidHash
+		}
+		return v, nil
+	}
+
+	return 0, ErrInvalidHash
+}
+
+// String returns the hash in the PHC string format
+func (p *PHC) String() string {
+	var b strings.Builder
+	b.WriteString("$" + p.ID)
+
+	if p.Version != 0 {
+		fmt.Fprintf(&b, "$v=%d", p.Version)
+	}
+
+	for i, param := range p.Params {
+		if i == 0 {
+			b.WriteString("$")
+		} else {
+			b.WriteString(",")
+		}
+		b.WriteString(param.Name + "=" + param.Value)
+	}
+
+	if p.Salt != nil {
+		b.WriteString("$" + base64.RawStdEncoding.EncodeToString(p.Salt))
+		if p.Hash != nil {
+			b.WriteString("$" + base64.RawStdEncoding.EncodeToString(p.Hash))
+		}
+	}
+
+	return b.String()
+}
+
+// validPHCName returns if s is a valid function or parameter name of a PHC string
+func validPHCName(s string) bool {
+	if s == "" || len(s) > 32 {
+		return false
+	}
+
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package hash
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+
+	"chapper.dev/server/internal/utils"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scryptID is the PHC identifier of scrypt
+const scryptID = "scrypt"
+
+// Scrypt hashes payloads with scrypt. It is used to validate the passwords of users
+// which were imported from other systems. Hashes use the PHC string format
+// '$scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>'
+type Scrypt struct {
+	config ScryptConfig
+}
+
+type ScryptConfig struct {
+	LogN       uint8
+	R          int
+	P          int
+	SaltLength uint32
+	KeyLength  int
+}
+
+// DefaultScryptConfig is the default config when using NewScrypt
+var DefaultScryptConfig = ScryptConfig{
+	LogN:       15,
+	R:          8,
+	P:          1,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// NewScrypt returns a new scrypt hasher with the default config
+func NewScrypt() Scrypt {
+	return NewScryptWithConfig(DefaultScryptConfig)
+}
+
+// NewScryptWithConfig returns a new scrypt hasher with a custom config
+func NewScryptWithConfig(c ScryptConfig) Scrypt {
+	return Scrypt{
+		config: c,
+	}
+}
+
+// Hash hashes the payload
+func (s Scrypt) Hash(payload string) (string, error) {
+	salt, err := utils.RandomByteSlice(s.config.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := scrypt.Key([]byte(payload), salt, 1<<s.config.LogN, s.config.R, s.config.P, s.config.KeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := &PHC{
+		ID: scryptID,
+		Params: []Param{
+			{Name: "ln", Value: strconv.Itoa(int(s.config.LogN))},
+			{Name: "r", Value: strconv.Itoa(s.config.R)},
+			{Name: "p", Value: strconv.Itoa(s.config.P)},
+		},
+		Salt: salt,
+		Hash: hash,
+	}
+	return encoded.String(), nil
+}
+
+// Valid compares the input to a hashed payload. The hash is computed with the
+// parameters stored in the hashed payload
+func (s Scrypt) Valid(input, hashed string) (bool, error) {
+	p, salt, hash, err := decodeScryptHash(hashed)
+	if err != nil {
+		return false, err
+	}
+
+	compareHash, err := scrypt.Key([]byte(input), salt, 1<<p.LogN, p.R, p.P, p.KeyLength)
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(hash, compareHash) == 1, nil
+}
+
+// Matches returns if the hashed payload is a scrypt hash
+func (s Scrypt) Matches(hashed string) bool {
+	return strings.HasPrefix(hashed, "$"+scryptID+"$")
+}
+
+// NeedsRehash returns if the hashed payload was not created with the current config
+func (s Scrypt) NeedsRehash(hashed string) bool {
+	p, _, _, err := decodeScryptHash(hashed)
+	if err != nil {
+		return true
+	}
+
+	return p.LogN != s.config.LogN ||
+		p.R != s.config.R ||
+		p.P != s.config.P ||
+		p.SaltLength < s.config.SaltLength ||
+		p.KeyLength != s.config.KeyLength
+}
+
+// decodeScryptHash returns the parameters, the salt and the hash of a scrypt hash in
+// the PHC string format
+func decodeScryptHash(encodedHash string) (p *ScryptConfig, salt, hash []byte, err error) {
+	phc, err := ParsePHC(encodedHash)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if phc.ID != scryptID || len(phc.Salt) == 0 || len(phc.Hash) == 0 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	logN, err := phc.Uint("ln", 8)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	r, err := phc.Uint("r", 31)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	parallelism, err := phc.Uint("p", 31)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// N has to be a power of 2 greater than 1 which fits into an int
+	if logN < 1 || logN > 62 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	p = &ScryptConfig{
+		LogN:       uint8(logN),
+		R:          int(r),
+		P:          int(parallelism),
+		SaltLength: uint32(len(phc.Salt)),
+		KeyLength:  len(phc.Hash),
+	}
+
+	return p, phc.Salt, phc.Hash, nil
+}
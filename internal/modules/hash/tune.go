@@ -0,0 +1,94 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package hash
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/constants"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// tuneMinMemory is the memory tuning starts with
+	tuneMinMemory = 8 * constants.Argon2Kibibyte
+
+	// tuneMaxIterations limits the iterations if hashes are very fast
+	tuneMaxIterations = 100
+
+	// tuneRuns is the number of hashes per measurement, the fastest one counts
+	tuneRuns = 3
+)
+
+// TuneArgon2 benchmarks Argon2id on this machine and returns the config whose hashes
+// take at most target. Memory is increased first, up to maxMemory KiB, then the
+// iterations. It also returns the duration of one hash with the config. If even the
+// smallest config takes longer than target, the smallest config is returned
+func TuneArgon2(target time.Duration, maxMemory uint32, parallelism uint8) (Argon2Config, time.Duration) {
+	c := DefaultArgon2Config
+	c.Memory = tuneMinMemory
+	c.Iterations = 1
+	c.Parallelism = parallelism
+
+	if c.Memory > maxMemory {
+		c.Memory = maxMemory
+	}
+
+	took := measureArgon2(c)
+
+	// Double the memory while the hash is fast enough
+	for took <= target && c.Memory*2 <= maxMemory && c.Memory*2 > c.Memory {
+		next := c
+		next.Memory *= 2
+
+		d := measureArgon2(next)
+		if d > target {
+			break
+		}
+		c, took = next, d
+	}
+
+	if took >= target {
+		return c, took
+	}
+
+	// The duration grows linearly with the iterations, so the estimate only needs
+	// small corrections
+	next := c
+	next.Iterations = uint32(target / took)
+	if next.Iterations > tuneMaxIterations {
+		next.Iterations = tuneMaxIterations
+	}
+
+	for next.Iterations > c.Iterations {
+		d := measureArgon2(next)
+		if d <= target {
+			return next, d
+		}
+		next.Iterations--
+	}
+
+	return c, took
+}
+
+// measureArgon2 returns the duration of the fastest of multiple hashes with config c
+func measureArgon2(c Argon2Config) time.Duration {
+	password := []byte("correct horse battery staple")
+	salt := make([]byte, c.SaltLength)
+
+	var fastest time.Duration
+	for i := 0; i < tuneRuns; i++ {
+		start := time.Now()
+		argon2.IDKey(password, salt, c.Iterations, c.Memory, c.Parallelism, c.KeyLength)
+
+		took := time.Since(start)
+		if i == 0 || took < fastest {
+			fastest = took
+		}
+	}
+
+	return fastest
+}
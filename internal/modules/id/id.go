@@ -0,0 +1,100 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package id provides unique identifiers for entities and random codes for invites
+package id
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+
+	"chapper.dev/server/internal/utils"
+)
+
+const (
+	// Length is the length of an ID
+	Length = 26
+
+	// inviteCodeBytes is the number of random bytes of an invite code. 16 bytes encode
+	// to a 22 characters long URL-safe code
+	inviteCodeBytes = 16
+
+	// alphabet is Crockford's base32 alphabet. It is ordered, so IDs sort like the time
+	// they were created at
+	alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// New returns a new unique ID. IDs are 26 characters long and consist of a 48 bit
+// millisecond timestamp followed by 80 random bits, both encoded in Crockford's base32.
+// IDs created later sort after IDs created earlier
+func New() (string, error) {
+	return At(time.Now())
+}
+
+// At returns a new unique ID with the timestamp t
+func At(t time.Time) (string, error) {
+	var b [16]byte
+
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+
+	_, err := rand.Read(b[6:])
+	if err != nil {
+		return "", err
+	}
+
+	return encode(b), nil
+}
+
+// Valid returns if s is a well-formed ID
+func Valid(s string) bool {
+	if len(s) != Length {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isAlphabet(s[i]) {
+			return false
+		}
+	}
+
+	// The first character only holds 3 bits of the timestamp
+	return s[0] <= '7'
+}
+
+// InviteCode returns a new cryptographically secure, URL-safe invite code
+func InviteCode() (string, error) {
+	return utils.RandomCryptoString(inviteCodeBytes)
+}
+
+// encode encodes the 128 bits of b into 26 base32 characters. The first character
+// encodes the 3 most significant bits, every following character 5 bits
+func encode(b [16]byte) string {
+	var (
+		out [Length]byte
+		hi  = binary.BigEndian.Uint64(b[0:8])
+		lo  = binary.BigEndian.Uint64(b[8:16])
+	)
+
+	for i := Length - 1; i >= 0; i-- {
+		out[i] = alphabet[lo&0x1F]
+
+		// Shift the 128 bit value right by 5 bits
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out[:])
+}
+
+func isAlphabet(c byte) bool {
+	for i := 0; i < len(alphabet); i++ {
+		if alphabet[i] == c {
+			return true
+		}
+	}
+	return false
+}
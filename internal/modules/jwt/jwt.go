@@ -17,16 +17,12 @@ var (
 	ErrUsernameEmpty = errors.New("Username cannot be empty")
 )
 
-// JWT wraps a JWT token, it's key and claims
-type JWT struct {
-	token  *jwt.Token
-	key    string
-	claims Claims
-}
-
-// Claims is a custom claims struct
+// Claims is a custom claims struct. TokenID is only set for personal access tokens,
+// which are not JWTs. It is never part of a signed token
 type Claims struct {
 	Username   string            `json:"username"`
+	SessionID  string            `json:"sid"`
+	TokenID    string            `json:"-"`
 	Privileges models.Privileges `json:"privileges"`
 	StandardClaims
 }
@@ -34,44 +30,11 @@ type Claims struct {
 // StandardClaims is a wrapper for jwt.StandardClaims
 type StandardClaims jwt.StandardClaims
 
-// New returns a new JWT instance
-func New(key string, c *Claims) *JWT {
-	return &JWT{
-		token: jwt.NewWithClaims(jwt.SigningMethodHS256, c),
-		key:   key,
-	}
-}
-
-// Parse parses the input token and returns a new JWT instance or an error
-func Parse(input, key string, c Claims) (*JWT, error) {
-	token, err := jwt.ParseWithClaims(input, &c, func(token *jwt.Token) (interface{}, error) {
-		return []byte(key), nil
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return &JWT{
-		token:  token,
-		key:    key,
-		claims: c,
-	}, nil
-}
-
-// Claims returns the cliams of the token
-func (j *JWT) Claims() Claims {
-	return j.claims
-}
-
-// Sign signs the token and returns the token as a string or an error
-func (j *JWT) Sign() (string, error) {
-	return j.token.SignedString([]byte(j.key))
-}
-
-// Valid returns wether the claims are valid
+// Valid returns wether the claims are valid. Tokens with an expiry are invalid after
+// they expired
 func (c Claims) Valid() error {
 	if c.Username == "" {
 		return ErrUsernameEmpty
 	}
-	return nil
+	return jwt.StandardClaims(c.StandardClaims).Valid()
 }
@@ -0,0 +1,352 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"chapper.dev/server/internal/models"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// DefaultRotation is the time after which a new key replaces the active key if
+	// the rotation is not set
+	DefaultRotation = 30 * 24 * time.Hour
+
+	// reloadInterval is the minimum time between two reloads of the keys from the
+	// store, which happen if a token carries an unknown key ID. Other instances
+	// sharing the store may have rotated the key
+	reloadInterval = time.Minute
+)
+
+var (
+	// ErrMissingSecret indicates that HS256 is used without a secret
+	ErrMissingSecret = errors.New("JWT_SIGNING_KEY cannot be empty if JWT_ALGORITHM is HS256")
+
+	// ErrUnknownKey indicates that the key which signed a token is unknown or expired
+	ErrUnknownKey = errors.New("unknown or expired jwt key id")
+)
+
+// Options describes how tokens are signed
+type Options struct {
+	// Algorithm is EdDSA, RS256 or HS256
+	Algorithm string
+
+	// Secret is the key of HS256. Asymmetric keys are generated and saved in the store
+	Secret string
+
+	// Rotation is the time after which a new key replaces the active key. HS256 keys
+	// are not rotated
+	Rotation time.Duration
+
+	// Retention is the time retired keys still verify tokens. It has to be at least
+	// the lifetime of the tokens
+	Retention time.Duration
+}
+
+// Validate returns an error if the options are invalid
+func (o Options) Validate() error {
+	switch o.Algorithm {
+	case AlgorithmEdDSA, AlgorithmRS256:
+		return nil
+	case AlgorithmHS256:
+		if o.Secret == "" {
+			return ErrMissingSecret
+		}
+		return nil
+	}
+	return ErrUnknownAlgorithm
+}
+
+// KeyStore persists the keys of the keyring
+type KeyStore interface {
+	// GetSigningKeys returns all keys
+	GetSigningKeys() ([]models.SigningKey, error)
+
+	// CreateSigningKey saves a new key
+	CreateSigningKey(key *models.SigningKey) error
+
+	// RetireSigningKeys retires all keys except the one identified by id
+	RetireSigningKeys(id string, retiredAt time.Time) error
+
+	// DeleteSigningKeys deletes all keys which were retired before the provided time
+	DeleteSigningKeys(retiredBefore time.Time) error
+}
+
+// Keyring signs tokens with the active key and verifies tokens with all keys which
+// are not expired. Keys are identified by the 'kid' header of the token. The keyring
+// is safe for concurrent use
+type Keyring struct {
+	sync.RWMutex
+
+	store    KeyStore
+	options  Options
+	active   *Key
+	keys     map[string]*Key
+	loadedAt time.Time
+}
+
+// NewKeyring returns a new keyring. Asymmetric keys are loaded from the store and a new
+// key is generated if there is none or the active key is due for rotation
+func NewKeyring(store KeyStore, options Options) (*Keyring, error) {
+	err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Rotation <= 0 {
+		options.Rotation = DefaultRotation
+	}
+
+	k := &Keyring{
+		store:   store,
+		options: options,
+		keys:    make(map[string]*Key),
+	}
+
+	if options.Algorithm == AlgorithmHS256 {
+		k.active = secretKey(options.Secret)
+		k.keys[k.active.ID] = k.active
+		return k, nil
+	}
+
+	k.Lock()
+	defer k.Unlock()
+
+	err = k.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if k.due() {
+		err = k.rotate()
+	}
+	return k, err
+}
+
+// Sign signs the claims with the active key and returns the token. The active key is
+// rotated first if it is due
+func (k *Keyring) Sign(c *Claims) (string, error) {
+	key, err := k.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method(), c)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.private)
+}
+
+// Parse verifies the input token with the key identified by its 'kid' header and
+// returns the token with its claims of type *Claims
+func (k *Keyring) Parse(input string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(input, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		id, _ := token.Header["kid"].(string)
+
+		key := k.verificationKey(id)
+		if key == nil {
+			return nil, ErrUnknownKey
+		}
+
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected jwt signing method=%v", token.Header["alg"])
+		}
+		return key.public, nil
+	})
+}
+
+// JWKS returns the public keys which verify tokens, the active key first. It is empty
+// if tokens are signed with HS256
+func (k *Keyring) JWKS() JWKS {
+	k.reload()
+
+	k.RLock()
+	defer k.RUnlock()
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range k.sorted() {
+		if k.expired(key) {
+			continue
+		}
+
+		jwk, ok := key.JWK()
+		if ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// signingKey returns the active key and rotates it if it is due. Other instances
+// sharing the store may have rotated it already, so the keys are reloaded first
+func (k *Keyring) signingKey() (*Key, error) {
+	k.RLock()
+	key, due := k.active, k.due()
+	k.RUnlock()
+
+	if !due {
+		return key, nil
+	}
+
+	k.Lock()
+	defer k.Unlock()
+
+	err := k.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if k.due() {
+		err = k.rotate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return k.active, nil
+}
+
+// verificationKey returns the key identified by id or nil if it is unknown or expired.
+// Unknown keys trigger a reload of the keys
+func (k *Keyring) verificationKey(id string) *Key {
+	key := k.lookup(id)
+	if key == nil && k.reload() {
+		key = k.lookup(id)
+	}
+	return key
+}
+
+// lookup returns the key identified by id or nil if it is unknown or expired
+func (k *Keyring) lookup(id string) *Key {
+	k.RLock()
+	defer k.RUnlock()
+
+	key, ok := k.keys[id]
+	if !ok || k.expired(key) {
+		return nil
+	}
+	return key
+}
+
+// reload loads the keys from the store if they were loaded longer ago than the reload
+// interval. It returns if the keys were reloaded
+func (k *Keyring) reload() bool {
+	if k.options.Algorithm == AlgorithmHS256 {
+		return false
+	}
+
+	k.Lock()
+	defer k.Unlock()
+
+	if time.Since(k.loadedAt) < reloadInterval {
+		return false
+	}
+
+	// A failed reload keeps the known keys and is not retried before the interval
+	err := k.load()
+	k.loadedAt = time.Now()
+	return err == nil
+}
+
+// load replaces the keys with the ones in the store and deletes expired keys from the
+// store. The newest key which is not retired becomes the active key. The caller has to
+// hold the write lock
+func (k *Keyring) load() error {
+	err := k.store.DeleteSigningKeys(time.Now().UTC().Add(-k.options.Retention))
+	if err != nil {
+		return err
+	}
+
+	saved, err := k.store.GetSigningKeys()
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*Key)
+	var active *Key
+
+	for _, s := range saved {
+		key, err := parseKey(s)
+		if err != nil {
+			return fmt.Errorf("%w '%s': %v", ErrInvalidKey, s.ID, err)
+		}
+		keys[key.ID] = key
+
+		if key.RetiredAt.IsZero() && (active == nil || key.CreatedAt.After(active.CreatedAt)) {
+			active = key
+		}
+	}
+
+	k.keys = keys
+	k.active = active
+	k.loadedAt = time.Now()
+	return nil
+}
+
+// rotate generates a new active key and retires all other keys. The caller has to hold
+// the write lock
+func (k *Keyring) rotate() error {
+	key, saved, err := generateKey(k.options.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	err = k.store.CreateSigningKey(saved)
+	if err != nil {
+		return err
+	}
+
+	err = k.store.RetireSigningKeys(key.ID, key.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range k.keys {
+		if other.RetiredAt.IsZero() {
+			other.RetiredAt = key.CreatedAt
+		}
+	}
+
+	k.keys[key.ID] = key
+	k.active = key
+	return nil
+}
+
+// due returns if there is no active key, it uses another algorithm or it is older than
+// the rotation. The caller has to hold the lock
+func (k *Keyring) due() bool {
+	if k.options.Algorithm == AlgorithmHS256 {
+		return false
+	}
+
+	return k.active == nil ||
+		k.active.Algorithm != k.options.Algorithm ||
+		time.Since(k.active.CreatedAt) >= k.options.Rotation
+}
+
+// expired returns if the retired key no longer verifies tokens. The caller has to hold
+// the lock
+func (k *Keyring) expired(key *Key) bool {
+	return !key.RetiredAt.IsZero() && time.Since(key.RetiredAt) > k.options.Retention
+}
+
+// sorted returns the keys, the newest first. The caller has to hold the lock
+func (k *Keyring) sorted() []*Key {
+	keys := make([]*Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/utils"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// AlgorithmEdDSA signs tokens with Ed25519 keys
+	AlgorithmEdDSA = "EdDSA"
+
+	// AlgorithmRS256 signs tokens with RSA keys and SHA-256
+	AlgorithmRS256 = "RS256"
+
+	// AlgorithmHS256 signs tokens with HMAC SHA-256 and the configured secret. The
+	// secret is not rotated and other services need it to verify tokens
+	AlgorithmHS256 = "HS256"
+
+	// rsaKeyBits is the size of generated RSA keys
+	rsaKeyBits = 2048
+
+	// keyIDBytes is the number of random bytes of the ID of generated keys
+	keyIDBytes = 12
+)
+
+var (
+	// ErrUnknownAlgorithm indicates that the signing algorithm is not supported
+	ErrUnknownAlgorithm = errors.New("unknown jwt signing algorithm, use EdDSA, RS256 or HS256")
+
+	// ErrInvalidKey indicates that a saved signing key could not be decoded
+	ErrInvalidKey = errors.New("invalid jwt signing key")
+)
+
+// Key is a key of the keyring. RetiredAt is zero as long as the key signs new tokens
+type Key struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	RetiredAt time.Time
+
+	private interface{}
+	public  interface{}
+}
+
+// JWK is the public part of a key as JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set of the public keys which verify tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// generateKey generates a new key of algorithm. It returns the key and its
+// representation in the store
+func generateKey(algorithm string) (*Key, *models.SigningKey, error) {
+	var private interface{}
+	var err error
+
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, nil, ErrUnknownAlgorithm
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id, err := utils.RandomCryptoString(keyIDBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	saved := &models.SigningKey{
+		ID:         id,
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	key, err := parseKey(*saved)
+	return key, saved, err
+}
+
+// parseKey decodes the key saved in the store
+func parseKey(saved models.SigningKey) (*Key, error) {
+	block, _ := pem.Decode([]byte(saved.PrivateKey))
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{
+		ID:        saved.ID,
+		Algorithm: saved.Algorithm,
+		CreatedAt: saved.CreatedAt,
+		RetiredAt: saved.RetiredAt.ValueOrZero(),
+		private:   private,
+	}
+
+	// The algorithm has to match the type of the key
+	switch private := private.(type) {
+	case ed25519.PrivateKey:
+		if saved.Algorithm != AlgorithmEdDSA {
+			return nil, ErrInvalidKey
+		}
+		key.public = private.Public()
+	case *rsa.PrivateKey:
+		if saved.Algorithm != AlgorithmRS256 {
+			return nil, ErrInvalidKey
+		}
+		key.public = private.Public()
+	default:
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// secretKey returns the HS256 key of secret. The ID is derived from the secret, so that
+// it stays the same across restarts
+func secretKey(secret string) *Key {
+	sum := sha256.Sum256([]byte(secret))
+
+	return &Key{
+		ID:        hex.EncodeToString(sum[:8]),
+		Algorithm: AlgorithmHS256,
+		private:   []byte(secret),
+		public:    []byte(secret),
+	}
+}
+
+// method returns the signing method of the key
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgorithmEdDSA:
+		return SigningMethodEdDSA
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	case AlgorithmHS256:
+		return jwt.SigningMethodHS256
+	}
+	return nil
+}
+
+// JWK returns the public key as JSON Web Key. Secret keys can't be published and
+// return false
+func (k *Key) JWK() (JWK, bool) {
+	jwk := JWK{
+		Use:       "sig",
+		Algorithm: k.Algorithm,
+		KeyID:     k.ID,
+	}
+
+	switch public := k.public.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
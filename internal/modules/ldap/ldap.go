@@ -0,0 +1,316 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package ldap authenticates users against an LDAP directory, like OpenLDAP or Active
+// Directory. Users are searched with a service account and authenticated by binding
+// with their DN and password. Every operation opens its own connection
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+const (
+	// DefaultTimeout is the timeout of connections and requests to the directory if no
+	// timeout is set
+	DefaultTimeout = 10 * time.Second
+
+	// pageSize is the number of entries requested at once when all users are listed
+	pageSize = 500
+)
+
+var (
+	// ErrMissingURL indicates that the URL of the directory is empty
+	ErrMissingURL = errors.New("ldap url cannot be empty")
+
+	// ErrInvalidScheme indicates that the URL of the directory is neither ldap:// nor
+	// ldaps://
+	ErrInvalidScheme = errors.New("ldap url has to start with ldap:// or ldaps://")
+
+	// ErrMissingBaseDN indicates that the base DN of the search is empty
+	ErrMissingBaseDN = errors.New("ldap base dn cannot be empty")
+
+	// ErrMissingUsernameAttribute indicates that the attribute of usernames is empty
+	ErrMissingUsernameAttribute = errors.New("ldap username attribute cannot be empty")
+
+	// ErrUserNotFound indicates that no user with the username matches the user filter
+	ErrUserNotFound = errors.New("ldap user not found")
+
+	// ErrAmbiguousUser indicates that more than one user has the username
+	ErrAmbiguousUser = errors.New("ldap username matches more than one user")
+
+	// ErrInvalidCredentials indicates that the password of the user is wrong
+	ErrInvalidCredentials = errors.New("ldap credentials are invalid")
+)
+
+// Options describes how users are found in the directory
+type Options struct {
+	// URL of the directory, ldap://host:389 or ldaps://host:636
+	URL string
+
+	// StartTLS upgrades ldap:// connections to TLS before binding
+	StartTLS bool
+
+	// BindDN and BindPassword are the credentials of the service account which
+	// searches users. The search is anonymous if BindDN is empty
+	BindDN       string
+	BindPassword string
+
+	// BaseDN is the subtree which is searched for users
+	BaseDN string
+
+	// UserFilter restricts the entries which are users, e.g. (objectClass=person).
+	// Users which stop matching the filter are treated as removed
+	UserFilter string
+
+	// UsernameAttribute holds the login name, like uid or sAMAccountName. EmailAttribute
+	// and GroupAttribute are optional
+	UsernameAttribute string
+	EmailAttribute    string
+	GroupAttribute    string
+
+	// Timeout of connections and requests
+	Timeout time.Duration
+}
+
+// Validate returns an error if the options are invalid
+func (o Options) Validate() error {
+	if o.URL == "" {
+		return ErrMissingURL
+	}
+
+	if o.BaseDN == "" {
+		return ErrMissingBaseDN
+	}
+
+	if o.UsernameAttribute == "" {
+		return ErrMissingUsernameAttribute
+	}
+
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return ErrInvalidScheme
+	}
+
+	if o.UserFilter != "" {
+		_, err = goldap.CompileFilter(o.UserFilter)
+		if err != nil {
+			return fmt.Errorf("ldap user filter is invalid: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Entry is a user of the directory
+type Entry struct {
+	DN       string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// GroupNames returns the groups of the entry. Groups which are DNs are additionally
+// returned by the value of their first RDN, so that cn=admins,ou=groups,dc=example,dc=org
+// can be referred to as admins
+func (e Entry) GroupNames() []string {
+	names := make([]string, 0, 2*len(e.Groups))
+	for _, group := range e.Groups {
+		names = append(names, group)
+
+		dn, err := goldap.ParseDN(group)
+		if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+			continue
+		}
+
+		if name := dn.RDNs[0].Attributes[0].Value; name != group {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Directory is the LDAP directory users authenticate against. It is safe for
+// concurrent use
+type Directory struct {
+	options Options
+}
+
+// New returns a new directory. The connection is only opened when it is used
+func New(options Options) (*Directory, error) {
+	err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	return &Directory{options: options}, nil
+}
+
+// URL returns the LDAP URL of the base DN, which identifies the directory
+func (d *Directory) URL() string {
+	return strings.TrimSuffix(d.options.URL, "/") + "/" + d.options.BaseDN
+}
+
+// Authenticate returns the user with username if password is correct. It returns
+// ErrUserNotFound if no user has the username and ErrInvalidCredentials if the
+// password is wrong
+func (d *Directory) Authenticate(username, password string) (*Entry, error) {
+	// An empty password would be an unauthenticated bind, which succeeds for any DN
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	res, err := conn.Search(d.searchRequest(goldap.EscapeFilter(username), 2))
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, err
+	}
+
+	switch {
+	case len(res.Entries) == 0:
+		return nil, ErrUserNotFound
+	case len(res.Entries) > 1:
+		return nil, ErrAmbiguousUser
+	}
+
+	entry := d.entry(res.Entries[0])
+
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// Users returns all users which match the user filter
+func (d *Directory) Users() ([]Entry, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	res, err := conn.SearchWithPaging(d.searchRequest("*", 0), pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		entry := d.entry(e)
+		if entry.Username != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// connect opens a connection to the directory and binds as the service account
+func (d *Directory) connect() (*goldap.Conn, error) {
+	u, err := url.Parse(d.options.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{ServerName: u.Hostname()}
+
+	conn, err := goldap.DialURL(d.options.URL,
+		goldap.DialWithDialer(&net.Dialer{Timeout: d.options.Timeout}),
+		goldap.DialWithTLSConfig(config),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(d.options.Timeout)
+
+	if d.options.StartTLS && u.Scheme == "ldap" {
+		err = conn.StartTLS(config)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if d.options.BindDN != "" {
+		err = conn.Bind(d.options.BindDN, d.options.BindPassword)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap bind of the service account failed: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// searchRequest returns the search of users whose username matches the filter value
+// username, which has to be escaped
+func (d *Directory) searchRequest(username string, sizeLimit int) *goldap.SearchRequest {
+	filter := fmt.Sprintf("(%s=%s)", d.options.UsernameAttribute, username)
+	if d.options.UserFilter != "" {
+		filter = fmt.Sprintf("(&%s%s)", d.options.UserFilter, filter)
+	}
+
+	attributes := []string{d.options.UsernameAttribute}
+	for _, attribute := range []string{d.options.EmailAttribute, d.options.GroupAttribute} {
+		if attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	return goldap.NewSearchRequest(
+		d.options.BaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		sizeLimit,
+		int(d.options.Timeout/time.Second),
+		false,
+		filter,
+		attributes,
+		nil,
+	)
+}
+
+// entry converts a search result to an entry
+func (d *Directory) entry(e *goldap.Entry) Entry {
+	entry := Entry{
+		DN:       e.DN,
+		Username: e.GetEqualFoldAttributeValue(d.options.UsernameAttribute),
+	}
+
+	if d.options.EmailAttribute != "" {
+		entry.Email = e.GetEqualFoldAttributeValue(d.options.EmailAttribute)
+	}
+
+	if d.options.GroupAttribute != "" {
+		entry.Groups = e.GetEqualFoldAttributeValues(d.options.GroupAttribute)
+	}
+
+	return entry
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"chapper.dev/server/internal/modules/ldap/ldaptest"
+)
+
+const (
+	testBaseDN      = "dc=chapper,dc=test"
+	testServiceDN   = "cn=service,dc=chapper,dc=test"
+	testServicePass = "service-secret"
+)
+
+func testPerson(uid, ou, password string, groups ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       "uid=" + uid + ",ou=" + ou + "," + testBaseDN,
+		Password: password,
+		Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {uid},
+			"mail":        {uid + "@chapper.test"},
+			"memberOf":    groups,
+		},
+	}
+}
+
+func testService() ldaptest.Entry {
+	return ldaptest.Entry{DN: testServiceDN, Password: testServicePass}
+}
+
+func newTestDirectory(t *testing.T, entries ...ldaptest.Entry) (*Directory, *ldaptest.Server) {
+	server, err := ldaptest.NewServer(append(entries, testService())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	d, err := New(Options{
+		URL:               server.URL,
+		BindDN:            testServiceDN,
+		BindPassword:      testServicePass,
+		BaseDN:            testBaseDN,
+		UserFilter:        "(objectClass=person)",
+		UsernameAttribute: "uid",
+		EmailAttribute:    "mail",
+		GroupAttribute:    "memberOf",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d, server
+}
+
+func TestAuthenticate(t *testing.T) {
+	d, _ := newTestDirectory(t,
+		testPerson("alice", "people", "secret", "cn=admins,ou=groups,dc=chapper,dc=test"),
+		testPerson("bob", "people", "hunter2"),
+	)
+
+	entry, err := d.Authenticate("alice", "secret")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	want := &Entry{
+		DN:       "uid=alice,ou=people," + testBaseDN,
+		Username: "alice",
+		Email:    "alice@chapper.test",
+		Groups:   []string{"cn=admins,ou=groups,dc=chapper,dc=test"},
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("Authenticate() = %+v, want %+v", entry, want)
+	}
+
+	names := entry.GroupNames()
+	if !reflect.DeepEqual(names, []string{"cn=admins,ou=groups,dc=chapper,dc=test", "admins"}) {
+		t.Errorf("GroupNames() = %v, want the DN and admins", names)
+	}
+}
+
+func TestAuthenticateInvalidCredentials(t *testing.T) {
+	d, _ := newTestDirectory(t, testPerson("alice", "people", "secret"))
+
+	_, err := d.Authenticate("alice", "wrong")
+	if err != ErrInvalidCredentials {
+		t.Errorf("Authenticate() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	_, err = d.Authenticate("nobody", "secret")
+	if err != ErrUserNotFound {
+		t.Errorf("Authenticate() of unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestAuthenticateEmptyPassword(t *testing.T) {
+	d, server := newTestDirectory(t, testPerson("alice", "people", "secret"))
+
+	// The directory accepts unauthenticated binds, the empty password must never reach it
+	_, err := d.Authenticate("alice", "")
+	if err != ErrInvalidCredentials {
+		t.Fatalf("Authenticate() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	if binds := server.Binds(); len(binds) != 0 {
+		t.Errorf("directory received binds %v", binds)
+	}
+}
+
+func TestAuthenticateFilterEscaping(t *testing.T) {
+	d, _ := newTestDirectory(t, testPerson("alice", "people", "secret"))
+
+	for _, username := range []string{"*", "alice)(uid=*", "*)(objectClass=*", `alice\2a`} {
+		entry, err := d.Authenticate(username, "secret")
+		if err != ErrUserNotFound {
+			t.Errorf("Authenticate(%q) = %+v, %v, want %v", username, entry, err, ErrUserNotFound)
+		}
+	}
+}
+
+func TestAuthenticateAmbiguousUser(t *testing.T) {
+	d, _ := newTestDirectory(t,
+		testPerson("alice", "people", "secret"),
+		testPerson("alice", "contractors", "secret"),
+		testPerson("alice", "interns", "secret"),
+	)
+
+	_, err := d.Authenticate("alice", "secret")
+	if err != ErrAmbiguousUser {
+		t.Errorf("Authenticate() error = %v, want %v", err, ErrAmbiguousUser)
+	}
+}
+
+func TestAuthenticateUserFilter(t *testing.T) {
+	group := ldaptest.Entry{
+		DN:       "uid=admins,ou=groups," + testBaseDN,
+		Password: "secret",
+		Attributes: map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"uid":         {"admins"},
+		},
+	}
+	d, _ := newTestDirectory(t, group)
+
+	_, err := d.Authenticate("admins", "secret")
+	if err != ErrUserNotFound {
+		t.Errorf("Authenticate() of an entry outside the user filter error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestAuthenticateServiceAccount(t *testing.T) {
+	d, server := newTestDirectory(t, testPerson("alice", "people", "secret"))
+	d.options.BindPassword = "wrong"
+
+	_, err := d.Authenticate("alice", "secret")
+	if err == nil {
+		t.Fatal("Authenticate() succeeded although the service account bind failed")
+	}
+
+	if binds := server.Binds(); len(binds) != 1 || binds[0] != testServiceDN {
+		t.Errorf("directory received binds %v, want only the service account", binds)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	d, server := newTestDirectory(t,
+		testPerson("alice", "people", "secret"),
+		testPerson("bob", "people", ""),
+		ldaptest.Entry{
+			DN:         "cn=admins,ou=groups," + testBaseDN,
+			Attributes: map[string][]string{"objectClass": {"groupOfNames"}, "cn": {"admins"}},
+		},
+	)
+
+	entries, err := d.Users()
+	if err != nil {
+		t.Fatalf("Users() error = %v", err)
+	}
+
+	if got := usernames(entries); !reflect.DeepEqual(got, []string{"alice", "bob"}) {
+		t.Errorf("Users() = %v, want [alice bob]", got)
+	}
+
+	server.SetEntries(testPerson("bob", "people", ""), testService())
+
+	entries, err = d.Users()
+	if err != nil {
+		t.Fatalf("Users() error = %v", err)
+	}
+
+	if got := usernames(entries); !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Errorf("Users() after alice was removed = %v, want [bob]", got)
+	}
+}
+
+func usernames(entries []Entry) []string {
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Username)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package ldaptest provides an in-process LDAP directory for tests. It understands the
+// operations the ldap package uses: simple binds, subtree searches with and, or, not,
+// equality and presence filters, and unbinds. Other requests close the connection
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// Entry is an entry of the directory. Entries with a password can bind
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is a directory listening on a random local port. Its entries can be changed
+// while it is running
+type Server struct {
+	// URL of the directory, ldap://127.0.0.1:port
+	URL string
+
+	listener net.Listener
+
+	mu      sync.Mutex
+	entries []Entry
+	binds   []string
+}
+
+// NewServer starts a new directory with the entries
+func NewServer(entries ...Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+	}
+
+	go s.serve()
+	return s, nil
+}
+
+// Close stops accepting connections
+func (s *Server) Close() {
+	s.listener.Close()
+}
+
+// SetEntries replaces all entries of the directory
+func (s *Server) SetEntries(entries ...Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+// Binds returns the DNs of all binds so far, including failed and unauthenticated ones
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// handle answers the requests of one connection until it is closed or unbound
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			code := s.bind(op.Children[1].Data.String(), op.Children[2].Data.String())
+			conn.Write(result(id, goldap.ApplicationBindResponse, code).Bytes())
+		case goldap.ApplicationSearchRequest:
+			for _, entry := range s.search(id, op) {
+				conn.Write(entry.Bytes())
+			}
+		default:
+			return
+		}
+	}
+}
+
+// bind returns the result code of a simple bind. Binds without password are
+// unauthenticated binds, which succeed for any DN like with most directories
+func (s *Server) bind(dn, password string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.binds = append(s.binds, dn)
+
+	if password == "" {
+		return goldap.LDAPResultSuccess
+	}
+
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			return goldap.LDAPResultSuccess
+		}
+	}
+
+	return goldap.LDAPResultInvalidCredentials
+}
+
+// search returns the messages of the matching entries followed by the result
+func (s *Server) search(id int64, op *ber.Packet) []*ber.Packet {
+	base := strings.ToLower(op.Children[0].Data.String())
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := []*ber.Packet{}
+	code := int64(goldap.LDAPResultSuccess)
+
+	for _, entry := range s.entries {
+		dn := strings.ToLower(entry.DN)
+		if dn != base && !strings.HasSuffix(dn, ","+base) || !entry.matches(filter) {
+			continue
+		}
+
+		if sizeLimit > 0 && int64(len(messages)) >= sizeLimit {
+			code = goldap.LDAPResultSizeLimitExceeded
+			break
+		}
+
+		messages = append(messages, entry.message(id))
+	}
+
+	return append(messages, result(id, goldap.ApplicationSearchResultDone, code))
+}
+
+// matches returns if the entry matches the filter
+func (e Entry) matches(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !e.matches(child) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			if e.matches(child) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return len(filter.Children) == 1 && !e.matches(filter.Children[0])
+	case goldap.FilterEqualityMatch:
+		value := filter.Children[1].Data.String()
+		for _, v := range e.values(filter.Children[0].Data.String()) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterPresent:
+		return len(e.values(filter.Data.String())) > 0
+	}
+
+	return false
+}
+
+// values returns the values of the attribute, whose name is case-insensitive
+func (e Entry) values(attribute string) []string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+// message returns the search result entry message of the entry
+func (e Entry) message(id int64) *ber.Packet {
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range e.Attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+
+	entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "DN"))
+	entry.AppendChild(attributes)
+
+	return envelope(id, entry)
+}
+
+// result returns a result message with the code
+func result(id int64, tag ber.Tag, code int64) *ber.Packet {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+
+	return envelope(id, res)
+}
+
+// envelope wraps the operation into a message with the message ID
+func envelope(id int64, op *ber.Packet) *ber.Packet {
+	message := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Message")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	message.AppendChild(op)
+	return message
+}
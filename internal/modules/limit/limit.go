@@ -0,0 +1,167 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package limit provides a limiter which slows down repeated failed attempts
+package limit
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultOptions are the options used if no other options are configured
+var DefaultOptions = Options{
+	Attempts: 5,
+	Delay:    time.Second,
+	Lockout:  15 * time.Minute,
+	Reset:    time.Hour,
+}
+
+// Options configure how a limiter delays failed attempts
+type Options struct {
+	// Attempts is the number of failed attempts which are not delayed
+	Attempts int
+
+	// Delay is the delay after the first delayed attempt. It doubles with every
+	// further failed attempt
+	Delay time.Duration
+
+	// Lockout is the maximum delay
+	Lockout time.Duration
+
+	// Reset is the time after which failed attempts are forgotten if there were no
+	// further failed attempts
+	Reset time.Duration
+}
+
+// Limiter counts the failed attempts of keys like usernames or IP addresses. After the
+// allowed attempts of a key failed, the key has to wait with exponential backoff until
+// it is locked out for the maximum delay. All methods are safe for concurrent use
+type Limiter struct {
+	sync.Mutex
+
+	options Options
+	entries map[string]*entry
+	purged  time.Time
+}
+
+// entry tracks the failed attempts of one key
+type entry struct {
+	failures int
+	last     time.Time
+	until    time.Time
+}
+
+// New returns a new limiter
+func New(options Options) *Limiter {
+	return &Limiter{
+		options: options,
+		entries: make(map[string]*entry),
+		purged:  time.Now(),
+	}
+}
+
+// Attempt counts an attempt of key as failed before it is checked, so that concurrent
+// attempts can't exceed the allowed attempts while the first ones are checked. It
+// returns true and how long key has to wait before the next attempt if the attempt is
+// allowed. Otherwise nothing is counted and it returns false and how long key has to
+// wait. Undo forgets the attempt once it succeeded
+func (l *Limiter) Attempt(key string) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	l.purge(now)
+
+	e := l.get(key, now)
+	if e != nil && now.Before(e.until) {
+		return e.until.Sub(now), false
+	}
+
+	if e == nil {
+		e = &entry{}
+		l.entries[key] = e
+	}
+
+	e.failures++
+	e.last = now
+
+	if e.failures < l.options.Attempts {
+		return 0, true
+	}
+
+	e.until = now.Add(l.delay(e.failures - l.options.Attempts))
+	return e.until.Sub(now), true
+}
+
+// Undo forgets one attempt of key counted by Attempt, because it succeeded
+func (l *Limiter) Undo(key string) {
+	l.Lock()
+	defer l.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return
+	}
+
+	e.failures--
+	switch {
+	case e.failures <= 0:
+		delete(l.entries, key)
+	case e.failures < l.options.Attempts:
+		e.until = time.Time{}
+	default:
+		e.until = e.last.Add(l.delay(e.failures - l.options.Attempts))
+	}
+}
+
+// Reset forgets all failed attempts of key
+func (l *Limiter) Reset(key string) {
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.entries, key)
+}
+
+// delay returns the delay after the n-th delayed attempt, starting at 0
+func (l *Limiter) delay(n int) time.Duration {
+	d := l.options.Delay
+	for i := 0; i < n && d < l.options.Lockout; i++ {
+		d *= 2
+	}
+
+	if d > l.options.Lockout {
+		return l.options.Lockout
+	}
+	return d
+}
+
+// get returns the entry of key, or nil if the key has no failed attempts which are
+// younger than the reset time
+func (l *Limiter) get(key string, now time.Time) *entry {
+	e, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if now.Sub(e.last) > l.options.Reset && !now.Before(e.until) {
+		delete(l.entries, key)
+		return nil
+	}
+
+	return e
+}
+
+// purge deletes all entries which can be forgotten. It runs at most once per reset
+// time
+func (l *Limiter) purge(now time.Time) {
+	if now.Sub(l.purged) < l.options.Reset {
+		return
+	}
+
+	for key := range l.entries {
+		l.get(key, now)
+	}
+	l.purged = now
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mail provides mailers which deliver plain text mails via SMTP, into a
+// maildir or nowhere
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"chapper.dev/server/internal/utils"
+)
+
+const (
+	// DriverSMTP delivers mails to an SMTP server
+	DriverSMTP = "smtp"
+
+	// DriverMaildir writes mails into a maildir, e.g. for development or if a local
+	// MTA picks them up
+	DriverMaildir = "maildir"
+
+	// DriverNoop discards all mails
+	DriverNoop = "noop"
+)
+
+var (
+	ErrInvalidDriver = errors.New("invalid mail driver, must be smtp, maildir or noop")
+	ErrMissingHost   = errors.New("the smtp mail driver requires a host")
+	ErrMissingPath   = errors.New("the maildir mail driver requires a path")
+	ErrMissingFrom   = errors.New("invalid or missing sender address")
+)
+
+// Mailer delivers mails
+type Mailer interface {
+	Send(m Message) error
+}
+
+// Message is a plain text mail
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Options configure a mailer. Host, Port, User, Password and TLS are used by the SMTP
+// driver, Path by the maildir driver
+type Options struct {
+	Driver   string
+	From     string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	TLS      bool
+	Path     string
+}
+
+// Validate returns an error if the options cannot be used to create a mailer
+func (o Options) Validate() error {
+	switch o.Driver {
+	case DriverNoop:
+		return nil
+	case DriverSMTP:
+		if o.Host == "" {
+			return ErrMissingHost
+		}
+	case DriverMaildir:
+		if o.Path == "" {
+			return ErrMissingPath
+		}
+	default:
+		return ErrInvalidDriver
+	}
+
+	if _, err := mail.ParseAddress(o.From); err != nil {
+		return ErrMissingFrom
+	}
+	return nil
+}
+
+// New returns the mailer selected by the driver in o
+func New(o Options) (Mailer, error) {
+	err := o.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Driver {
+	case DriverSMTP:
+		return newSMTP(o), nil
+	case DriverMaildir:
+		return newMaildir(o)
+	default:
+		return Noop{}, nil
+	}
+}
+
+// Noop is a mailer which discards all mails
+type Noop struct{}
+
+// Send discards m
+func (Noop) Send(m Message) error {
+	return nil
+}
+
+// bytes returns the message with headers in the internet message format
+func (m Message) bytes(from string) ([]byte, error) {
+	if strings.ContainsAny(m.To, "\r\n") {
+		return nil, fmt.Errorf("invalid recipient %q", m.To)
+	}
+
+	id, err := utils.RandomCryptoString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i != -1 {
+		domain = strings.Trim(from[i+1:], "> ")
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", from)
+	header("To", m.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", id, domain))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	_, err = w.Write([]byte(strings.ReplaceAll(m.Body, "\n", "\r\n")))
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	return buf.Bytes(), err
+}
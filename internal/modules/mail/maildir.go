@@ -0,0 +1,66 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mail
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"chapper.dev/server/internal/utils"
+)
+
+// Maildir is a mailer which writes mails into the new directory of a maildir
+type Maildir struct {
+	from string
+	path string
+}
+
+func newMaildir(o Options) (*Maildir, error) {
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(o.Path, dir), 0700)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Maildir{
+		from: o.From,
+		path: o.Path,
+	}, nil
+}
+
+// Send writes m into the maildir. The mail is written to tmp first and then moved to
+// new, so that readers never see partially written mails
+func (d *Maildir) Send(m Message) error {
+	msg, err := m.bytes(d.from)
+	if err != nil {
+		return err
+	}
+
+	unique, err := utils.RandomCryptoString(8)
+	if err != nil {
+		return err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	host = strings.NewReplacer("/", "\\057", ":", "\\072").Replace(host)
+
+	name := fmt.Sprintf("%d.%d_%s.%s", time.Now().Unix(), os.Getpid(), unique, host)
+	tmp := filepath.Join(d.path, "tmp", name)
+
+	err = ioutil.WriteFile(tmp, msg, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(d.path, "new", name))
+}
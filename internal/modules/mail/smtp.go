@@ -0,0 +1,102 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mail
+
+import (
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTP is a mailer which delivers mails to an SMTP server. Without TLS the connection
+// is upgraded with STARTTLS if the server supports it
+type SMTP struct {
+	options Options
+	addr    string
+}
+
+func newSMTP(o Options) *SMTP {
+	if o.Port == 0 {
+		o.Port = 587
+		if o.TLS {
+			o.Port = 465
+		}
+	}
+
+	return &SMTP{
+		options: o,
+		addr:    net.JoinHostPort(o.Host, strconv.Itoa(o.Port)),
+	}
+}
+
+// Send delivers m to the SMTP server
+func (s *SMTP) Send(m Message) error {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(s.options.From)
+	if err != nil {
+		return err
+	}
+
+	msg, err := m.bytes(s.options.From)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.options.User != "" {
+		auth = smtp.PlainAuth("", s.options.User, s.options.Password, s.options.Host)
+	}
+
+	if !s.options.TLS {
+		return smtp.SendMail(s.addr, auth, from.Address, []string{to.Address}, msg)
+	}
+
+	conn, err := tls.Dial("tcp", s.addr, &tls.Config{ServerName: s.options.Host})
+	if err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.options.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if auth != nil {
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Mail(from.Address); err != nil {
+		return err
+	}
+
+	if err = c.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oidc
+
+import "strings"
+
+// Claims are the claims of a verified ID token. Names of nested claims are separated by
+// dots, e.g. 'realm_access.roles'
+type Claims map[string]interface{}
+
+// Subject returns the identifier of the user at the identity provider
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// String returns the claim if it is a string
+func (c Claims) String(name string) string {
+	s, _ := c.lookup(name).(string)
+	return s
+}
+
+// Bool returns the claim if it is a boolean. Some identity providers send booleans as
+// strings
+func (c Claims) Bool(name string) bool {
+	switch v := c.lookup(name).(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// Strings returns the claim if it is a string or a list of strings. Other values in the
+// list are skipped
+func (c Claims) Strings(name string) []string {
+	switch v := c.lookup(name).(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// lookup returns the claim identified by name. Names which don't exist as is are split
+// at dots to look up nested claims
+func (c Claims) lookup(name string) interface{} {
+	if v, ok := c[name]; ok {
+		return v
+	}
+
+	var current interface{} = map[string]interface{}(c)
+	for _, part := range strings.Split(name, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	// Registers the EdDSA signing method
+	_ "chapper.dev/server/internal/modules/jwt"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// keysRefreshInterval is the minimum time between two requests of the keys of the
+// identity provider, which happen if an ID token carries an unknown key ID
+const keysRefreshInterval = time.Minute
+
+var (
+	// ErrUnknownKey indicates that the key which signed the ID token is unknown
+	ErrUnknownKey = errors.New("unknown oidc signing key")
+
+	// ErrInvalidIDToken indicates that the claims of the ID token are invalid
+	ErrInvalidIDToken = errors.New("invalid oidc id token")
+)
+
+// signingMethods are the accepted algorithms of ID tokens. Symmetric algorithms would
+// use the client secret as key and are not accepted
+var signingMethods = map[string]bool{
+	"RS256": true, "RS384": true, "RS512": true,
+	"PS256": true, "PS384": true, "PS512": true,
+	"ES256": true, "ES384": true, "ES512": true,
+	"EdDSA": true,
+}
+
+// keySet is the cached JSON Web Key Set of the identity provider
+type keySet struct {
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// jwk is a JSON Web Key (RFC 7517) of the identity provider
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// verify verifies the signature and the claims of the ID token and returns its claims
+func (p *Provider) verify(idToken string, d *discovery, nonce string) (Claims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if !signingMethods[token.Method.Alg()] {
+			return nil, fmt.Errorf("unexpected id token signing method=%v", token.Header["alg"])
+		}
+
+		id, _ := token.Header["kid"].(string)
+		return p.key(d, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := Claims(claims)
+	audience := c.Strings("aud")
+
+	switch {
+	case c.String("iss") != d.Issuer:
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidIDToken)
+	case !contains(audience, p.options.ClientID):
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidIDToken)
+	case len(audience) > 1 && c.String("azp") != p.options.ClientID:
+		return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidIDToken)
+	case c.String("nonce") != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	case c.Subject() == "":
+		return nil, fmt.Errorf("%w: subject missing", ErrInvalidIDToken)
+	case claims["exp"] == nil:
+		return nil, fmt.Errorf("%w: expiry missing", ErrInvalidIDToken)
+	}
+
+	return c, nil
+}
+
+// key returns the public key identified by id. Tokens without ID are accepted if the
+// identity provider has only one key. The keys are fetched again if the ID is unknown
+func (p *Provider) key(d *discovery, id string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys.lookup(id); ok {
+		return key, nil
+	}
+
+	if p.keys != nil && time.Since(p.keys.fetchedAt) < keysRefreshInterval {
+		return nil, ErrUnknownKey
+	}
+
+	keys, err := p.fetchKeys(d)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+
+	if key, ok := p.keys.lookup(id); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// fetchKeys requests the keys of the identity provider. Keys of unsupported types are
+// skipped
+func (p *Provider) fetchKeys(d *discovery) (*keySet, error) {
+	req, err := http.NewRequest(http.MethodGet, d.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	err = p.do(req, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &keySet{
+		keys:      make(map[string]interface{}),
+		fetchedAt: time.Now(),
+	}
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys.keys[k.KeyID] = key
+	}
+
+	return keys, nil
+}
+
+// lookup returns the key identified by id
+func (s *keySet) lookup(id string) (interface{}, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	if id == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, ok := s.keys[id]
+	return key, ok
+}
+
+// publicKey decodes the RSA, EC or Ed25519 public key
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid rsa exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Curve)
+		}
+
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve '%s'", k.Curve)
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if k.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Curve)
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type '%s'", k.KeyType)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty integer")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package oidc provides the relying party of the OpenID Connect authorization code flow
+// with PKCE. The endpoints and keys of the identity provider are discovered from the
+// issuer URL. Requests use the provided HTTP client, so that a local stand-in identity
+// provider can be used
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"chapper.dev/server/internal/utils"
+)
+
+const (
+	// DefaultTimeout is the timeout of requests to the identity provider if no HTTP
+	// client is provided
+	DefaultTimeout = 10 * time.Second
+
+	// flowSecretBytes is the number of random bytes of the nonce and the PKCE code
+	// verifier
+	flowSecretBytes = 32
+
+	// maxResponseSize limits the size of responses of the identity provider
+	maxResponseSize = 1 << 20
+)
+
+var (
+	// ErrMissingIssuer indicates that the issuer URL is empty
+	ErrMissingIssuer = errors.New("oidc issuer cannot be empty")
+
+	// ErrMissingClientID indicates that the client ID is empty
+	ErrMissingClientID = errors.New("oidc client id cannot be empty")
+
+	// ErrMissingRedirectURL indicates that the redirect URL is empty
+	ErrMissingRedirectURL = errors.New("oidc redirect url cannot be empty")
+
+	// ErrIssuerMismatch indicates that the discovery document belongs to another issuer
+	ErrIssuerMismatch = errors.New("oidc discovery document belongs to another issuer")
+
+	// ErrMissingIDToken indicates that the token response contains no ID token
+	ErrMissingIDToken = errors.New("oidc token response contains no id token")
+)
+
+// Options describes the client registration at the identity provider
+type Options struct {
+	// Issuer is the URL of the identity provider. The discovery document is served at
+	// Issuer/.well-known/openid-configuration
+	Issuer string
+
+	// ClientID and ClientSecret are issued by the identity provider. The secret is
+	// empty for public clients, which authenticate with PKCE only
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the page of the client which receives the code
+	RedirectURL string
+
+	// Scopes are requested in addition to 'openid'
+	Scopes []string
+}
+
+// Validate returns an error if the options are invalid
+func (o Options) Validate() error {
+	if o.Issuer == "" {
+		return ErrMissingIssuer
+	}
+
+	if o.ClientID == "" {
+		return ErrMissingClientID
+	}
+
+	if o.RedirectURL == "" {
+		return ErrMissingRedirectURL
+	}
+
+	_, err := url.Parse(o.Issuer)
+	return err
+}
+
+// Provider is the identity provider of the single sign-on. It is safe for concurrent
+// use
+type Provider struct {
+	options Options
+	client  *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      *keySet
+}
+
+// discovery is the part of the discovery document used by the relying party
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Flow is the state of one login which has to be kept until the code is exchanged
+type Flow struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// New returns a new provider. The discovery document is fetched with the first login,
+// so that the server starts while the identity provider is unreachable. A nil client
+// uses a client with DefaultTimeout
+func New(options Options, client *http.Client) (*Provider, error) {
+	err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	options.Issuer = strings.TrimSuffix(options.Issuer, "/")
+
+	return &Provider{
+		options: options,
+		client:  client,
+	}, nil
+}
+
+// NewFlow returns the nonce and PKCE code verifier of a new login
+func NewFlow() (Flow, error) {
+	nonce, err := utils.RandomCryptoString(flowSecretBytes)
+	if err != nil {
+		return Flow{}, err
+	}
+
+	verifier, err := utils.RandomCryptoString(flowSecretBytes)
+	if err != nil {
+		return Flow{}, err
+	}
+
+	return Flow{
+		Nonce:    nonce,
+		Verifier: verifier,
+	}, nil
+}
+
+// AuthURL returns the URL of the identity provider the user has to visit to login.
+// The state is passed back to the redirect URL with the code
+func (p *Provider) AuthURL(state string, flow Flow) (string, error) {
+	d, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.options.ClientID)
+	query.Set("redirect_uri", p.options.RedirectURL)
+	query.Set("scope", strings.Join(p.scopes(), " "))
+	query.Set("state", state)
+	query.Set("nonce", flow.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return d.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange exchanges the code for tokens and returns the verified claims of the ID
+// token
+func (p *Provider) Exchange(code string, flow Flow) (Claims, error) {
+	d, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.options.RedirectURL)
+	form.Set("code_verifier", flow.Verifier)
+
+	// Public clients identify with the client ID only, confidential clients
+	// authenticate with the secret
+	if p.options.ClientSecret == "" {
+		form.Set("client_id", p.options.ClientID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.options.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.options.ClientID), url.QueryEscape(p.options.ClientSecret))
+	}
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	err = p.do(req, &tokens)
+	if err != nil {
+		if tokens.Error != "" {
+			return nil, fmt.Errorf("oidc token request failed: %s %s", tokens.Error, tokens.ErrorDescription)
+		}
+		return nil, err
+	}
+
+	if tokens.IDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	return p.verify(tokens.IDToken, d, flow.Nonce)
+}
+
+// scopes returns the requested scopes, which always include 'openid'
+func (p *Provider) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range p.options.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// getDiscovery returns the discovery document. It is fetched once, failed requests are
+// retried with the next login
+func (p *Provider) getDiscovery() (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.options.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var d discovery
+	err = p.do(req, &d)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != p.options.Issuer {
+		return nil, ErrIssuerMismatch
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery document of '%s' is incomplete", p.options.Issuer)
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// do sends the request and decodes the JSON response into v. Responses with an error
+// status are decoded too, because they carry the error of the identity provider
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	decodeErr := json.Unmarshal(body, v)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc request to '%s' failed with status %d", req.URL.Host+req.URL.Path, res.StatusCode)
+	}
+
+	return decodeErr
+}
@@ -0,0 +1,374 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	testClientID    = "chapper"
+	testRedirectURL = "https://chapper.test/sso"
+	testKeyID       = "key-1"
+)
+
+// testIdP is a stand-in identity provider. It remembers the nonce and PKCE challenge
+// of every authorization request and issues ID tokens for the code, like a real
+// identity provider would
+type testIdP struct {
+	*httptest.Server
+
+	key *rsa.PrivateKey
+
+	mu          sync.Mutex
+	flows       map[string]url.Values
+	keyRequests int
+
+	// claims can change the claims of the next ID tokens
+	claims func(jwt.MapClaims)
+
+	// sign can replace the key and key ID which sign the next ID tokens
+	sign func(*jwt.Token) (string, error)
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &testIdP{
+		key:   key,
+		flows: make(map[string]url.Values),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/keys", idp.keys)
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func (idp *testIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 idp.URL,
+		"authorization_endpoint": idp.URL + "/authorize",
+		"token_endpoint":         idp.URL + "/token",
+		"jwks_uri":               idp.URL + "/keys",
+	})
+}
+
+// authorize logs the user in at once and redirects back with a new code
+func (idp *testIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	code := query.Get("state") + "-code"
+
+	idp.mu.Lock()
+	idp.flows[code] = query
+	idp.mu.Unlock()
+
+	http.Redirect(w, r, query.Get("redirect_uri")+"?code="+code+"&state="+query.Get("state"), http.StatusFound)
+}
+
+func (idp *testIdP) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	idp.mu.Lock()
+	flow, ok := idp.flows[r.PostForm.Get("code")]
+	delete(idp.flows, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || flow.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":                idp.URL,
+		"sub":                "subject-1",
+		"aud":                testClientID,
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              flow.Get("nonce"),
+		"preferred_username": "alice",
+		"realm_access":       map[string]interface{}{"roles": []string{"admins"}},
+	}
+	if idp.claims != nil {
+		idp.claims(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+
+	sign := idp.sign
+	if sign == nil {
+		sign = func(t *jwt.Token) (string, error) {
+			return t.SignedString(idp.key)
+		}
+	}
+
+	idToken, err := sign(token)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+}
+
+func (idp *testIdP) keys(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	idp.keyRequests++
+	idp.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+// login runs the authorization code flow with the provider and returns the claims of
+// the ID token
+func (idp *testIdP) login(t *testing.T, p *Provider) (Claims, error) {
+	flow, err := NewFlow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := p.AuthURL("state-1", flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := idp.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	res, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if location.Query().Get("state") != "state-1" {
+		t.Fatalf("state = %q, want state-1", location.Query().Get("state"))
+	}
+
+	return p.Exchange(location.Query().Get("code"), flow)
+}
+
+func newTestProvider(t *testing.T, idp *testIdP) *Provider {
+	p, err := New(Options{
+		Issuer:      idp.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	}, idp.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestExchange(t *testing.T) {
+	idp := newTestIdP(t)
+	p := newTestProvider(t, idp)
+
+	claims, err := idp.login(t, p)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	if claims.Subject() != "subject-1" {
+		t.Errorf("Subject() = %q, want subject-1", claims.Subject())
+	}
+
+	if claims.String("preferred_username") != "alice" {
+		t.Errorf("String(preferred_username) = %q, want alice", claims.String("preferred_username"))
+	}
+
+	roles := claims.Strings("realm_access.roles")
+	if len(roles) != 1 || roles[0] != "admins" {
+		t.Errorf("Strings(realm_access.roles) = %v, want [admins]", roles)
+	}
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	idp := newTestIdP(t)
+	p := newTestProvider(t, idp)
+
+	flow, _ := NewFlow()
+	_, err := p.AuthURL("state-1", flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := NewFlow()
+	_, err = p.Exchange("state-1-code", other)
+	if err == nil {
+		t.Fatal("Exchange() with the verifier of another flow succeeded")
+	}
+}
+
+func TestExchangeInvalidClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(jwt.MapClaims)
+	}{
+		{"nonce", func(c jwt.MapClaims) { c["nonce"] = "other" }},
+		{"missing nonce", func(c jwt.MapClaims) { delete(c, "nonce") }},
+		{"audience", func(c jwt.MapClaims) { c["aud"] = "other" }},
+		{"authorized party", func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "other"} }},
+		{"issuer", func(c jwt.MapClaims) { c["iss"] = "https://other.test" }},
+		{"subject", func(c jwt.MapClaims) { delete(c, "sub") }},
+		{"missing expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t)
+			idp.claims = tt.claims
+			p := newTestProvider(t, idp)
+
+			_, err := idp.login(t, p)
+			if err == nil {
+				t.Fatal("Exchange() succeeded")
+			}
+		})
+	}
+}
+
+func TestExchangeAudienceList(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.claims = func(c jwt.MapClaims) {
+		c["aud"] = []string{testClientID, "other"}
+		c["azp"] = testClientID
+	}
+	p := newTestProvider(t, idp)
+
+	_, err := idp.login(t, p)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+}
+
+func TestExchangeUnknownKey(t *testing.T) {
+	idp := newTestIdP(t)
+	p := newTestProvider(t, idp)
+
+	// Fetch the keys with a valid login first
+	_, err := idp.login(t, p)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp.sign = func(token *jwt.Token) (string, error) {
+		token.Header["kid"] = "key-2"
+		return token.SignedString(other)
+	}
+
+	_, err = idp.login(t, p)
+	if !isUnknownKey(err) {
+		t.Fatalf("Exchange() error = %v, want %v", err, ErrUnknownKey)
+	}
+
+	if idp.keyRequests != 1 {
+		t.Errorf("keys were requested %d times within the refresh interval, want 1", idp.keyRequests)
+	}
+
+	// After the refresh interval an unknown key ID fetches the keys again, but only once
+	p.keys.fetchedAt = time.Now().Add(-keysRefreshInterval)
+
+	for i := 0; i < 2; i++ {
+		_, err = idp.login(t, p)
+		if !isUnknownKey(err) {
+			t.Fatalf("Exchange() error = %v, want %v", err, ErrUnknownKey)
+		}
+	}
+
+	if idp.keyRequests != 2 {
+		t.Errorf("keys were requested %d times, want 2", idp.keyRequests)
+	}
+}
+
+func TestExchangeForgedKeyID(t *testing.T) {
+	idp := newTestIdP(t)
+	p := newTestProvider(t, idp)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token signed by another key under the known key ID
+	idp.sign = func(token *jwt.Token) (string, error) {
+		return token.SignedString(other)
+	}
+
+	_, err = idp.login(t, p)
+	if err == nil {
+		t.Fatal("Exchange() succeeded")
+	}
+}
+
+func TestExchangeSymmetricAlgorithm(t *testing.T) {
+	idp := newTestIdP(t)
+	p := newTestProvider(t, idp)
+
+	idp.sign = func(token *jwt.Token) (string, error) {
+		token.Method = jwt.SigningMethodHS256
+		token.Header["alg"] = "HS256"
+		return token.SignedString([]byte("secret"))
+	}
+
+	_, err := idp.login(t, p)
+	if err == nil {
+		t.Fatal("Exchange() succeeded")
+	}
+}
+
+func isUnknownKey(err error) bool {
+	var verr *jwt.ValidationError
+	if errors.As(err, &verr) {
+		return verr.Inner == ErrUnknownKey
+	}
+	return false
+}
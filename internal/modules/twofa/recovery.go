@@ -0,0 +1,63 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package twofa
+
+import (
+	"encoding/base32"
+	"strings"
+
+	"chapper.dev/server/internal/utils"
+)
+
+const (
+	// recoveryCodeBytes is the number of random bytes of a recovery code. 10 bytes
+	// encode to 16 characters
+	recoveryCodeBytes = 10
+
+	// recoveryCodeGroup is the number of characters between two dashes
+	recoveryCodeGroup = 4
+)
+
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCode generates a new recovery code like 'abcd-efgh-ijkl-mnop'. The
+// first group of the code identifies the code, see RecoveryCodeID
+func GenerateRecoveryCode() (string, error) {
+	b, err := utils.RandomByteSlice(recoveryCodeBytes)
+	if err != nil {
+		return "", err
+	}
+
+	code := recoveryEncoding.EncodeToString(b)
+
+	groups := make([]string, 0, len(code)/recoveryCodeGroup)
+	for i := 0; i < len(code); i += recoveryCodeGroup {
+		groups = append(groups, code[i:i+recoveryCodeGroup])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// NormalizeRecoveryCode removes dashes and whitespace from the code and converts it to
+// lower case
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// RecoveryCodeID returns the ID of the recovery code, which is its first group. It is
+// used to look up the code, so that the provided code only has to be compared to one
+// hash. It returns an empty string if the code is too short
+func RecoveryCodeID(code string) string {
+	code = NormalizeRecoveryCode(code)
+	if len(code) != recoveryEncoding.EncodedLen(recoveryCodeBytes) {
+		return ""
+	}
+	return code[:recoveryCodeGroup]
+}
@@ -6,25 +6,63 @@
 package twofa
 
 import (
+	"errors"
 	"image"
+	"strings"
 	"time"
 
 	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
 	"github.com/pquerna/otp/totp"
 )
 
+var (
+	// ErrInvalidAlgorithm indicates the TOTP algorithm is not supported
+	ErrInvalidAlgorithm = errors.New("TOTP algorithm must be SHA1, SHA256 or SHA512")
+
+	// ErrInvalidDigits indicates the number of TOTP digits is not supported
+	ErrInvalidDigits = errors.New("TOTP digits must be 6 or 8")
+
+	// ErrInvalidPeriod indicates the TOTP period is zero
+	ErrInvalidPeriod = errors.New("TOTP period must be greater than 0")
+)
+
+// Options are the parameters of a TOTP. Algorithm, Digits and Period are part of the
+// enrollment and have to match the authenticator app. Skew is the number of periods
+// before and after the current one in which codes are still accepted
+type Options struct {
+	Algorithm string
+	Digits    int
+	Period    uint
+	Skew      uint
+}
+
+// DefaultOptions are compatible with most authenticator apps.
+// NOTE(Techassi): Use SHA1 because Google Authenticator does BS when using SHA512
+var DefaultOptions = Options{
+	Algorithm: "SHA1",
+	Digits:    6,
+	Period:    30,
+	Skew:      1,
+}
+
 type TOTPKey struct {
 	key *otp.Key
 }
 
-// GenerateTOTP generates a new TOTP
-func GenerateTOTP(issuer, account string) (TOTPKey, error) {
+// GenerateTOTP generates a new TOTP with the provided options
+func GenerateTOTP(issuer, account string, o Options) (TOTPKey, error) {
+	algorithm, digits, err := o.parse()
+	if err != nil {
+		return TOTPKey{}, err
+	}
+
 	options := totp.GenerateOpts{
 		Issuer:      issuer,
 		AccountName: account,
-		// NOTE(Techassi): Use SHA1 because Google Authenticator does BS when using SHA512
-		// Maybe make this algo configurable by the user
-		Algorithm: otp.AlgorithmSHA1,
+		Period:      o.Period,
+		Digits:      digits,
+		Algorithm:   algorithm,
 	}
 
 	key, err := totp.Generate(options)
@@ -33,19 +71,69 @@ func GenerateTOTP(issuer, account string) (TOTPKey, error) {
 	}, err
 }
 
-// ValidateTOTP validates a 6 digit TOTP code
-func ValidateTOTP(code, secret string) bool {
-	ok, _ := totp.ValidateCustom(
-		code,
-		secret,
-		time.Now().UTC(),
-		totp.ValidateOpts{
-			Period:    30,
-			Skew:      1,
-			Digits:    otp.DigitsSix,
-			Algorithm: otp.AlgorithmSHA1,
+// ValidateTOTP validates a TOTP code with the provided options at the current time. It
+// returns the time step the code belongs to, which can be used to reject the reuse of
+// a code
+func ValidateTOTP(code, secret string, o Options) (uint64, bool) {
+	algorithm, digits, err := o.parse()
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.TrimSpace(code)
+	current := uint64(time.Now().UTC().Unix()) / uint64(o.Period)
+
+	for step := current - uint64(o.Skew); step <= current+uint64(o.Skew); step++ {
+		ok, err := hotp.ValidateCustom(code, step, secret, hotp.ValidateOpts{
+			Digits:    digits,
+			Algorithm: algorithm,
 		})
-	return ok
+		if err != nil {
+			return 0, false
+		}
+
+		if ok {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// Validate returns an error if the options are not supported
+func (o Options) Validate() error {
+	_, _, err := o.parse()
+	return err
+}
+
+func (o Options) parse() (otp.Algorithm, otp.Digits, error) {
+	var algorithm otp.Algorithm
+	switch strings.ToUpper(o.Algorithm) {
+	case "SHA1":
+		algorithm = otp.AlgorithmSHA1
+	case "SHA256":
+		algorithm = otp.AlgorithmSHA256
+	case "SHA512":
+		algorithm = otp.AlgorithmSHA512
+	default:
+		return 0, 0, ErrInvalidAlgorithm
+	}
+
+	var digits otp.Digits
+	switch o.Digits {
+	case 6:
+		digits = otp.DigitsSix
+	case 8:
+		digits = otp.DigitsEight
+	default:
+		return 0, 0, ErrInvalidDigits
+	}
+
+	if o.Period == 0 {
+		return 0, 0, ErrInvalidPeriod
+	}
+
+	return algorithm, digits, nil
 }
 
 // Secret returns the TOTP secret
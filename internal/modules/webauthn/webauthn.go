@@ -0,0 +1,247 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package webauthn provides the registration and assertion ceremonies of WebAuthn
+// (FIDO2) authenticators like passkeys or security keys. Responses of authenticators
+// are read from an io.Reader, so that the ceremonies can be driven by a software
+// authenticator
+package webauthn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+
+	"github.com/duo-labs/webauthn/protocol"
+	lib "github.com/duo-labs/webauthn/webauthn"
+)
+
+// decoyKeyBytes is the number of random bytes of the key which derives the credential
+// IDs of decoy logins
+const decoyKeyBytes = 32
+
+// WebAuthn wraps the configuration of the relying party, which is this instance
+type WebAuthn struct {
+	w        *lib.WebAuthn
+	decoyKey []byte
+}
+
+// User is the owner of credentials. The ID is the user handle, which must not change
+// when the user gets renamed
+type User struct {
+	ID          []byte
+	Name        string
+	Credentials []Credential
+}
+
+// Credential is a public key credential registered by an authenticator
+type Credential struct {
+	ID              []byte
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+
+	// CloneWarning is set by FinishLogin if the signature counter didn't increase,
+	// which indicates the authenticator was cloned
+	CloneWarning bool
+}
+
+// New returns a new relying party. The rpID is the domain of the instance and origin
+// the full URL the client is served from
+func New(displayName, rpID, origin string) (*WebAuthn, error) {
+	w, err := lib.New(&lib.Config{
+		RPDisplayName: displayName,
+		RPID:          rpID,
+		RPOrigin:      origin,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	decoyKey := make([]byte, decoyKeyBytes)
+	_, err = rand.Read(decoyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthn{
+		w:        w,
+		decoyKey: decoyKey,
+	}, nil
+}
+
+// BeginRegistration starts the registration of a new credential of user. It returns
+// the options which are passed to navigator.credentials.create() by the client and the
+// session, which has to be passed to FinishRegistration
+func (w *WebAuthn) BeginRegistration(user User) (interface{}, string, error) {
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.Credentials))
+	for _, credential := range user.Credentials {
+		exclusions = append(exclusions, protocol.CredentialDescriptor{
+			Type:         protocol.PublicKeyCredentialType,
+			CredentialID: credential.ID,
+		})
+	}
+
+	options, session, err := w.w.BeginRegistration(user.adapter(), lib.WithExclusions(exclusions))
+	if err != nil {
+		return nil, "", err
+	}
+
+	s, err := encodeSession(session)
+	return options, s, err
+}
+
+// FinishRegistration verifies the response of the authenticator read from r and
+// returns the new credential
+func (w *WebAuthn) FinishRegistration(user User, session string, r io.Reader) (*Credential, error) {
+	s, err := decodeSession(session)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := protocol.ParseCredentialCreationResponseBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := w.w.CreateCredential(user.adapter(), s, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromLib(*credential), nil
+}
+
+// BeginLogin starts the assertion of one of the credentials of user. If
+// userVerification is true the authenticator has to verify the user, e.g. with a PIN
+// or biometrics. It returns the options which are passed to navigator.credentials.get()
+// by the client and the session, which has to be passed to FinishLogin
+func (w *WebAuthn) BeginLogin(user User, userVerification bool) (interface{}, string, error) {
+	verification := protocol.VerificationDiscouraged
+	if userVerification {
+		verification = protocol.VerificationRequired
+	}
+
+	options, session, err := w.w.BeginLogin(user.adapter(), lib.WithUserVerification(verification))
+	if err != nil {
+		return nil, "", err
+	}
+
+	s, err := encodeSession(session)
+	return options, s, err
+}
+
+// BeginDecoyLogin returns options and a session like BeginLogin for a user who doesn't
+// exist or has no credentials, so that logins don't reveal which users have
+// credentials. The allowed credential is derived from name and stays the same until the
+// restart. The session never validates
+func (w *WebAuthn) BeginDecoyLogin(name string, userVerification bool) (interface{}, string, error) {
+	mac := hmac.New(sha256.New, w.decoyKey)
+	mac.Write([]byte(name))
+
+	// A random user handle, which no user has, fails FinishLogin
+	id := make([]byte, decoyKeyBytes)
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return w.BeginLogin(User{
+		ID:          id,
+		Name:        name,
+		Credentials: []Credential{{ID: mac.Sum(nil)}},
+	}, userVerification)
+}
+
+// FinishLogin verifies the response of the authenticator read from r and returns the
+// used credential with the updated signature counter
+func (w *WebAuthn) FinishLogin(user User, session string, r io.Reader) (*Credential, error) {
+	s, err := decodeSession(session)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := protocol.ParseCredentialRequestResponseBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := w.w.ValidateLogin(user.adapter(), s, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromLib(*credential), nil
+}
+
+func encodeSession(session *lib.SessionData) (string, error) {
+	b, err := json.Marshal(session)
+	return string(b), err
+}
+
+func decodeSession(session string) (lib.SessionData, error) {
+	var s lib.SessionData
+	err := json.Unmarshal([]byte(session), &s)
+	return s, err
+}
+
+func fromLib(c lib.Credential) *Credential {
+	return &Credential{
+		ID:              c.ID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		AAGUID:          c.Authenticator.AAGUID,
+		SignCount:       c.Authenticator.SignCount,
+		CloneWarning:    c.Authenticator.CloneWarning,
+	}
+}
+
+// adapter implements the user interface of the WebAuthn library
+type adapter struct {
+	user        User
+	credentials []lib.Credential
+}
+
+func (u User) adapter() *adapter {
+	credentials := make([]lib.Credential, 0, len(u.Credentials))
+	for _, c := range u.Credentials {
+		credentials = append(credentials, lib.Credential{
+			ID:              c.ID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Authenticator: lib.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		})
+	}
+
+	return &adapter{
+		user:        u,
+		credentials: credentials,
+	}
+}
+
+func (a *adapter) WebAuthnID() []byte {
+	return a.user.ID
+}
+
+func (a *adapter) WebAuthnName() string {
+	return a.user.Name
+}
+
+func (a *adapter) WebAuthnDisplayName() string {
+	return a.user.Name
+}
+
+func (a *adapter) WebAuthnIcon() string {
+	return ""
+}
+
+func (a *adapter) WebAuthnCredentials() []lib.Credential {
+	return a.credentials
+}
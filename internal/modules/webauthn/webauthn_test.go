@@ -0,0 +1,321 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"chapper.dev/server/internal/modules/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "chapper.test"
+	testOrigin = "https://chapper.test"
+)
+
+func newTestWebAuthn(t *testing.T) *WebAuthn {
+	w, err := New("Chapper", testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func marshal(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// register registers a new credential of the authenticator for user
+func register(t *testing.T, w *WebAuthn, a *webauthntest.Authenticator, user *User) {
+	options, session, err := w.BeginRegistration(*user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+
+	response, err := a.Register(marshal(t, options))
+	if err != nil {
+		t.Fatalf("authenticator failed to register: %v", err)
+	}
+
+	credential, err := w.FinishRegistration(*user, session, bytes.NewReader(response))
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+
+	if credential.AttestationType != "none" {
+		t.Errorf("AttestationType = %q, want none", credential.AttestationType)
+	}
+
+	user.Credentials = append(user.Credentials, *credential)
+}
+
+// login asserts one of the credentials of user with the authenticator
+func login(t *testing.T, w *WebAuthn, a *webauthntest.Authenticator, user User, userVerification bool) (*Credential, error) {
+	options, session, err := w.BeginLogin(user, userVerification)
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+
+	response, err := a.Login(marshal(t, options))
+	if err != nil {
+		t.Fatalf("authenticator failed to login: %v", err)
+	}
+
+	return w.FinishLogin(user, session, bytes.NewReader(response))
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	w := newTestWebAuthn(t)
+	a := webauthntest.New(testOrigin)
+	user := User{ID: []byte("user-handle"), Name: "alice"}
+
+	register(t, w, a, &user)
+
+	for i := uint32(1); i <= 2; i++ {
+		credential, err := login(t, w, a, user, true)
+		if err != nil {
+			t.Fatalf("FinishLogin() error = %v", err)
+		}
+
+		if !bytes.Equal(credential.ID, user.Credentials[0].ID) {
+			t.Error("FinishLogin() returned another credential")
+		}
+
+		if credential.SignCount != i || credential.CloneWarning {
+			t.Errorf("SignCount = %d, CloneWarning = %v, want %d without warning", credential.SignCount, credential.CloneWarning, i)
+		}
+
+		user.Credentials[0].SignCount = credential.SignCount
+	}
+}
+
+func TestRegistrationExcludesCredentials(t *testing.T) {
+	w := newTestWebAuthn(t)
+	a := webauthntest.New(testOrigin)
+	user := User{ID: []byte("user-handle"), Name: "alice"}
+
+	register(t, w, a, &user)
+
+	options, _, err := w.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+
+	_, err = a.Register(marshal(t, options))
+	if err != webauthntest.ErrExcluded {
+		t.Errorf("second registration with the same authenticator error = %v, want %v", err, webauthntest.ErrExcluded)
+	}
+}
+
+func TestRegistrationWrongOrigin(t *testing.T) {
+	w := newTestWebAuthn(t)
+	a := webauthntest.New("https://phishing.test")
+	user := User{ID: []byte("user-handle"), Name: "alice"}
+
+	options, session, err := w.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+
+	response, err := a.Register(marshal(t, options))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.FinishRegistration(user, session, bytes.NewReader(response))
+	if err == nil {
+		t.Error("FinishRegistration() from another origin succeeded")
+	}
+}
+
+func TestLoginWrongSession(t *testing.T) {
+	w := newTestWebAuthn(t)
+	a := webauthntest.New(testOrigin)
+	user := User{ID: []byte("user-handle"), Name: "alice"}
+
+	register(t, w, a, &user)
+
+	options, _, err := w.BeginLogin(user, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, other, err := w.BeginLogin(user, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := a.Login(marshal(t, options))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.FinishLogin(user, other, bytes.NewReader(response))
+	if err == nil {
+		t.Error("FinishLogin() with the session of another challenge succeeded")
+	}
+}
+
+func TestLoginUserVerification(t *testing.T) {
+	w := newTestWebAuthn(t)
+	a := webauthntest.New(testOrigin)
+	user := User{ID: []byte("user-handle"), Name: "alice"}
+
+	register(t, w, a, &user)
+	a.UserVerified = false
+
+	_, err := login(t, w, a, user, true)
+	if err == nil {
+		t.Error("FinishLogin() without user verification succeeded")
+	}
+
+	_, err = login(t, w, a, user, false)
+	if err != nil {
+		t.Errorf("FinishLogin() as second factor error = %v", err)
+	}
+}
+
+func TestLoginClonedAuthenticator(t *testing.T) {
+	w := newTestWebAuthn(t)
+	a := webauthntest.New(testOrigin)
+	user := User{ID: []byte("user-handle"), Name: "alice"}
+
+	register(t, w, a, &user)
+	clone := a.Clone()
+
+	credential, err := login(t, w, a, user, true)
+	if err != nil {
+		t.Fatalf("FinishLogin() error = %v", err)
+	}
+	user.Credentials[0].SignCount = credential.SignCount
+
+	credential, err = login(t, w, clone, user, true)
+	if err != nil {
+		t.Fatalf("FinishLogin() error = %v", err)
+	}
+
+	if !credential.CloneWarning {
+		t.Error("FinishLogin() of the clone with the same signature counter gave no CloneWarning")
+	}
+}
+
+func TestBeginDecoyLogin(t *testing.T) {
+	w := newTestWebAuthn(t)
+	a := webauthntest.New(testOrigin)
+	user := User{ID: []byte("user-handle"), Name: "alice"}
+
+	register(t, w, a, &user)
+
+	real, _, err := w.BeginLogin(user, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoy, session, err := w.BeginDecoyLogin("bob", true)
+	if err != nil {
+		t.Fatalf("BeginDecoyLogin() error = %v", err)
+	}
+
+	realOptions, decoyOptions := decode(t, marshal(t, real)), decode(t, marshal(t, decoy))
+	if !sameShape(realOptions, decoyOptions) {
+		t.Errorf("decoy options %s look different from %s", marshal(t, decoy), marshal(t, real))
+	}
+
+	// The decoy credential stays the same, like a real one would
+	again, _, err := w.BeginDecoyLogin("bob", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if allowed(t, decoy) != allowed(t, again) {
+		t.Error("decoy credential changed between two logins")
+	}
+
+	other, _, err := w.BeginDecoyLogin("carol", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if allowed(t, decoy) == allowed(t, other) {
+		t.Error("decoy credentials of two users are the same")
+	}
+
+	// A response for the options of the real user can't finish the decoy
+	response, err := a.Login(marshal(t, real))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.FinishLogin(user, session, bytes.NewReader(response))
+	if err == nil {
+		t.Error("FinishLogin() of a decoy session succeeded")
+	}
+}
+
+func decode(t *testing.T, b []byte) map[string]interface{} {
+	var v map[string]interface{}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// sameShape returns if both values have the same keys and types, and lists the same
+// lengths
+func sameShape(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			if !sameShape(value, b[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !sameShape(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case string:
+		b, ok := b.(string)
+		return ok && len(a) == len(b)
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b)
+}
+
+// allowed returns the ID of the first allowed credential of the options
+func allowed(t *testing.T, options interface{}) string {
+	var o struct {
+		PublicKey struct {
+			AllowCredentials []struct {
+				ID string `json:"id"`
+			} `json:"allowCredentials"`
+		} `json:"publicKey"`
+	}
+
+	err := json.Unmarshal(marshal(t, options), &o)
+	if err != nil || len(o.PublicKey.AllowCredentials) == 0 {
+		t.Fatalf("options have no allowed credential: %v", err)
+	}
+	return o.PublicKey.AllowCredentials[0].ID
+}
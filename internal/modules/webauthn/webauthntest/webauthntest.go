@@ -0,0 +1,314 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package webauthntest provides a software authenticator for tests. It answers the
+// options of navigator.credentials.create() and navigator.credentials.get() like a
+// browser with a platform authenticator would, with 'none' attestation and ES256 keys
+package webauthntest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+
+	// coseAlgES256 is the COSE algorithm identifier of ECDSA with P-256 and SHA-256
+	coseAlgES256 = -7
+)
+
+var (
+	// ErrExcluded indicates that the authenticator already has one of the excluded
+	// credentials
+	ErrExcluded = errors.New("authenticator already has an excluded credential")
+
+	// ErrNoCredential indicates that the authenticator has none of the allowed
+	// credentials
+	ErrNoCredential = errors.New("authenticator has none of the allowed credentials")
+)
+
+// Authenticator is a software authenticator of the origin. Its credentials are kept in
+// memory
+type Authenticator struct {
+	// Origin is the origin the browser reports in the client data
+	Origin string
+
+	// UserVerified sets if the authenticator reports that it verified the user, e.g.
+	// with a PIN
+	UserVerified bool
+
+	credentials []*credential
+}
+
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+type descriptor struct {
+	ID string `json:"id"`
+}
+
+type creationOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+		RP        struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		ExcludeCredentials []descriptor `json:"excludeCredentials"`
+	} `json:"publicKey"`
+}
+
+type requestOptions struct {
+	PublicKey struct {
+		Challenge        string       `json:"challenge"`
+		RPID             string       `json:"rpId"`
+		AllowCredentials []descriptor `json:"allowCredentials"`
+	} `json:"publicKey"`
+}
+
+// New returns an authenticator without credentials which verifies users
+func New(origin string) *Authenticator {
+	return &Authenticator{
+		Origin:       origin,
+		UserVerified: true,
+	}
+}
+
+// Clone returns a copy of the authenticator with the same keys and signature counters,
+// like an attacker who extracted the keys would have
+func (a *Authenticator) Clone() *Authenticator {
+	clone := *a
+	clone.credentials = make([]*credential, 0, len(a.credentials))
+	for _, c := range a.credentials {
+		copied := *c
+		clone.credentials = append(clone.credentials, &copied)
+	}
+	return &clone
+}
+
+// Register creates a new credential for the creation options encoded as JSON and
+// returns the response of the browser encoded as JSON
+func (a *Authenticator) Register(options []byte) ([]byte, error) {
+	var o creationOptions
+	err := json.Unmarshal(options, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, excluded := range o.PublicKey.ExcludeCredentials {
+		if a.find(o.PublicKey.RP.ID, excluded.ID) != nil {
+			return nil, ErrExcluded
+		}
+	}
+
+	userHandle, err := decode(o.PublicKey.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &credential{
+		id:         make([]byte, 32),
+		rpID:       o.PublicKey.RP.ID,
+		userHandle: userHandle,
+		key:        key,
+	}
+
+	_, err = rand.Read(c.id)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2, // EC2 key type
+		3:  coseAlgES256,
+		-1: 1, // P-256
+		-2: pad(key.X),
+		-3: pad(key.Y),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Attested credential data: AAGUID, length of the ID, ID and public key
+	attested := make([]byte, 16, 16+2+len(c.id)+len(publicKey))
+	attested = append(attested, byte(len(c.id)>>8), byte(len(c.id)))
+	attested = append(attested, c.id...)
+	attested = append(attested, publicKey...)
+
+	attestation, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": append(a.authData(c, flagAttested), attested...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clientData, err := a.clientData("webauthn.create", o.PublicKey.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.credentials = append(a.credentials, c)
+
+	return json.Marshal(map[string]interface{}{
+		"id":    encode(c.id),
+		"rawId": encode(c.id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(clientData),
+			"attestationObject": encode(attestation),
+		},
+	})
+}
+
+// Login signs the challenge of the request options encoded as JSON with the first
+// allowed credential and returns the response of the browser encoded as JSON. The
+// signature counter of the credential is increased
+func (a *Authenticator) Login(options []byte) ([]byte, error) {
+	var o requestOptions
+	err := json.Unmarshal(options, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	var c *credential
+	for _, allowed := range o.PublicKey.AllowCredentials {
+		if c = a.find(o.PublicKey.RPID, allowed.ID); c != nil {
+			break
+		}
+	}
+
+	if c == nil {
+		return nil, ErrNoCredential
+	}
+
+	c.signCount++
+	authData := a.authData(c, 0)
+
+	clientData, err := a.clientData("webauthn.get", o.PublicKey.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), hash[:]...))
+
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"id":    encode(c.id),
+		"rawId": encode(c.id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(c.userHandle),
+		},
+	})
+}
+
+// find returns the credential of the relying party with the encoded ID
+func (a *Authenticator) find(rpID, id string) *credential {
+	b, err := decode(id)
+	if err != nil {
+		return nil
+	}
+
+	for _, c := range a.credentials {
+		if c.rpID == rpID && bytes.Equal(c.id, b) {
+			return c
+		}
+	}
+	return nil
+}
+
+// authData returns the authenticator data of the credential without attested
+// credential data
+func (a *Authenticator) authData(c *credential, flags byte) []byte {
+	flags |= flagUserPresent
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+
+	rpIDHash := sha256.Sum256([]byte(c.rpID))
+
+	data := make([]byte, 0, 37)
+	data = append(data, rpIDHash[:]...)
+	data = append(data, flags)
+
+	counter := make([]byte, 4)
+	binary.BigEndian.PutUint32(counter, c.signCount)
+	return append(data, counter...)
+}
+
+// clientData returns the client data of the ceremony. Browsers decode the challenge of
+// the options and report it encoded as base64url
+func (a *Authenticator) clientData(kind, challenge string) ([]byte, error) {
+	b, err := decode(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]string{
+		"type":      kind,
+		"challenge": encode(b),
+		"origin":    a.Origin,
+	})
+}
+
+// pad returns the coordinate as 32 bytes
+func pad(n *big.Int) []byte {
+	b := n.Bytes()
+	return append(make([]byte, 32-len(b)), b...)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decode accepts both base64 alphabets with and without padding, like browsers do
+func decode(s string) ([]byte, error) {
+	for _, encoding := range []*base64.Encoding{base64.RawURLEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.StdEncoding} {
+		if b, err := encoding.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.New("invalid base64")
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package authz provides declarative authorization for routes. Routes declare the
+// privilege they require and the scope in which it is evaluated, the policy evaluates
+// the requirement against the roles of the authenticated user
+package authz
+
+import (
+	"fmt"
+	"net/http"
+
+	"chapper.dev/server/internal/log"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/jwt"
+	"chapper.dev/server/internal/services"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+
+	j "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+)
+
+// privilegesKey is the context key of the evaluated privileges
+const privilegesKey = "privileges"
+
+var authzCtx = log.NewContext("authz")
+
+// Scope describes in which context a privilege is evaluated
+type Scope int
+
+const (
+	// ScopeGlobal evaluates privileges of global roles only
+	ScopeGlobal Scope = iota
+
+	// ScopeServer evaluates privileges of global roles and the roles of the virtual
+	// server identified by the ':server-hash' path parameter
+	ScopeServer
+
+	// ScopeRoom evaluates privileges like ScopeServer for the virtual server the room
+	// identified by the ':room-hash' path parameter belongs to
+	ScopeRoom
+)
+
+// Privilege describes a privilege a route can require
+type Privilege struct {
+	name    string
+	granted func(p models.Privileges, member bool) bool
+}
+
+// Policy evaluates the privileges routes require
+type Policy struct {
+	store   store.Store
+	members services.MemberService
+	logger  *log.Logger
+}
+
+// New returns a new policy which evaluates privileges based on the roles saved in
+// the store
+func New(store store.Store, logger *log.Logger) *Policy {
+	return &Policy{
+		store:   store,
+		members: services.NewMemberService(store, logger),
+		logger:  logger,
+	}
+}
+
+// Global returns a middleware which requires the privilege p in the global scope
+func (a *Policy) Global(p Privilege) echo.MiddlewareFunc {
+	return a.Require(p, ScopeGlobal)
+}
+
+// Server returns a middleware which requires the privilege p in the scope of the
+// virtual server identified by the ':server-hash' path parameter
+func (a *Policy) Server(p Privilege) echo.MiddlewareFunc {
+	return a.Require(p, ScopeServer)
+}
+
+// Room returns a middleware which requires the privilege p in the scope of the room
+// identified by the ':room-hash' path parameter
+func (a *Policy) Room(p Privilege) echo.MiddlewareFunc {
+	return a.Require(p, ScopeRoom)
+}
+
+// Require returns a middleware which requires the privilege p in the provided scope.
+// Requests without the privilege are rejected with 403. The middleware must run after
+// the JWT middleware. The evaluated privileges can be retrieved with Privileges
+func (a *Policy) Require(p Privilege, scope Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			privileges, err := a.evaluate(p, scope, c)
+			if err != nil {
+				return a.handleError(err, c)
+			}
+
+			c.Set(privilegesKey, privileges)
+			return next(c)
+		}
+	}
+}
+
+// Privileges returns the privileges evaluated by the policy middleware of the current
+// route
+func Privileges(c echo.Context) models.Privileges {
+	privileges, _ := c.Get(privilegesKey).(models.Privileges)
+	return privileges
+}
+
+// evaluate returns the privileges of the authenticated user in the scope or an error if
+// the user lacks the privilege p
+func (a *Policy) evaluate(p Privilege, scope Scope, c echo.Context) (models.Privileges, error) {
+	token, ok := c.Get("user").(*j.Token)
+	if !ok {
+		return models.Privileges{}, errors.ErrForbidden
+	}
+
+	claims, ok := token.Claims.(*jwt.Claims)
+	if !ok {
+		return models.Privileges{}, errors.ErrForbidden
+	}
+
+	serverHash, err := a.server(scope, c)
+	if err != nil {
+		return models.Privileges{}, err
+	}
+
+	privileges, member, err := a.members.GetPrivileges(claims.Username, serverHash)
+	if err != nil {
+		return models.Privileges{}, err
+	}
+
+	// Every user is a member of the instance itself
+	if serverHash == "" {
+		member = true
+	}
+
+	if !p.granted(privileges, member) {
+		a.logger.Infoc(authzCtx, fmt.Sprintf("user '%s' lacks privilege '%s'", claims.Username, p.name))
+		return models.Privileges{}, errors.ErrForbidden
+	}
+
+	return privileges, nil
+}
+
+// server returns the hash of the virtual server the scope refers to. An empty hash
+// refers to the global scope
+func (a *Policy) server(scope Scope, c echo.Context) (string, error) {
+	switch scope {
+	case ScopeServer:
+		return c.Param("server-hash"), nil
+	case ScopeRoom:
+		room, err := a.store.GetRoom(c.Param("room-hash"))
+		if err == store.ErrNotFound {
+			return "", errors.ErrNoSuchRoom
+		}
+
+		if err != nil {
+			a.logger.Errorc(authzCtx, err)
+			return "", errors.ErrGetRoom
+		}
+
+		// A room without server would be evaluated in the global scope, where every
+		// user is a member
+		if room.Server == "" {
+			return "", errors.ErrNoSuchRoom
+		}
+		return room.Server, nil
+	default:
+		return "", nil
+	}
+}
+
+func (a *Policy) handleError(err error, c echo.Context) error {
+	a.logger.Errorc(authzCtx, err)
+
+	if se, ok := err.(*errors.ServiceError); ok {
+		return c.JSON(se.Code(), map[string]interface{}{
+			"error": se.Err(),
+		})
+	}
+
+	return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"error": "internal-error",
+	})
+}
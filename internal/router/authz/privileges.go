@@ -0,0 +1,46 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package authz
+
+import "chapper.dev/server/internal/models"
+
+var (
+	// Member requires the user to be a member of the virtual server in scope. Users
+	// who can see all servers are treated as members
+	Member = Privilege{"member", func(p models.Privileges, member bool) bool {
+		return member || p.CanSeeAllServers
+	}}
+
+	CanCreateServer = privilege("canCreateServer", func(p models.Privileges) bool { return p.CanCreateServer })
+	CanDeleteServer = privilege("canDeleteServer", func(p models.Privileges) bool { return p.CanDeleteServer })
+	CanEditServer   = privilege("canEditServer", func(p models.Privileges) bool { return p.CanEditServer })
+
+	CanSeeAllServers = privilege("canSeeAllServers", func(p models.Privileges) bool { return p.CanSeeAllServers })
+
+	CanCreateRoom = privilege("canCreateRoom", func(p models.Privileges) bool { return p.CanCreateRoom })
+	CanDeleteRoom = privilege("canDeleteRoom", func(p models.Privileges) bool { return p.CanDeleteRoom })
+	CanEditRoom   = privilege("canEditRoom", func(p models.Privileges) bool { return p.CanEditRoom })
+
+	CanCreateInvite = privilege("canCreateInvite", func(p models.Privileges) bool { return p.CanCreateInvite })
+	CanDeleteInvite = privilege("canDeleteInvite", func(p models.Privileges) bool { return p.CanDeleteInvite })
+
+	CanKickUserFromRoom   = privilege("canKickUserFromRoom", func(p models.Privileges) bool { return p.CanKickUserFromRoom })
+	CanKickUserFromServer = privilege("canKickUserFromServer", func(p models.Privileges) bool { return p.CanKickUserFromServer })
+	CanBanUserFromRoom    = privilege("canBanUserFromRoom", func(p models.Privileges) bool { return p.CanBanUserFromRoom })
+	CanBanUserFromServer  = privilege("canBanUserFromServer", func(p models.Privileges) bool { return p.CanBanUserFromServer })
+
+	CanCreateRole         = privilege("canCreateRole", func(p models.Privileges) bool { return p.CanCreateRole })
+	CanDeleteRole         = privilege("canDeleteRole", func(p models.Privileges) bool { return p.CanDeleteRole })
+	CanAssignRoleToUser   = privilege("canAssignRoleToUser", func(p models.Privileges) bool { return p.CanAssignRoleToUser })
+	CanRemoveRoleFromUser = privilege("canRemoveRoleFromUser", func(p models.Privileges) bool { return p.CanRemoveRoleFromUser })
+)
+
+// privilege returns a privilege which is granted if fn returns true, regardless of
+// membership
+func privilege(name string, fn func(p models.Privileges) bool) Privilege {
+	return Privilege{name, func(p models.Privileges, member bool) bool {
+		return fn(p)
+	}}
+}
@@ -6,10 +6,13 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"strings"
 
 	"chapper.dev/server/internal/config"
 	"chapper.dev/server/internal/log"
+	"chapper.dev/server/internal/models"
 	"chapper.dev/server/internal/modules/jwt"
 	"chapper.dev/server/internal/services"
 	"chapper.dev/server/internal/services/errors"
@@ -41,20 +44,32 @@ type Handler struct {
 	authService   services.AuthService
 	roomService   services.RoomService
 	callService   services.CallService
+	roleService   services.RoleService
+	memberService services.MemberService
+	tokenRoutes   []TokenRoute
+}
+
+// TokenRoute allows personal access tokens on the routes below Prefix. Tokens need the
+// read scope of Resource for GET requests and the write scope otherwise
+type TokenRoute struct {
+	Prefix   string
+	Resource string
 }
 
 // Map is a wrapper for an map[string]interface{}, which gets used in JSON responses
 type Map map[string]interface{}
 
 // New returns a new handler with all required services injected
-func New(store *store.Store, config *config.Config, logger *log.Logger) *Handler {
+func New(store store.Store, config *config.Config, keys *jwt.Keyring, logger *log.Logger) *Handler {
 	// Create services
+	cs := services.NewCallService()
 	is := services.NewInviteService(store, config, logger)
-	as := services.NewAuthService(store, config, logger)
+	as := services.NewAuthService(store, config, keys, logger, cs)
 	ss := services.NewServerService(store, logger)
 	us := services.NewUserService(store, config)
 	rs := services.NewRoomService(store, logger)
-	cs := services.NewCallService()
+	ros := services.NewRoleService(store, logger)
+	ms := services.NewMemberService(store, logger)
 
 	// signalingHub := broadcast.NewSignalingHub()
 	// messagingHub := broadcast.NewMessagingHub()
@@ -70,9 +85,16 @@ func New(store *store.Store, config *config.Config, logger *log.Logger) *Handler
 		serverService: ss,
 		roomService:   rs,
 		callService:   cs,
+		roleService:   ros,
+		memberService: ms,
 	}
 }
 
+// RunJobs starts the background jobs of the services, which stop when ctx is done
+func (h *Handler) RunJobs(ctx context.Context) {
+	go h.authService.RunLDAPSync(ctx)
+}
+
 func (h *Handler) handleError(err error, c echo.Context) error {
 	if se, ok := err.(*errors.ServiceError); ok {
 		h.logger.Errorc(handlerCtx, se)
@@ -87,12 +109,123 @@ func (h *Handler) handleError(err error, c echo.Context) error {
 	})
 }
 
+// ResolveIDs is a middleware which replaces legacy server and room hashes in the path
+// parameters with the IDs which replaced them
+func (h *Handler) ResolveIDs(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// The values are replaced in place. SetParamValues would shrink the pooled
+		// parameter slice of the context
+		values := c.ParamValues()
+		for i, name := range c.ParamNames() {
+			switch name {
+			case "server-hash":
+				values[i] = h.serverService.ResolveID(store.AliasServer, values[i])
+			case "room-hash":
+				values[i] = h.serverService.ResolveID(store.AliasRoom, values[i])
+			}
+		}
+
+		return next(c)
+	}
+}
+
+// Authenticate is a middleware which verifies the access token in the Authorization
+// header with the keyring. The token is saved in the context as 'user'
+func (h *Handler) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return h.authenticate(bearerToken, next)
+}
+
+// AuthenticateQuery is a middleware like Authenticate, which reads the access token
+// from the query parameter 'token'. Browsers can't set headers when opening websockets
+func (h *Handler) AuthenticateQuery(next echo.HandlerFunc) echo.HandlerFunc {
+	return h.authenticate(func(c echo.Context) string {
+		return c.QueryParam("token")
+	}, next)
+}
+
+// AcceptPersonalAccessTokens sets the routes which accept personal access tokens. All
+// other routes reject them
+func (h *Handler) AcceptPersonalAccessTokens(routes ...TokenRoute) {
+	h.tokenRoutes = routes
+}
+
+func (h *Handler) authenticate(extract func(echo.Context) string, next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var (
+			input = extract(c)
+			token *j.Token
+			err   error
+		)
+
+		if services.IsPersonalAccessToken(input) {
+			token, err = h.authService.ParsePersonalAccessToken(input, h.tokenScope(c))
+		} else {
+			token, err = h.authService.ParseAccessToken(input)
+		}
+		if err != nil {
+			return h.handleError(err, c)
+		}
+
+		c.Set("user", token)
+		return next(c)
+	}
+}
+
+// CheckSession is a middleware which rejects access tokens of revoked sessions. It has
+// to run after the JWT middleware
+func (h *Handler) CheckSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := h.authService.CheckSession(getClaimes(c))
+		if err != nil {
+			return h.handleError(err, c)
+		}
+
+		return next(c)
+	}
+}
+
+// tokenScope returns the scope personal access tokens need for the route of the
+// request. The longest matching prefix wins. It is empty if the route rejects them
+func (h *Handler) tokenScope(c echo.Context) string {
+	var (
+		path     = c.Path()
+		match    string
+		resource string
+	)
+
+	for _, route := range h.tokenRoutes {
+		if len(route.Prefix) <= len(match) {
+			continue
+		}
+
+		if path == route.Prefix || strings.HasPrefix(path, route.Prefix+"/") {
+			match, resource = route.Prefix, route.Resource
+		}
+	}
+
+	if resource == "" {
+		return ""
+	}
+	return models.Scope(resource, c.Request().Method)
+}
+
 // RunHubs runs the different broadcasting hubs
 func (h *Handler) RunHubs() {
 	// h.signalingHub.Run()
 	// h.messagingHub.Run()
 }
 
+// bearerToken returns the token of the Authorization header with the Bearer scheme
+func bearerToken(c echo.Context) string {
+	const scheme = "Bearer "
+
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	if len(auth) > len(scheme) && strings.EqualFold(auth[:len(scheme)], scheme) {
+		return auth[len(scheme):]
+	}
+	return ""
+}
+
 func getClaimes(c echo.Context) *jwt.Claims {
 	user := c.Get("user").(*j.Token)
 	return user.Claims.(*jwt.Claims)
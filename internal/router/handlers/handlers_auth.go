@@ -34,14 +34,36 @@ func (h *Handler) AuthRegister(c echo.Context) error {
 	})
 }
 
-// AuthRefresh refreshes the JWT token
+// AuthRefresh exchanges a refresh token for a new access and refresh token
 func (h *Handler) AuthRefresh(c echo.Context) error {
-	return nil
+	tokens, err := h.authService.Refresh(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"state":         "authenticated",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
+}
+
+// AuthLogout revokes the refresh token of the current login
+func (h *Handler) AuthLogout(c echo.Context) error {
+	err := h.authService.Logout(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "logged-out",
+	})
 }
 
 // AuthLogin logs a user in
 func (h *Handler) AuthLogin(c echo.Context) error {
-	token, err := h.authService.Login(c)
+	tokens, challenge, err := h.authService.Login(c)
 	if err != nil {
 		if se, ok := err.(*errors.ServiceError); ok {
 			h.logger.Errorc(handlerCtx, se)
@@ -56,16 +78,19 @@ func (h *Handler) AuthLogin(c echo.Context) error {
 		})
 	}
 
-	// If the token is empty and there is no error we need to validate the 2FA code
-	if token == "" {
+	// If there are no tokens and there is no error we need to validate the 2FA code
+	if tokens == nil {
 		return c.JSON(http.StatusOK, Map{
-			"state": "code",
-			"token": token,
+			"state":     "code",
+			"challenge": challenge.Challenge,
+			"methods":   challenge.Methods,
 		})
 	}
 
 	return c.JSON(http.StatusOK, Map{
-		"state": "authenticated",
-		"token": token,
+		"state":         "authenticated",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
 	})
 }
@@ -4,14 +4,70 @@
 
 package handlers
 
-import "github.com/labstack/echo/v4"
+import (
+	"net/http"
 
-// AuthRegisterCode enables 2FA for a user
+	"github.com/labstack/echo/v4"
+)
+
+// AuthRegisterCode starts the 2FA enrollment of a user
 func (h *Handler) AuthRegisterCode(c echo.Context) error {
-	return nil
+	enrollment, err := h.authService.RegisterTwoFA(getClaimes(c).Username)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+// AuthActivateCode enables 2FA for a user after validating the first code
+func (h *Handler) AuthActivateCode(c echo.Context) error {
+	codes, err := h.authService.ActivateTwoFA(getClaimes(c).Username, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status":         "enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// AuthRecoveryCodes replaces the 2FA recovery codes of a user
+func (h *Handler) AuthRecoveryCodes(c echo.Context) error {
+	codes, err := h.authService.RegenerateRecoveryCodes(getClaimes(c).Username, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"recovery_codes": codes,
+	})
+}
+
+// AuthDisableCode disables 2FA for a user
+func (h *Handler) AuthDisableCode(c echo.Context) error {
+	err := h.authService.DisableTwoFA(getClaimes(c).Username, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "disabled",
+	})
 }
 
 // AuthCode validates the 2FA code
 func (h *Handler) AuthCode(c echo.Context) error {
-	return nil
+	tokens, err := h.authService.LoginCode(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"state":         "authenticated",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
 }
@@ -0,0 +1,59 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AuthVerifyEmail verifies the email address of a user with the token sent by mail
+func (h *Handler) AuthVerifyEmail(c echo.Context) error {
+	err := h.authService.VerifyEmail(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "verified",
+	})
+}
+
+// AuthResendVerification sends a new verification mail to a user
+func (h *Handler) AuthResendVerification(c echo.Context) error {
+	err := h.authService.ResendVerification(getClaimes(c).Username)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "sent",
+	})
+}
+
+// AuthForgotPassword sends a password reset mail to a user
+func (h *Handler) AuthForgotPassword(c echo.Context) error {
+	err := h.authService.RequestPasswordReset(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "sent",
+	})
+}
+
+// AuthResetPassword sets a new password with the token sent by mail
+func (h *Handler) AuthResetPassword(c echo.Context) error {
+	err := h.authService.ResetPassword(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "reset",
+	})
+}
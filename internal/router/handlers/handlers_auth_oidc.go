@@ -0,0 +1,38 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AuthBeginOIDCLogin returns the URL of the identity provider the client has to
+// redirect the user to
+func (h *Handler) AuthBeginOIDCLogin(c echo.Context) error {
+	login, err := h.authService.BeginOIDCLogin()
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, login)
+}
+
+// AuthFinishOIDCLogin logs a user in with the code the identity provider redirected
+// back with
+func (h *Handler) AuthFinishOIDCLogin(c echo.Context) error {
+	tokens, err := h.authService.FinishOIDCLogin(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"state":         "authenticated",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
+}
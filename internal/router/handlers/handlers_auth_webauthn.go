@@ -0,0 +1,80 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AuthBeginWebAuthnRegistration starts the registration of a WebAuthn credential
+func (h *Handler) AuthBeginWebAuthnRegistration(c echo.Context) error {
+	ceremony, err := h.authService.BeginWebAuthnRegistration(getClaimes(c).Username)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, ceremony)
+}
+
+// AuthFinishWebAuthnRegistration saves the WebAuthn credential created by the
+// authenticator
+func (h *Handler) AuthFinishWebAuthnRegistration(c echo.Context) error {
+	credential, err := h.authService.FinishWebAuthnRegistration(getClaimes(c).Username, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, credential)
+}
+
+// AuthGetWebAuthnCredentials returns the WebAuthn credentials of a user
+func (h *Handler) AuthGetWebAuthnCredentials(c echo.Context) error {
+	credentials, err := h.authService.GetWebAuthnCredentials(getClaimes(c).Username)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, credentials)
+}
+
+// AuthDeleteWebAuthnCredential deletes a WebAuthn credential of a user
+func (h *Handler) AuthDeleteWebAuthnCredential(c echo.Context) error {
+	err := h.authService.DeleteWebAuthnCredential(getClaimes(c).Username, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "deleted",
+	})
+}
+
+// AuthBeginWebAuthnLogin starts a login with a WebAuthn credential, either as second
+// factor or passwordless
+func (h *Handler) AuthBeginWebAuthnLogin(c echo.Context) error {
+	ceremony, err := h.authService.BeginWebAuthnLogin(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, ceremony)
+}
+
+// AuthFinishWebAuthnLogin validates the assertion of the authenticator
+func (h *Handler) AuthFinishWebAuthnLogin(c echo.Context) error {
+	tokens, err := h.authService.FinishWebAuthnLogin(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"state":         "authenticated",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
+}
@@ -15,7 +15,7 @@ func (h *Handler) NewCall(c echo.Context) error {
 	claims := getClaimes(c)
 	roomHash := c.Param("room-hash")
 
-	err := h.callService.NewCall(claims.Username, roomHash, c.Response().Writer, c.Request())
+	err := h.callService.NewCall(claims.Username, claims.SessionID, roomHash, c.Response().Writer, c.Request())
 	if err != nil {
 		log.Printf("ERROR [Router] Unable to create new call: %v\n", err)
 		return err
@@ -25,10 +25,10 @@ func (h *Handler) NewCall(c echo.Context) error {
 }
 
 func (h *Handler) JoinCall(c echo.Context) error {
-	// claims := getClaimes(c)
+	claims := getClaimes(c)
 	roomHash := c.Param("room-hash")
 
-	err := h.callService.NewCall("Test", roomHash, c.Response().Writer, c.Request())
+	err := h.callService.NewCall(claims.Username, claims.SessionID, roomHash, c.Response().Writer, c.Request())
 	if err != nil {
 		log.Printf("ERROR [Router] Unable to create or join call: %v\n", err)
 		return err
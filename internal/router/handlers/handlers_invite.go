@@ -21,12 +21,6 @@ func (h *Handler) GetInvite(c echo.Context) error {
 func (h *Handler) CreateInvite(c echo.Context) error {
 	claims := getClaimes(c)
 
-	if !claims.Privileges.CanCreateInvite {
-		return c.JSON(http.StatusUnauthorized, Map{
-			"error": ErrUnauthorized,
-		})
-	}
-
 	invite, err := h.inviteService.CreateInvite(claims.Username, c)
 	if err != nil {
 		if se, ok := err.(*errors.ServiceError); ok {
@@ -49,14 +43,5 @@ func (h *Handler) CreateInvite(c echo.Context) error {
 
 // DeleteInvite deletes an invite link indentified by it's name
 func (h *Handler) DeleteInvite(c echo.Context) error {
-	claims := getClaimes(c)
-
-	if !claims.Privileges.CanDeleteInvite {
-		return c.JSON(http.StatusUnauthorized, Map{
-			"errror": "Invalid request",
-			"code":   ErrUnauthorized,
-		})
-	}
-
 	return nil
 }
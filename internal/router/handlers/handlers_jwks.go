@@ -0,0 +1,18 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetJWKS returns the public keys which verify access tokens as JSON Web Key Set. Other
+// services cache it and fetch it again if a token carries an unknown key ID
+func (h *Handler) GetJWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.authService.JWKS())
+}
@@ -5,7 +5,6 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -13,35 +12,173 @@ import (
 
 // GetUserServers returns all servers the user is a member of
 func (h *Handler) GetUserServers(c echo.Context) error {
-	// claims := getClaimes(c)
+	claims := getClaimes(c)
 
-	// servers, err := h.userService.GetUserServers(claims.Username)
-	// if err != nil {
-	// 	log.Printf("ERROR [Router] Failed to get servers: %v\n", err)
-	// 	return c.JSON(http.StatusInternalServerError, Map{
-	// 		"error": ErrInternal,
-	// 	})
-	// }
+	servers, err := h.memberService.GetUserServers(claims.Username)
+	if err != nil {
+		return h.handleError(err, c)
+	}
 
-	// return c.JSON(http.StatusOK, Map{
-	// 	"servers": servers,
-	// })
-	return nil
+	return c.JSON(http.StatusOK, Map{
+		"servers": servers,
+	})
 }
 
 // PutUserServer adds one new server to the users list of servers he is a member of
+// by using an invite
 func (h *Handler) PutUserServer(c echo.Context) error {
 	claims := getClaimes(c)
 
-	servers, err := h.userService.PutUserServer(claims.Username)
+	server, err := h.memberService.JoinServer(claims.Username, c)
 	if err != nil {
-		log.Printf("ERROR [Router] Failed to put server: %v\n", err)
-		return c.JSON(http.StatusInternalServerError, Map{
-			"error": ErrInternal,
-		})
+		return h.handleError(err, c)
 	}
 
 	return c.JSON(http.StatusOK, Map{
-		"servers": servers,
+		"server": server,
+	})
+}
+
+// ChangePassword changes the password of the user
+func (h *Handler) ChangePassword(c echo.Context) error {
+	claims := getClaimes(c)
+
+	tokens, err := h.authService.ChangePassword(claims.Username, claims.SessionID, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
+}
+
+// ChangeEmail changes the email address of the user
+func (h *Handler) ChangeEmail(c echo.Context) error {
+	err := h.authService.ChangeEmail(getClaimes(c).Username, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "changed",
+	})
+}
+
+// ChangeUsername renames the user
+func (h *Handler) ChangeUsername(c echo.Context) error {
+	claims := getClaimes(c)
+
+	tokens, err := h.authService.ChangeUsername(claims.Username, claims.SessionID, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
+}
+
+// GetSessions returns all sessions of the user
+func (h *Handler) GetSessions(c echo.Context) error {
+	sessions, err := h.authService.GetSessions(getClaimes(c))
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"sessions": sessions,
+	})
+}
+
+// DeleteSession revokes one session of the user
+func (h *Handler) DeleteSession(c echo.Context) error {
+	err := h.authService.RevokeSession(getClaimes(c).Username, c.Param("session-id"))
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "revoked",
+	})
+}
+
+// DeleteSessions revokes all sessions of the user, including the current one
+func (h *Handler) DeleteSessions(c echo.Context) error {
+	err := h.authService.RevokeSessions(getClaimes(c).Username, "")
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "revoked",
+	})
+}
+
+// GetAccessTokens returns the personal access tokens of the user and of the bots of the
+// user
+func (h *Handler) GetAccessTokens(c echo.Context) error {
+	tokens, err := h.authService.GetPersonalAccessTokens(getClaimes(c).Username)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"tokens": tokens,
+	})
+}
+
+// CreateAccessToken creates a new personal access token of the user or of one of the
+// bots of the user. The token is only returned once
+func (h *Handler) CreateAccessToken(c echo.Context) error {
+	token, plain, err := h.authService.CreatePersonalAccessToken(getClaimes(c).Username, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"token":        token,
+		"access_token": plain,
+	})
+}
+
+// DeleteAccessToken revokes one personal access token of the user or of one of the bots
+// of the user
+func (h *Handler) DeleteAccessToken(c echo.Context) error {
+	err := h.authService.RevokePersonalAccessToken(getClaimes(c).Username, c.Param("token-id"))
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "revoked",
+	})
+}
+
+// GetBots returns the bots of the user
+func (h *Handler) GetBots(c echo.Context) error {
+	bots, err := h.authService.GetBots(getClaimes(c).Username)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"bots": bots,
+	})
+}
+
+// CreateBot creates a new bot owned by the user
+func (h *Handler) CreateBot(c echo.Context) error {
+	bot, err := h.authService.CreateBot(getClaimes(c).Username, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"bot": bot,
 	})
 }
@@ -0,0 +1,121 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package handlers
+
+import (
+	"net/http"
+
+	"chapper.dev/server/internal/router/authz"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetMembers returns all members of a server
+func (h *Handler) GetMembers(c echo.Context) error {
+	members, err := h.memberService.GetMembers(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"members": members,
+	})
+}
+
+// KickMember removes a member from a server
+func (h *Handler) KickMember(c echo.Context) error {
+	privileges := authz.Privileges(c)
+
+	err := h.memberService.KickMember(privileges, c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "kicked",
+	})
+}
+
+// AssignMemberRole assigns a server role to a member
+func (h *Handler) AssignMemberRole(c echo.Context) error {
+	privileges := authz.Privileges(c)
+
+	err := h.roleService.AssignRole(privileges, c.Param("server-hash"), c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "assigned",
+	})
+}
+
+// RemoveMemberRole removes a server role from a member
+func (h *Handler) RemoveMemberRole(c echo.Context) error {
+	privileges := authz.Privileges(c)
+
+	err := h.roleService.RemoveRole(privileges, c.Param("server-hash"), c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "removed",
+	})
+}
+
+// GetServerRoles returns all roles of a server
+func (h *Handler) GetServerRoles(c echo.Context) error {
+	roles, err := h.roleService.GetRoles(c.Param("server-hash"))
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"roles": roles,
+	})
+}
+
+// CreateServerRole creates a new role in a server
+func (h *Handler) CreateServerRole(c echo.Context) error {
+	privileges := authz.Privileges(c)
+
+	role, err := h.roleService.CreateRole(privileges, c.Param("server-hash"), c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"role": role,
+	})
+}
+
+// UpdateServerRole updates a role of a server identified by it's ID
+func (h *Handler) UpdateServerRole(c echo.Context) error {
+	privileges := authz.Privileges(c)
+
+	err := h.roleService.UpdateRole(privileges, c.Param("server-hash"), c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "updated",
+	})
+}
+
+// DeleteServerRole deletes a role of a server identified by it's ID
+func (h *Handler) DeleteServerRole(c echo.Context) error {
+	privileges := authz.Privileges(c)
+
+	err := h.roleService.DeleteRole(privileges, c.Param("server-hash"), c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "deleted",
+	})
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package handlers
+
+import (
+	"net/http"
+
+	"chapper.dev/server/internal/router/authz"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CreateRole creates a new global role
+func (h *Handler) CreateRole(c echo.Context) error {
+	role, err := h.roleService.CreateRole(authz.Privileges(c), "", c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"role": role,
+	})
+}
+
+// GetRole returns a global role identified by it's ID
+func (h *Handler) GetRole(c echo.Context) error {
+	role, err := h.roleService.GetRole("", c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"role": role,
+	})
+}
+
+// GetRoles returns all global roles
+func (h *Handler) GetRoles(c echo.Context) error {
+	roles, err := h.roleService.GetRoles("")
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"roles": roles,
+	})
+}
+
+// UpdateRole updates a global role identified by it's ID
+func (h *Handler) UpdateRole(c echo.Context) error {
+	err := h.roleService.UpdateRole(authz.Privileges(c), "", c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "updated",
+	})
+}
+
+// DeleteRole deletes a global role identified by it's ID
+func (h *Handler) DeleteRole(c echo.Context) error {
+	err := h.roleService.DeleteRole(authz.Privileges(c), "", c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "deleted",
+	})
+}
+
+// GetUserRoles returns all global roles of a user
+func (h *Handler) GetUserRoles(c echo.Context) error {
+	roles, err := h.roleService.GetUserRoles(c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"roles": roles,
+	})
+}
+
+// AssignRole assigns a global role to a user
+func (h *Handler) AssignRole(c echo.Context) error {
+	err := h.roleService.AssignRole(authz.Privileges(c), "", c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "assigned",
+	})
+}
+
+// RemoveRole removes a global role from a user
+func (h *Handler) RemoveRole(c echo.Context) error {
+	err := h.roleService.RemoveRole(authz.Privileges(c), "", c)
+	if err != nil {
+		return h.handleError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, Map{
+		"status": "removed",
+	})
+}
@@ -14,15 +14,7 @@ import (
 
 // CreateRoom handles incoming requests to create a room
 func (h *Handler) CreateRoom(c echo.Context) error {
-	// claims := getClaimes(c)
-
-	// if !claims.Privileges.CanCreateRoom {
-	// 	return c.JSON(http.StatusUnauthorized, Map{
-	// 		"error": ErrUnauthorized,
-	// 	})
-	// }
-
-	err := h.roomService.CreateRoom(c)
+	room, err := h.roomService.CreateRoom(c)
 	if err != nil {
 		if se, ok := err.(*errors.ServiceError); ok {
 			h.logger.Errorc(handlerCtx, se)
@@ -39,6 +31,7 @@ func (h *Handler) CreateRoom(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, Map{
 		"status": "created",
+		"room":   room,
 	})
 }
 
@@ -64,9 +57,9 @@ func (h *Handler) GetRoom(c echo.Context) error {
 	})
 }
 
-// GetRooms handles incoming requests to get multiple rooms
+// GetRooms handles incoming requests to get all rooms of a server
 func (h *Handler) GetRooms(c echo.Context) error {
-	rooms, err := h.roomService.GetRooms()
+	rooms, err := h.roomService.GetRooms(c)
 	if err != nil {
 		if se, ok := err.(*errors.ServiceError); ok {
 			h.logger.Errorc(handlerCtx, se)
@@ -110,14 +103,6 @@ func (h *Handler) UpdateRoom(c echo.Context) error {
 
 // DeleteRoom handles incoming requests to delete one room
 func (h *Handler) DeleteRoom(c echo.Context) error {
-	claims := getClaimes(c)
-
-	if !claims.Privileges.CanDeleteRoom {
-		return c.JSON(http.StatusUnauthorized, Map{
-			"errror": ErrUnauthorized,
-		})
-	}
-
 	err := h.roomService.DeleteRoom(c)
 	if err != nil {
 		if se, ok := err.(*errors.ServiceError); ok {
@@ -5,7 +5,6 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -13,21 +12,14 @@ import (
 
 // CreateServer handles incoming requests
 func (h *Handler) CreateServer(c echo.Context) error {
-	claims := getClaimes(c)
-
-	if !claims.Privileges.CanCreateServer {
-		return c.JSON(http.StatusUnauthorized, Map{
-			"error": ErrUnauthorized,
-		})
-	}
-
-	err := h.serverService.CreateServer(c)
+	server, err := h.serverService.CreateServer(getClaimes(c).Username, c)
 	if err != nil {
-		h.handleError(err, c)
+		return h.handleError(err, c)
 	}
 
 	return c.JSON(http.StatusOK, Map{
 		"status": "created",
+		"server": server,
 	})
 }
 
@@ -35,7 +27,7 @@ func (h *Handler) CreateServer(c echo.Context) error {
 func (h *Handler) GetServer(c echo.Context) error {
 	server, err := h.serverService.GetServer(c)
 	if err != nil {
-		h.handleError(err, c)
+		return h.handleError(err, c)
 	}
 
 	return c.JSON(http.StatusOK, Map{
@@ -45,17 +37,9 @@ func (h *Handler) GetServer(c echo.Context) error {
 
 // GetServers returns all servers
 func (h *Handler) GetServers(c echo.Context) error {
-	claims := getClaimes(c)
-
-	if !claims.Privileges.CanSeeAllServers {
-		return c.JSON(http.StatusUnauthorized, Map{
-			"error": ErrUnauthorized,
-		})
-	}
-
 	servers, err := h.serverService.GetServers()
 	if err != nil {
-		h.handleError(err, c)
+		return h.handleError(err, c)
 	}
 
 	return c.JSON(http.StatusOK, Map{
@@ -70,20 +54,9 @@ func (h *Handler) UpdateServer(c echo.Context) error {
 
 // DeleteServer deletes a server identified by it's hash
 func (h *Handler) DeleteServer(c echo.Context) error {
-	claims := getClaimes(c)
-
-	if !claims.Privileges.CanDeleteServer {
-		return c.JSON(http.StatusUnauthorized, Map{
-			"error": ErrUnauthorized,
-		})
-	}
-
 	err := h.serverService.DeleteServer(c.Param("server-hash"))
 	if err != nil {
-		log.Printf("ERROR [Router] Failed to delete server: %v\n", err)
-		return c.JSON(http.StatusInternalServerError, Map{
-			"error": ErrInternal,
-		})
+		return h.handleError(err, c)
 	}
 
 	return c.JSON(http.StatusOK, Map{
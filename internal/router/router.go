@@ -11,7 +11,8 @@ import (
 
 	"chapper.dev/server/internal/config"
 	"chapper.dev/server/internal/log"
-	"chapper.dev/server/internal/modules/jwt"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/router/authz"
 	"chapper.dev/server/internal/router/handlers"
 	"chapper.dev/server/internal/utils"
 
@@ -40,6 +41,10 @@ func New(c *config.Config, l *log.Logger) *Router {
 	// Hide startup message
 	e.HideBanner = true
 
+	// Only trust the X-Forwarded-For header of proxies in private networks, so that
+	// clients can't spoof their address
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+
 	// Register middlewares
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
@@ -66,8 +71,9 @@ func New(c *config.Config, l *log.Logger) *Router {
 	}
 }
 
-// AddRoutes adds all routes to the router instance and registers the handlers
-func (r *Router) AddRoutes(handle *handlers.Handler) {
+// AddRoutes adds all routes to the router instance and registers the handlers. Routes
+// which require privileges declare them with the provided policy
+func (r *Router) AddRoutes(handle *handlers.Handler, policy *authz.Policy) {
 	// TODO: Move this to config validation / default values
 	webRoot, err := utils.Abs(r.config.Router.WebPath)
 	if err != nil {
@@ -90,11 +96,36 @@ func (r *Router) AddRoutes(handle *handlers.Handler) {
 	media.GET("/images", handle.GetImage)
 	media.GET("/videos", handle.GetVideo)
 
-	// JWT middleware setup
-	jwtware := middleware.JWTWithConfig(middleware.JWTConfig{
-		SigningKey: []byte(r.config.Router.JWTSecret),
-		Claims:     &jwt.Claims{},
-	})
+	// Public keys which verify access tokens
+	r.echo.GET("/.well-known/jwks.json", handle.GetJWKS)
+
+	// Scripts and bots authenticate with personal access tokens instead of access
+	// tokens. Only the routes below these prefixes accept them
+	handle.AcceptPersonalAccessTokens(
+		handlers.TokenRoute{Prefix: "/key", Resource: models.ResourceProfiles},
+		handlers.TokenRoute{Prefix: "/messaging", Resource: models.ResourceMessages},
+		handlers.TokenRoute{Prefix: "/api/v1/invite", Resource: models.ResourceInvites},
+		handlers.TokenRoute{Prefix: "/api/v1/profile", Resource: models.ResourceProfiles},
+		handlers.TokenRoute{Prefix: "/api/v1/servers", Resource: models.ResourceServers},
+		handlers.TokenRoute{Prefix: "/api/v1/servers/:server-hash/members", Resource: models.ResourceMembers},
+		handlers.TokenRoute{Prefix: "/api/v1/servers/:server-hash/rooms", Resource: models.ResourceRooms},
+		handlers.TokenRoute{Prefix: "/api/v1/servers/:server-hash/roles", Resource: models.ResourceRoles},
+		handlers.TokenRoute{Prefix: "/api/v1/roles", Resource: models.ResourceRoles},
+		handlers.TokenRoute{Prefix: "/api/v1/me/servers", Resource: models.ResourceServers},
+		handlers.TokenRoute{Prefix: "/api/v1/me/server", Resource: models.ResourceServers},
+	)
+
+	// JWT middleware setup. Access tokens of revoked sessions are rejected, personal
+	// access tokens need the scope of the route
+	jwtware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return handle.Authenticate(handle.CheckSession(next))
+	}
+
+	// Browsers can't set headers when opening websockets, the token is passed in the
+	// query instead
+	wsware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return handle.AuthenticateQuery(handle.CheckSession(next))
+	}
 
 	// AVATAR
 	avatar := r.echo.Group("/avatar")
@@ -120,8 +151,10 @@ func (r *Router) AddRoutes(handle *handlers.Handler) {
 	v1 := api.Group("/v1")
 
 	// INVITES
+	// The server of a new invite is part of the request body, so the invite service
+	// checks the privilege to create invites itself
 	invite := v1.Group("/invite")
-	invite.DELETE("/:name", handle.DeleteInvite)
+	invite.DELETE("/:name", handle.DeleteInvite, policy.Global(authz.CanDeleteInvite))
 	invite.PUT("", handle.CreateInvite)
 
 	// PROFILE
@@ -129,38 +162,94 @@ func (r *Router) AddRoutes(handle *handlers.Handler) {
 	profile.GET("/:username", handle.GetProfile)
 
 	// VIRTUAL SERVERS
-	server := v1.Group("/servers")
-	server.DELETE("/:server-hash", handle.DeleteServer)
-	server.POST("/:server-hash", handle.UpdateServer)
-	server.GET("/:server-hash", handle.GetServer)
-	server.PUT("", handle.CreateServer)
-	server.GET("", handle.GetServers)
+	server := v1.Group("/servers", handle.ResolveIDs)
+	server.DELETE("/:server-hash", handle.DeleteServer, policy.Server(authz.CanDeleteServer))
+	server.POST("/:server-hash", handle.UpdateServer, policy.Server(authz.CanEditServer))
+	server.GET("/:server-hash", handle.GetServer, policy.Server(authz.Member))
+	server.PUT("", handle.CreateServer, policy.Global(authz.CanCreateServer))
+	server.GET("", handle.GetServers, policy.Global(authz.CanSeeAllServers))
+
+	// MEMBERS
+	members := server.Group("/:server-hash/members")
+	members.DELETE("/:username/roles/:role-id", handle.RemoveMemberRole, policy.Server(authz.CanRemoveRoleFromUser))
+	members.PUT("/:username/roles/:role-id", handle.AssignMemberRole, policy.Server(authz.CanAssignRoleToUser))
+	members.DELETE("/:username", handle.KickMember, policy.Server(authz.CanKickUserFromServer))
+	members.GET("", handle.GetMembers, policy.Server(authz.Member))
 
 	// ROOMS
-	rooms := v1.Group("/rooms")
-	rooms.DELETE("/:room-hash", handle.DeleteRoom)
-	rooms.POST("/:room-hash", handle.UpdateRoom)
-	rooms.GET("/:room-hash", handle.GetRoom)
-	rooms.PUT("", handle.CreateRoom)
-	rooms.GET("", handle.GetRooms)
+	rooms := server.Group("/:server-hash/rooms")
+	rooms.DELETE("/:room-hash", handle.DeleteRoom, policy.Server(authz.CanDeleteRoom))
+	rooms.POST("/:room-hash", handle.UpdateRoom, policy.Server(authz.CanEditRoom))
+	rooms.GET("/:room-hash", handle.GetRoom, policy.Server(authz.Member))
+	rooms.PUT("", handle.CreateRoom, policy.Server(authz.CanCreateRoom))
+	rooms.GET("", handle.GetRooms, policy.Server(authz.Member))
+
+	// SERVER ROLES
+	serverRoles := server.Group("/:server-hash/roles")
+	serverRoles.DELETE("/:role-id", handle.DeleteServerRole, policy.Server(authz.CanDeleteRole))
+	serverRoles.POST("/:role-id", handle.UpdateServerRole, policy.Server(authz.CanCreateRole))
+	serverRoles.PUT("", handle.CreateServerRole, policy.Server(authz.CanCreateRole))
+	serverRoles.GET("", handle.GetServerRoles, policy.Server(authz.Member))
+
+	// ROLES
+	roles := v1.Group("/roles")
+	roles.GET("/users/:username", handle.GetUserRoles)
+	roles.DELETE("/:role-id/users/:username", handle.RemoveRole, policy.Global(authz.CanRemoveRoleFromUser))
+	roles.PUT("/:role-id/users/:username", handle.AssignRole, policy.Global(authz.CanAssignRoleToUser))
+	roles.DELETE("/:role-id", handle.DeleteRole, policy.Global(authz.CanDeleteRole))
+	roles.POST("/:role-id", handle.UpdateRole, policy.Global(authz.CanCreateRole))
+	roles.GET("/:role-id", handle.GetRole)
+	roles.PUT("", handle.CreateRole, policy.Global(authz.CanCreateRole))
+	roles.GET("", handle.GetRoles)
 
 	// CALLS
+	// Only members of the server of a room can join its call. Aliases of the room are
+	// resolved after the token was verified
 	calls := r.echo.Group("/calls")
 	// calls.POST("/new/:room-hash", handle.NewCall)
 	// calls.POST("/sdp/:room-hash", handle.ForwardSDP)
-	calls.GET("/join/:room-hash", handle.JoinCall)
+	calls.GET("/join/:room-hash", handle.JoinCall, wsware, handle.ResolveIDs, policy.Room(authz.Member))
 
 	//// AUTH ////
 	auth := r.echo.Group("/auth")
-	auth.POST("/code/register", handle.AuthRegisterCode)
+	auth.POST("/code/register", handle.AuthRegisterCode, jwtware)
+	auth.POST("/code/activate", handle.AuthActivateCode, jwtware)
+	auth.POST("/code/recovery", handle.AuthRecoveryCodes, jwtware)
+	auth.POST("/code/disable", handle.AuthDisableCode, jwtware)
 	auth.POST("/register", handle.AuthRegister)
 	auth.POST("/refresh", handle.AuthRefresh)
+	auth.POST("/logout", handle.AuthLogout)
 	auth.POST("/login", handle.AuthLogin)
 	auth.POST("/code", handle.AuthCode)
+	auth.POST("/email/verify", handle.AuthVerifyEmail)
+	auth.POST("/email/resend", handle.AuthResendVerification, jwtware)
+	auth.POST("/password/forgot", handle.AuthForgotPassword)
+	auth.POST("/password/reset", handle.AuthResetPassword)
+	auth.GET("/oidc", handle.AuthBeginOIDCLogin)
+	auth.POST("/oidc/callback", handle.AuthFinishOIDCLogin)
+
+	webauthn := auth.Group("/webauthn")
+	webauthn.POST("/register/begin", handle.AuthBeginWebAuthnRegistration, jwtware)
+	webauthn.POST("/register/finish", handle.AuthFinishWebAuthnRegistration, jwtware)
+	webauthn.GET("/credentials", handle.AuthGetWebAuthnCredentials, jwtware)
+	webauthn.DELETE("/credentials/:credential-id", handle.AuthDeleteWebAuthnCredential, jwtware)
+	webauthn.POST("/login/begin", handle.AuthBeginWebAuthnLogin)
+	webauthn.POST("/login/finish", handle.AuthFinishWebAuthnLogin)
 
 	me := v1.Group("/me")
 	me.GET("/servers", handle.GetUserServers)
 	me.PUT("/server", handle.PutUserServer)
+	me.POST("/password", handle.ChangePassword)
+	me.POST("/email", handle.ChangeEmail)
+	me.POST("/username", handle.ChangeUsername)
+	me.DELETE("/sessions/:session-id", handle.DeleteSession)
+	me.DELETE("/sessions", handle.DeleteSessions)
+	me.GET("/sessions", handle.GetSessions)
+	me.DELETE("/tokens/:token-id", handle.DeleteAccessToken)
+	me.PUT("/tokens", handle.CreateAccessToken)
+	me.GET("/tokens", handle.GetAccessTokens)
+	me.PUT("/bots", handle.CreateBot)
+	me.GET("/bots", handle.GetBots)
 
 	// This serves the correct SPA route (even when reloading)
 	r.echo.File("/*", webRoot)
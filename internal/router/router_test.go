@@ -0,0 +1,325 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/id"
+	"chapper.dev/server/internal/router/authz"
+	"chapper.dev/server/internal/router/handlers"
+	"chapper.dev/server/internal/testutil"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newTestRouter returns a router with all routes, wired like the app but backed by the
+// memory store of the returned environment
+func newTestRouter(t *testing.T) (*Router, *testutil.Env) {
+	env := testutil.New(t, nil)
+
+	r := New(env.Config, env.Logger)
+	r.AddRoutes(handlers.New(env.Store, env.Config, env.Keys, env.Logger), authz.New(env.Store, env.Logger))
+	return r, env
+}
+
+// do sends the request with body encoded as JSON through the router and returns the
+// status and the decoded response. An access token is sent if token is not empty
+func do(t *testing.T, r *Router, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	r.echo.ServeHTTP(rec, req)
+
+	var res map[string]interface{}
+	if rec.Body.Len() > 0 {
+		err := json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Fatalf("%s %s returned no JSON: %s", method, path, rec.Body.String())
+		}
+	}
+	return rec.Code, res
+}
+
+// register registers the user and returns an access token of a new login
+func register(t *testing.T, r *Router, username string) string {
+	code, res := do(t, r, http.MethodPost, "/auth/register", "", map[string]string{
+		"username":  username,
+		"password":  username + "-password",
+		"publickey": username + "-key",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("POST /auth/register = %d %v, want 200", code, res)
+	}
+
+	code, res = do(t, r, http.MethodPost, "/auth/login", "", map[string]string{
+		"username": username,
+		"password": username + "-password",
+	})
+	if code != http.StatusOK || res["state"] != "authenticated" {
+		t.Fatalf("POST /auth/login = %d %v, want 200 authenticated", code, res)
+	}
+
+	token, _ := res["token"].(string)
+	if token == "" {
+		t.Fatalf("POST /auth/login returned no token: %v", res)
+	}
+	return token
+}
+
+func TestAPI(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	// The first user becomes superadmin
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+
+	// Like the JWT middleware of echo, a missing token is a bad request
+	code, _ := do(t, r, http.MethodGet, "/api/v1/me/servers", "", nil)
+	if code != http.StatusBadRequest {
+		t.Errorf("GET /api/v1/me/servers without token = %d, want 400", code)
+	}
+
+	code, _ = do(t, r, http.MethodGet, "/api/v1/me/servers", "not-a-token", nil)
+	if code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/me/servers with an invalid token = %d, want 401", code)
+	}
+
+	code, res := do(t, r, http.MethodPut, "/api/v1/servers", bob, map[string]string{"name": "Bobs"})
+	if code != http.StatusForbidden {
+		t.Errorf("PUT /api/v1/servers by bob = %d %v, want 403", code, res)
+	}
+
+	code, res = do(t, r, http.MethodPut, "/api/v1/servers", alice, map[string]string{"name": "Chapper"})
+	if code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/servers by alice = %d %v, want 200", code, res)
+	}
+
+	server, _ := res["server"].(map[string]interface{})
+	hash, _ := server["hash"].(string)
+	if hash == "" || server["name"] != "Chapper" {
+		t.Fatalf("PUT /api/v1/servers returned server %v", res["server"])
+	}
+
+	code, res = do(t, r, http.MethodGet, "/api/v1/servers/"+hash, alice, nil)
+	if code != http.StatusOK {
+		t.Errorf("GET /api/v1/servers/:hash by alice = %d %v, want 200", code, res)
+	}
+
+	code, res = do(t, r, http.MethodGet, "/api/v1/servers/"+hash, bob, nil)
+	if code != http.StatusForbidden {
+		t.Errorf("GET /api/v1/servers/:hash by bob, who is no member = %d %v, want 403", code, res)
+	}
+
+	code, res = do(t, r, http.MethodGet, "/api/v1/me/sessions", alice, nil)
+	if sessions, _ := res["sessions"].([]interface{}); code != http.StatusOK || len(sessions) != 1 {
+		t.Errorf("GET /api/v1/me/sessions = %d %v, want 200 with one session", code, res)
+	}
+
+	// Access tokens of revoked sessions are rejected before they expire
+	code, res = do(t, r, http.MethodDelete, "/api/v1/me/sessions", alice, nil)
+	if code != http.StatusOK {
+		t.Fatalf("DELETE /api/v1/me/sessions = %d %v, want 200", code, res)
+	}
+
+	code, _ = do(t, r, http.MethodGet, "/api/v1/me/servers", alice, nil)
+	if code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/me/servers with the token of a revoked session = %d, want 401", code)
+	}
+
+	code, _ = do(t, r, http.MethodGet, "/api/v1/me/servers", bob, nil)
+	if code != http.StatusOK {
+		t.Errorf("GET /api/v1/me/servers by bob = %d, want 200", code)
+	}
+}
+
+// createServer creates a server and returns its hash
+func createServer(t *testing.T, r *Router, token, name string) string {
+	code, res := do(t, r, http.MethodPut, "/api/v1/servers", token, map[string]string{"name": name})
+	if code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/servers = %d %v, want 200", code, res)
+	}
+
+	server, _ := res["server"].(map[string]interface{})
+	hash, _ := server["hash"].(string)
+	return hash
+}
+
+// createAccessToken creates a personal access token with the scopes and returns it
+func createAccessToken(t *testing.T, r *Router, token string, body map[string]interface{}) string {
+	code, res := do(t, r, http.MethodPut, "/api/v1/me/tokens", token, body)
+	if code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/me/tokens = %d %v, want 200", code, res)
+	}
+
+	plain, _ := res["access_token"].(string)
+	if plain == "" {
+		t.Fatalf("PUT /api/v1/me/tokens returned no token: %v", res)
+	}
+	return plain
+}
+
+func TestPersonalAccessTokens(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	alice := register(t, r, "alice")
+	hash := createServer(t, r, alice, "Chapper")
+
+	token := createAccessToken(t, r, alice, map[string]interface{}{
+		"name":     "Script",
+		"scopes":   []string{"servers:read"},
+		"password": "alice-password",
+	})
+
+	for _, test := range []struct {
+		method, path string
+		want         int
+	}{
+		// The longest matching prefix decides the resource
+		{http.MethodGet, "/api/v1/me/servers", http.StatusOK},
+		{http.MethodGet, "/api/v1/servers/" + hash, http.StatusOK},
+		{http.MethodGet, "/api/v1/servers/" + hash + "/members", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/servers/" + hash + "/rooms", http.StatusForbidden},
+
+		// Writing needs another scope than reading
+		{http.MethodPut, "/api/v1/servers", http.StatusForbidden},
+		{http.MethodPost, "/api/v1/servers/" + hash, http.StatusForbidden},
+
+		// Routes outside the resources reject personal access tokens
+		{http.MethodGet, "/api/v1/me/tokens", http.StatusForbidden},
+		{http.MethodPut, "/api/v1/me/tokens", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/me/sessions", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/roles", http.StatusForbidden},
+	} {
+		code, res := do(t, r, test.method, test.path, token, map[string]string{})
+		if code != test.want {
+			t.Errorf("%s %s with a personal access token = %d %v, want %d", test.method, test.path, code, res, test.want)
+		}
+
+		// Rejected because of the scope, not because of missing privileges
+		if test.want == http.StatusForbidden && res["error"] != "insufficient-scope" {
+			t.Errorf("%s %s with a personal access token returned %v, want insufficient-scope", test.method, test.path, res)
+		}
+	}
+
+	code, _ := do(t, r, http.MethodGet, "/api/v1/me/servers", "chp_unknown", nil)
+	if code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/me/servers with an unknown personal access token = %d, want 401", code)
+	}
+}
+
+func TestPersonalAccessTokensOfBots(t *testing.T) {
+	r, env := newTestRouter(t)
+
+	alice := register(t, r, "alice")
+
+	code, res := do(t, r, http.MethodPut, "/api/v1/me/bots", alice, map[string]string{
+		"username": "alice-bot",
+		"password": "alice-password",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/me/bots = %d %v, want 200", code, res)
+	}
+
+	bot := createAccessToken(t, r, alice, map[string]interface{}{
+		"name":     "Bot",
+		"scopes":   []string{"servers:read"},
+		"bot":      "alice-bot",
+		"password": "alice-password",
+	})
+
+	code, res = do(t, r, http.MethodGet, "/api/v1/me/servers", bot, nil)
+	if code != http.StatusOK {
+		t.Fatalf("GET /api/v1/me/servers as bot = %d %v, want 200", code, res)
+	}
+
+	// Bots stop working with their owner
+	err := env.Store.UpdateUserDisabled("alice", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, res = do(t, r, http.MethodGet, "/api/v1/me/servers", bot, nil)
+	if code != http.StatusForbidden || res["error"] != "user-disabled" {
+		t.Errorf("GET /api/v1/me/servers as bot of a disabled owner = %d %v, want 403 user-disabled", code, res)
+	}
+}
+
+func TestJoinCall(t *testing.T) {
+	r, env := newTestRouter(t)
+
+	// The first user becomes superadmin and can see all servers
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+	hash := createServer(t, r, alice, "Chapper")
+
+	code, res := do(t, r, http.MethodPut, "/api/v1/servers/"+hash+"/rooms", alice, map[string]string{
+		"name": "Voice",
+		"type": "voice",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/servers/:hash/rooms = %d %v, want 200", code, res)
+	}
+
+	room, _ := res["room"].(map[string]interface{})
+	roomHash, _ := room["hash"].(string)
+
+	// Rooms of servers which were created before rooms belonged to servers
+	orphan, err := id.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.Store.CreateRoom(&models.Room{Hash: orphan, Name: "Orphan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	join := func(room, token string) int {
+		// The request is no websocket handshake, the call can't be joined even if the
+		// policy allows it
+		req := httptest.NewRequest(http.MethodGet, "/calls/join/"+room+"?token="+token, nil)
+		rec := httptest.NewRecorder()
+		r.echo.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := join(roomHash, alice); code == http.StatusForbidden || code == http.StatusNotFound {
+		t.Errorf("GET /calls/join/:room by alice = %d, want the policy to allow it", code)
+	}
+
+	for _, test := range []struct {
+		name, room, token string
+		want              int
+	}{
+		{"bob, who is no member", roomHash, bob, http.StatusForbidden},
+		{"bob in an unknown room", "01F8MECHZX3TBDSZ7XRADM79XV", bob, http.StatusNotFound},
+		{"bob in a room without server", orphan, bob, http.StatusNotFound},
+	} {
+		if code := join(test.room, test.token); code != test.want {
+			t.Errorf("GET /calls/join/:room by %s = %d, want %d", test.name, code, test.want)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/id"
+	"chapper.dev/server/internal/modules/jwt"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+	"chapper.dev/server/internal/utils"
+
+	j "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/guregu/null.v4"
+)
+
+const (
+	// accessTokenPrefix tells personal access tokens apart from JWTs and makes leaked
+	// tokens easy to find
+	accessTokenPrefix = "chp_"
+
+	// accessTokenBytes is the number of random bytes of a personal access token
+	accessTokenBytes = 32
+)
+
+// accessTokenRequest creates a personal access token. Tokens outlive sessions, so the
+// request requires the current password, or a 2FA code if the user uses 2FA
+type accessTokenRequest struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt null.Time `json:"expires_at"`
+	Bot       string    `json:"bot"`
+	Password  string    `json:"password"`
+	Code      string    `json:"code"`
+}
+
+// botRequest creates a bot. Like accessTokenRequest, it requires the current password
+// or a 2FA code
+type botRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// IsPersonalAccessToken returns if token looks like a personal access token instead of
+// a JWT
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, accessTokenPrefix)
+}
+
+// ParsePersonalAccessToken returns the personal access token input as token with
+// claims of type *jwt.Claims, like ParseAccessToken. The token needs scope, an empty
+// scope means that the route doesn't accept personal access tokens
+func (s AuthService) ParsePersonalAccessToken(input, scope string) (*j.Token, error) {
+	token, err := s.store.GetPersonalAccessToken(hashToken(input))
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, errors.ErrInvalidAccessToken
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetAccessToken
+	}
+
+	if token.IsExpired() {
+		return nil, errors.ErrInvalidAccessToken
+	}
+
+	if scope == "" || !token.Scopes.Contains(scope) {
+		return nil, errors.ErrInsufficientScope
+	}
+
+	// Tokens stop working with the user, bots stop working with their owner
+	account, err := s.store.GetUser(token.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	if account.Disabled {
+		return nil, errors.ErrUserDisabled
+	}
+
+	if account.Bot {
+		owner, err := s.store.GetUser(account.BotOwner.String)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return nil, errors.ErrGetUser
+		}
+
+		if owner.Disabled {
+			return nil, errors.ErrUserDisabled
+		}
+	}
+
+	privileges, err := s.users.GetPrivileges(token.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetRole
+	}
+
+	now := time.Now().UTC()
+	if !token.LastUsedAt.Valid || now.Sub(token.LastUsedAt.Time) > sessionTouchInterval {
+		err = s.store.TouchPersonalAccessToken(token.ID, now)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+		}
+	}
+
+	return &j.Token{
+		Raw:   input,
+		Valid: true,
+		Claims: &jwt.Claims{
+			Username:   token.Username,
+			TokenID:    token.ID,
+			Privileges: privileges,
+		},
+	}, nil
+}
+
+// CreatePersonalAccessToken creates a new personal access token of the user identified
+// by username or of one of the bots of the user. The plain token is only returned once.
+// The request body has to contain the password of the user or a valid 2FA code
+func (s AuthService) CreatePersonalAccessToken(username string, c echo.Context) (*models.PersonalAccessToken, string, error) {
+	var req accessTokenRequest
+
+	err := c.Bind(&req)
+	if err != nil || req.Name == "" || len(req.Name) > 100 || len(req.Scopes) == 0 {
+		return nil, "", errors.ErrMissingAccessTokenData
+	}
+
+	scopes := models.Scopes{}
+	for _, scope := range req.Scopes {
+		if !models.ValidScope(scope) {
+			return nil, "", errors.ErrInvalidScope
+		}
+
+		if !scopes.Contains(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	now := time.Now().UTC()
+	if req.ExpiresAt.Valid && !req.ExpiresAt.Time.After(now) {
+		return nil, "", errors.ErrMissingAccessTokenData
+	}
+
+	if req.Password == "" && req.Code == "" {
+		return nil, "", errors.ErrMissingPassword
+	}
+
+	_, err = s.confirmUser(username, req.Password, req.Code, c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	owner := username
+	if req.Bot != "" {
+		err = s.checkBotOwner(username, req.Bot)
+		if err != nil {
+			return nil, "", err
+		}
+		owner = req.Bot
+	}
+
+	tokenID, err := id.New()
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, "", errors.ErrCreateAccessToken
+	}
+
+	random, err := utils.RandomCryptoString(accessTokenBytes)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, "", errors.ErrCreateAccessToken
+	}
+	plain := accessTokenPrefix + random
+
+	token := &models.PersonalAccessToken{
+		ID:        tokenID,
+		Hash:      hashToken(plain),
+		Username:  owner,
+		Name:      req.Name,
+		Scopes:    scopes,
+		CreatedBy: username,
+		CreatedAt: now,
+	}
+	if req.ExpiresAt.Valid {
+		token.ExpiresAt = null.TimeFrom(req.ExpiresAt.Time.UTC())
+	}
+
+	err = s.store.CreatePersonalAccessToken(token)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, "", errors.ErrCreateAccessToken
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' created personal access token '%s' of '%s'", username, token.ID, owner))
+	return token, plain, nil
+}
+
+// GetPersonalAccessTokens returns the personal access tokens of the user identified by
+// username and of all bots of the user
+func (s AuthService) GetPersonalAccessTokens(username string) ([]models.PersonalAccessToken, error) {
+	owners, err := s.tokenOwners(username)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []models.PersonalAccessToken{}
+	for _, owner := range owners {
+		owned, err := s.store.GetPersonalAccessTokens(owner)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return nil, errors.ErrGetAccessToken
+		}
+		tokens = append(tokens, owned...)
+	}
+
+	return tokens, nil
+}
+
+// RevokePersonalAccessToken deletes the personal access token identified by id of the
+// user identified by username or of one of the bots of the user
+func (s AuthService) RevokePersonalAccessToken(username, id string) error {
+	owners, err := s.tokenOwners(username)
+	if err != nil {
+		return err
+	}
+
+	for _, owner := range owners {
+		err = s.store.DeletePersonalAccessToken(owner, id)
+		if err == store.ErrNotFound {
+			continue
+		}
+
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return errors.ErrDeleteAccessToken
+		}
+		return nil
+	}
+
+	return errors.ErrNoSuchAccessToken
+}
+
+// CreateBot creates a new bot owned by the user identified by username. Bots have no
+// password, they use the API with personal access tokens created by their owner. The
+// request body has to contain the password of the user or a valid 2FA code
+func (s AuthService) CreateBot(username string, c echo.Context) (models.User, error) {
+	var req botRequest
+
+	err := c.Bind(&req)
+	if err != nil || !models.ValidUsername(req.Username) {
+		return models.User{}, errors.ErrInvalidUsername
+	}
+
+	if req.Password == "" && req.Code == "" {
+		return models.User{}, errors.ErrMissingPassword
+	}
+
+	_, err = s.confirmUser(username, req.Password, req.Code, c)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	bots, err := s.GetBots(username)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if len(bots) >= s.config.General.MaxBots {
+		return models.User{}, errors.ErrBotLimit
+	}
+
+	_, err = s.store.GetUser(req.Username)
+	if err == nil {
+		return models.User{}, errors.ErrUsernameTaken
+	}
+
+	if err != store.ErrNotFound {
+		s.logger.Errorc(authCtx, err)
+		return models.User{}, errors.ErrGetUser
+	}
+
+	// The bot is created with its owner and role at once, so that a failure never
+	// leaves a user behind which isn't counted as bot
+	err = s.users.CreateAccount(&store.Account{
+		User:     models.PublicUser{Username: req.Username},
+		BotOwner: username,
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return models.User{}, err
+	}
+
+	bot, err := s.store.GetUser(req.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return models.User{}, errors.ErrGetUser
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' created bot '%s'", username, bot.Username))
+	return bot, nil
+}
+
+// revokePersonalAccessTokens deletes all personal access tokens of the user identified
+// by username and of all bots of the user
+func (s AuthService) revokePersonalAccessTokens(username string) error {
+	owners, err := s.tokenOwners(username)
+	if err != nil {
+		return err
+	}
+
+	for _, owner := range owners {
+		err = s.store.DeletePersonalAccessTokens(owner)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return errors.ErrDeleteAccessToken
+		}
+	}
+
+	return nil
+}
+
+// GetBots returns the bots of the user identified by username
+func (s AuthService) GetBots(username string) ([]models.User, error) {
+	bots, err := s.store.GetBots(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetBot
+	}
+	return bots, nil
+}
+
+// checkBotOwner returns an error unless the user identified by bot is a bot of the user
+// identified by username
+func (s AuthService) checkBotOwner(username, bot string) error {
+	account, err := s.store.GetUser(bot)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return errors.ErrNoSuchBot
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrGetUser
+	}
+
+	if !account.Bot || account.BotOwner.String != username {
+		return errors.ErrNoSuchBot
+	}
+	return nil
+}
+
+// tokenOwners returns the user identified by username and the bots of the user, whose
+// personal access tokens the user manages
+func (s AuthService) tokenOwners(username string) ([]string, error) {
+	bots, err := s.GetBots(username)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := []string{username}
+	for _, bot := range bots {
+		owners = append(owners, bot.Username)
+	}
+	return owners, nil
+}
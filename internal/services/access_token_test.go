@@ -0,0 +1,119 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"net/http"
+	"testing"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/services/errors"
+)
+
+var testScope = models.Scope(models.ResourceServers, http.MethodGet)
+
+// createAccessToken creates a personal access token with the scope to read servers.
+// The body adds the password, code or bot to the request
+func createAccessToken(t *testing.T, s AuthService, username string, body map[string]interface{}) (string, error) {
+	body["name"] = "Script"
+	body["scopes"] = []string{testScope}
+
+	_, plain, err := s.CreatePersonalAccessToken(username, newTestContext(t, body))
+	return plain, err
+}
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	s, _ := newTwoFATestService(t, nil)
+
+	for _, test := range []struct {
+		username string
+		body     map[string]interface{}
+		want     error
+	}{
+		{"alice", map[string]interface{}{}, errors.ErrMissingPassword},
+		{"alice", map[string]interface{}{"password": "wrong"}, errors.ErrInvalidPassword},
+		{"alice", map[string]interface{}{"code": "invalid"}, errors.ErrInvalidCode},
+		{"bob", map[string]interface{}{"code": "123456"}, errors.ErrInvalidCode},
+	} {
+		_, err := createAccessToken(t, s, test.username, test.body)
+		if err != test.want {
+			t.Errorf("CreatePersonalAccessToken() of %s with %v error = %v, want %v", test.username, test.body, err, test.want)
+		}
+	}
+
+	plain, err := createAccessToken(t, s, "alice", map[string]interface{}{"password": "alice-password"})
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken() with the password error = %v", err)
+	}
+
+	_, err = s.ParsePersonalAccessToken(plain, testScope)
+	if err != nil {
+		t.Errorf("ParsePersonalAccessToken() of the new token error = %v", err)
+	}
+}
+
+func TestCreateBot(t *testing.T) {
+	s, _ := newTwoFATestService(t, nil)
+
+	create := func(body map[string]string) error {
+		body["username"] = "bob-bot"
+		_, err := s.CreateBot("bob", newTestContext(t, body))
+		return err
+	}
+
+	err := create(map[string]string{})
+	if err != errors.ErrMissingPassword {
+		t.Errorf("CreateBot() without password error = %v, want %v", err, errors.ErrMissingPassword)
+	}
+
+	err = create(map[string]string{"password": "wrong"})
+	if err != errors.ErrInvalidPassword {
+		t.Errorf("CreateBot() with a wrong password error = %v, want %v", err, errors.ErrInvalidPassword)
+	}
+
+	err = create(map[string]string{"password": "bob-password"})
+	if err != nil {
+		t.Errorf("CreateBot() with the password error = %v", err)
+	}
+}
+
+func TestChangePasswordRevokesAccessTokens(t *testing.T) {
+	s, _ := newTwoFATestService(t, nil)
+
+	_, err := s.CreateBot("bob", newTestContext(t, map[string]string{
+		"username": "bob-bot",
+		"password": "bob-password",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tokens []string
+	for _, body := range []map[string]interface{}{
+		{"password": "bob-password"},
+		{"password": "bob-password", "bot": "bob-bot"},
+	} {
+		plain, err := createAccessToken(t, s, "bob", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, plain)
+	}
+
+	_, err = s.ChangePassword("bob", "", newTestContext(t, map[string]string{
+		"password":     "bob-password",
+		"new_password": "new-password",
+	}))
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+
+	for i, plain := range tokens {
+		_, err = s.ParsePersonalAccessToken(plain, testScope)
+		if err != errors.ErrInvalidAccessToken {
+			t.Errorf("ParsePersonalAccessToken() of token %d after the password changed error = %v, want %v", i, err, errors.ErrInvalidAccessToken)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"fmt"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+
+	"github.com/labstack/echo/v4"
+)
+
+// accountRequest changes one property of an account. Every change requires the current
+// password
+type accountRequest struct {
+	Password    string `json:"password"`
+	NewPassword string `json:"new_password"`
+	Email       string `json:"email"`
+	Username    string `json:"username"`
+}
+
+// ChangePassword changes the password of the user identified by username. All other
+// sessions and all personal access tokens of the user and of the bots of the user are
+// revoked, the caller receives new tokens for the session identified by sessionID
+func (s AuthService) ChangePassword(username, sessionID string, c echo.Context) (*Tokens, error) {
+	if s.config.General.DisablePasswordLogin {
+		return nil, errors.ErrPasswordLoginDisabled
+	}
+
+	req, account, err := s.bindAccountRequest(username, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.NewPassword == "" {
+		return nil, errors.ErrMissingUserData
+	}
+
+	hashed, err := s.HashPassword(req.NewPassword)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrHashPassword
+	}
+
+	// The confirmed password is no longer valid if it was changed concurrently
+	err = s.store.UpdatePassword(account.Username, account.Password, hashed)
+	if err == store.ErrNotFound {
+		return nil, errors.ErrInvalidPassword
+	}
+
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrUpdateUser
+	}
+
+	err = s.revokePersonalAccessTokens(account.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' changed the password", account.Username))
+	return s.reissueTokens(account.Username, sessionID)
+}
+
+// ChangeEmail changes the email address of the user identified by username. The new
+// address has to be verified again
+func (s AuthService) ChangeEmail(username string, c echo.Context) error {
+	req, account, err := s.bindAccountRequest(username, c)
+	if err != nil {
+		return err
+	}
+
+	if !models.ValidEmail(req.Email) {
+		return errors.ErrInvalidEmail
+	}
+
+	if req.Email == account.Email.String {
+		return nil
+	}
+
+	err = s.store.UpdateEmail(account.Username, req.Email)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrUpdateUser
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' changed the email address", account.Username))
+
+	// The change succeeded even if the mail can't be sent, the user can request a new
+	// one
+	s.sendVerification(account.Username, req.Email)
+	return nil
+}
+
+// ChangeUsername renames the user identified by username. Tokens carry the username,
+// so all other sessions of the user are revoked and the caller receives new tokens for
+// the session identified by sessionID
+func (s AuthService) ChangeUsername(username, sessionID string, c echo.Context) (*Tokens, error) {
+	req, account, err := s.bindAccountRequest(username, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if !models.ValidUsername(req.Username) {
+		return nil, errors.ErrInvalidUsername
+	}
+
+	if req.Username == account.Username {
+		return nil, errors.ErrUsernameTaken
+	}
+
+	err = s.users.RenameUser(account.Username, req.Username)
+	if err != nil {
+		if err != errors.ErrUsernameTaken {
+			s.logger.Errorc(authCtx, err)
+		}
+		return nil, err
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' was renamed to '%s'", account.Username, req.Username))
+	return s.reissueTokens(req.Username, sessionID)
+}
+
+// bindAccountRequest binds the request body and checks the current password of the
+// user identified by username. Wrong passwords count as failed login attempts
+func (s AuthService) bindAccountRequest(username string, c echo.Context) (*accountRequest, *models.User, error) {
+	var req accountRequest
+	err := c.Bind(&req)
+	if err != nil || req.Password == "" {
+		return nil, nil, errors.ErrMissingUserData
+	}
+
+	account, err := s.confirmUser(username, req.Password, "", c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &req, account, nil
+}
+
+// reissueTokens revokes all sessions of the user identified by username except the
+// session identified by sessionID and issues a new pair of tokens for it. The refresh
+// tokens which were issued for the session before are revoked as well
+func (s AuthService) reissueTokens(username, sessionID string) (*Tokens, error) {
+	err := s.RevokeSessions(username, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.RevokeRefreshTokens(sessionID)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrRevokeRefreshToken
+	}
+
+	tokens, refreshToken, err := s.newTokens(username, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.CreateRefreshToken(refreshToken)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrCreateRefreshToken
+	}
+
+	err = s.store.TouchSession(sessionID, refreshToken.CreatedAt, refreshToken.ExpiresAt)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+	}
+
+	return tokens, nil
+}
@@ -5,17 +5,24 @@
 package services
 
 import (
+	"fmt"
+
 	"chapper.dev/server/internal/config"
 	"chapper.dev/server/internal/log"
 	"chapper.dev/server/internal/models"
-	"chapper.dev/server/internal/modules/avatar"
 	"chapper.dev/server/internal/modules/hash"
 	"chapper.dev/server/internal/modules/jwt"
+	"chapper.dev/server/internal/modules/ldap"
+	"chapper.dev/server/internal/modules/limit"
+	"chapper.dev/server/internal/modules/mail"
+	"chapper.dev/server/internal/modules/oidc"
 	"chapper.dev/server/internal/modules/twofa"
+	"chapper.dev/server/internal/modules/webauthn"
 	"chapper.dev/server/internal/services/errors"
 	"chapper.dev/server/internal/store"
 	"chapper.dev/server/internal/utils"
 
+	j "github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo/v4"
 )
 
@@ -25,32 +32,117 @@ var (
 
 // AuthService wraps authentication dependencies
 type AuthService struct {
-	hash   hash.Hash
-	store  *store.Store
-	config *config.Config
-	logger *log.Logger
+	hash           hash.Hash
+	dummyHash      string
+	codes          hash.Hash
+	keys           *jwt.Keyring
+	store          store.Store
+	users          UserService
+	members        MemberService
+	webauthn       *webauthn.WebAuthn
+	oidc           *oidc.Provider
+	ldap           *ldap.Directory
+	authenticators []string
+	mailer         mail.Mailer
+	closers        []SessionCloser
+	accounts       *limit.Limiter
+	addresses      *limit.Limiter
+	config         *config.Config
+	logger         *log.Logger
 }
 
-// NewAuthService returns a new authentication service
-func NewAuthService(store *store.Store, config *config.Config, logger *log.Logger) AuthService {
+// NewAuthService returns a new authentication service. Access tokens are signed with
+// the keys of the keyring. The closers are notified when a session is revoked
+func NewAuthService(store store.Store, config *config.Config, keys *jwt.Keyring, logger *log.Logger, closers ...SessionCloser) AuthService {
+	// WebAuthn stays unavailable if the relying party is misconfigured
+	w, err := webauthn.New(config.General.Name, config.Router.WebAuthnRPID, config.Router.WebAuthnOrigin)
+	if err != nil {
+		logger.Errorc(authCtx, err)
+	}
+
+	// Single sign-on stays unavailable if it is not configured
+	var provider *oidc.Provider
+	if config.OIDC.Enabled() {
+		provider, err = oidc.New(config.OIDC.ProviderOptions(), nil)
+		if err != nil {
+			logger.Errorc(authCtx, err)
+		}
+	}
+
+	// The LDAP authenticator is skipped if the directory is not configured
+	var directory *ldap.Directory
+	if config.LDAP.Enabled() {
+		directory, err = ldap.New(config.LDAP.DirectoryOptions())
+		if err != nil {
+			logger.Errorc(authCtx, err)
+		}
+	}
+
+	// Mails are discarded if the mailer can't be created
+	mailer, err := mail.New(config.Mail.MailerOptions())
+	if err != nil {
+		logger.Errorc(authCtx, err)
+		mailer = mail.Noop{}
+	}
+
+	passwords := hash.NewChain(hash.NewArgon2WithConfig(config.Hash.Argon2Config()), hash.NewBcrypt(0), hash.NewScrypt())
+
+	// Passwords of unknown users are compared to a hash of no password, so that their
+	// logins take as long as those of known users
+	dummyHash, err := passwords.Hash("")
+	if err != nil {
+		logger.Errorc(authCtx, err)
+	}
+
 	return AuthService{
-		hash:   hash.NewArgon2(),
-		store:  store,
-		config: config,
-		logger: logger,
+		hash:           passwords,
+		dummyHash:      dummyHash,
+		codes:          hash.NewArgon2WithConfig(recoveryCodeHashConfig),
+		keys:           keys,
+		store:          store,
+		users:          NewUserService(store, config),
+		members:        NewMemberService(store, logger),
+		webauthn:       w,
+		oidc:           provider,
+		ldap:           directory,
+		authenticators: newAuthenticatorChain(config, directory),
+		mailer:         mailer,
+		closers:        closers,
+		accounts:       limit.New(config.Limit.AccountOptions()),
+		addresses:      limit.New(config.Limit.IPOptions()),
+		config:         config,
+		logger:         logger,
 	}
 }
 
-// Register handles the registration process of a new user
+// registerRequest is the request body of a registration. The invite is required if
+// registration is invite-only
+type registerRequest struct {
+	models.PublicUser
+	Invite string `json:"invite"`
+}
+
+// Register handles the registration process of a new user. If registration is
+// invite-only, the invite is used up and the user joins the server of the invite
 func (s AuthService) Register(c echo.Context) error {
-	var user models.PublicUser
+	if s.config.General.DisablePasswordLogin {
+		return errors.ErrPasswordLoginDisabled
+	}
+
+	mode := s.config.General.Registration
+	if mode == config.RegistrationClosed {
+		return errors.ErrRegistrationClosed
+	}
+
+	var req registerRequest
 
 	// Bind to signup user model
-	err := c.Bind(&user)
+	err := c.Bind(&req)
 	if err != nil {
 		s.logger.Errorc(authCtx, err)
 		return errors.ErrBindUser
 	}
+	user := req.PublicUser
 
 	// Check if some data is missing
 	if user.IsInvalid() {
@@ -58,6 +150,28 @@ func (s AuthService) Register(c echo.Context) error {
 		return errors.ErrMissingUserData
 	}
 
+	if user.Email != "" && !models.ValidEmail(user.Email) {
+		return errors.ErrInvalidEmail
+	}
+
+	// Guessing invite codes costs registration attempts
+	err = s.checkRegistration(c)
+	if err != nil {
+		return err
+	}
+
+	var (
+		invite *models.Invite
+		server *models.Server
+	)
+
+	if mode == config.RegistrationInvite {
+		invite, server, err = s.members.checkInvite(req.Invite)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Hash the password to save into the database
 	hashedPassword, err := s.HashPassword(user.Password)
 	if err != nil {
@@ -66,84 +180,106 @@ func (s AuthService) Register(c echo.Context) error {
 	}
 	user.Password = hashedPassword
 
-	// Insert new user into the database
-	err = s.store.CreateUser(user)
-	if err != nil {
-		s.logger.Errorc(authCtx, err)
-		return errors.ErrCreateUser
+	// The invite is used up before the user is created, so that a one time invite
+	// can't create two users
+	if invite != nil {
+		err = s.members.useInvite(invite)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Generate the default profile avatar based on the username
-	profileAvatar := avatar.New(240, user.Username)
-	err = profileAvatar.Generate(s.config.Router.AvatarPath)
+	// Insert new user into the database and generate the default profile avatar based
+	// on the username
+	err = s.users.CreateUser(user)
 	if err != nil {
 		s.logger.Errorc(authCtx, err)
-		return errors.ErrCreateAvatar
+		if invite != nil {
+			s.members.restoreInvite(invite)
+		}
+		return err
+	}
+
+	// The user exists now, so the registration succeeds even if joining fails
+	if server != nil {
+		err = s.members.addMember(server.Hash, user.Username, models.ServerMember().Name)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+		}
+	}
+
+	// The registration succeeded even if the mail can't be sent, the user can request
+	// a new one
+	if user.Email != "" {
+		s.sendVerification(user.Username, user.Email)
 	}
 
 	return nil
 }
 
-// Login handles the login process of a user
-func (s AuthService) Login(c echo.Context) (string, error) {
+// Login handles the login process of a user. The password is verified by the chain of
+// authenticators. Users with 2FA receive no tokens, but a challenge, which has to be
+// exchanged for tokens together with a valid 2FA code or a WebAuthn assertion
+func (s AuthService) Login(c echo.Context) (*Tokens, *Challenge, error) {
+	if len(s.authenticators) == 0 {
+		return nil, nil, errors.ErrPasswordLoginDisabled
+	}
+
 	var user models.PublicUser
 
 	// Bind to user model
 	err := c.Bind(&user)
 	if err != nil {
 		s.logger.Errorc(authCtx, err)
-		return "", errors.ErrBindUser
+		return nil, nil, errors.ErrBindUser
 	}
 
 	// Check if some data is missing
 	if user.IsLoginEmpty() {
 		s.logger.Infoc(authCtx, "some data to login/register is missing")
-		return "", errors.ErrMissingUserData
+		return nil, nil, errors.ErrMissingUserData
 	}
 
-	// Get the account from the database by username
-	account, err := s.store.GetUser(user.Username)
+	// Every attempt costs a password hash, so attempts are counted first
+	err = s.beginAttempt(user.Username, c)
 	if err != nil {
-		s.logger.Errorc(authCtx, err)
-		return "", errors.ErrGetUser
+		return nil, nil, err
 	}
 
-	// Check if the account uses 2FA
-	if account.UsesTwoFA() {
-		verifyToken, err := s.GenerateVerifyToken()
-		if err != nil {
-			s.logger.Errorc(authCtx, err)
-			return "", errors.ErrCreateVerifyToken
-		}
+	account, err := s.authenticate(user.Username, user.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.endAttempt(user.Username, c)
 
-		err = s.store.UpdateTwoFAVerify(account.Username, verifyToken)
-		if err != nil {
-			s.logger.Errorc(authCtx, err)
-			return "", errors.ErrUpdateVerifyToken
-		}
+	if account.Disabled {
+		return nil, nil, errors.ErrUserDisabled
+	}
 
-		return "", nil
+	if s.config.General.RequireVerifiedEmail && !account.EmailVerified {
+		return nil, nil, errors.ErrEmailNotVerified
 	}
 
-	// Compare the provided with the saved password
-	valid, err := s.ComparePassword(user.Password, account.Password)
-	if !valid || err != nil {
-		s.logger.Errorc(authCtx, err)
-		return "", errors.ErrInvalidPassword
+	// Check if the account uses a second factor
+	methods, err := s.secondFactors(&account)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Generate a new JWT token
-	token := s.NewJWT(s.config.Router.JWTSecret, &jwt.Claims{
-		Username: account.Username,
-	})
+	if len(methods) > 0 {
+		challenge, err := s.newChallenge(account.Username, models.ChallengePassword, "")
+		if err != nil {
+			return nil, nil, err
+		}
 
-	signedToken, err := token.Sign()
-	if err != nil {
-		s.logger.Errorc(authCtx, err)
-		return "", errors.ErrSignToken
+		return nil, &Challenge{
+			Challenge: challenge,
+			Methods:   methods,
+		}, nil
 	}
 
-	return signedToken, nil
+	tokens, err := s.issueTokens(account.Username, c)
+	return tokens, nil, err
 }
 
 // HashPassword returns the Argon2-hashed password or an error
@@ -161,27 +297,63 @@ func (s AuthService) ComparePassword(input, hashed string) (bool, error) {
 	return s.hash.Valid(input, hashed)
 }
 
-// NewJWT returns a new JWT
-func (s AuthService) NewJWT(secret string, c *jwt.Claims) *jwt.JWT {
-	return jwt.New(secret, c)
+// rehashPassword replaces the password hash of account with a new hash of password.
+// The login succeeds even if the hash can't be replaced. A password which was changed
+// since account was read is kept
+func (s AuthService) rehashPassword(account *models.User, password string) {
+	hashed, err := s.HashPassword(password)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return
+	}
+
+	err = s.store.UpdatePassword(account.Username, account.Password, hashed)
+	if err == store.ErrNotFound {
+		return
+	}
+
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return
+	}
+	account.Password = hashed
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("password hash of user '%s' was upgraded", account.Username))
+}
+
+// ParseAccessToken verifies the access token with the keyring and returns it. The
+// claims of the token are of type *jwt.Claims
+func (s AuthService) ParseAccessToken(input string) (*j.Token, error) {
+	if input == "" {
+		return nil, errors.ErrMissingAccessToken
+	}
+
+	token, err := s.keys.Parse(input)
+	if err != nil {
+		s.logger.Infoc(authCtx, fmt.Sprintf("invalid access token: %v", err))
+		return nil, errors.ErrInvalidAccessToken
+	}
+
+	return token, nil
 }
 
-// ParseJWT parses a JWT
-func (s AuthService) ParseJWT(input, key string, c jwt.Claims) (*jwt.JWT, error) {
-	return jwt.Parse(input, key, c)
+// JWKS returns the public keys which verify access tokens
+func (s AuthService) JWKS() jwt.JWKS {
+	return s.keys.JWKS()
 }
 
 // GenerateTOTP generates a new TOTP
-func (s AuthService) GenerateTOTP(issuer, account string) (twofa.TOTPKey, error) {
-	return twofa.GenerateTOTP(issuer, account)
+func (s AuthService) GenerateTOTP(issuer, account string, o twofa.Options) (twofa.TOTPKey, error) {
+	return twofa.GenerateTOTP(issuer, account, o)
 }
 
-// ValidateTOTP validates a 6 digit TOTP code
-func (s AuthService) ValidateTOTP(code, secret string) bool {
-	return twofa.ValidateTOTP(code, secret)
+// ValidateTOTP validates a TOTP code and returns the time step it belongs to
+func (s AuthService) ValidateTOTP(code, secret string, o twofa.Options) (uint64, bool) {
+	return twofa.ValidateTOTP(code, secret, o)
 }
 
-// GenerateVerifyToken generates a random verify token
+// GenerateVerifyToken generates a random verify token, which is used as 2FA login
+// challenge
 func (s AuthService) GenerateVerifyToken() (string, error) {
 	return utils.RandomCryptoString(16)
 }
@@ -0,0 +1,135 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"testing"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/hash"
+	"chapper.dev/server/internal/services/errors"
+)
+
+// countingHash counts the compared passwords
+type countingHash struct {
+	hash.Chain
+	compared *int
+}
+
+func (h countingHash) Valid(input, hashed string) (bool, error) {
+	*h.compared++
+	return h.Chain.Valid(input, hashed)
+}
+
+func TestLoginUnknownUser(t *testing.T) {
+	s, _ := newTwoFATestService(t, nil)
+
+	compared := 0
+	s.hash = countingHash{s.hash.(hash.Chain), &compared}
+
+	// Unknown users can't be told apart from wrong passwords
+	for _, username := range []string{"bob", "nobody"} {
+		compared = 0
+
+		_, _, err := s.Login(newTestContext(t, map[string]string{"username": username, "password": "wrong"}))
+		if err != errors.ErrInvalidPassword {
+			t.Errorf("Login() of %s error = %v, want %v", username, err, errors.ErrInvalidPassword)
+		}
+
+		if compared != 1 {
+			t.Errorf("Login() of %s compared %d passwords, want 1", username, compared)
+		}
+	}
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	s, m := newTestAuthService(t, nil)
+
+	// An imported bcrypt hash is replaced with an Argon2 hash at the next login
+	imported, err := hash.NewBcrypt(0).Hash("bob-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.users.CreateUser(models.PublicUser{Username: "bob", Password: imported})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = s.Login(newTestContext(t, map[string]string{"username": "bob", "password": "bob-password"}))
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	account, err := m.GetUser("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if account.Password == imported || s.hash.NeedsRehash(account.Password) {
+		t.Errorf("Login() kept the password hash %q, want a new hash", account.Password)
+	}
+}
+
+func TestRehashPasswordKeepsChangedPassword(t *testing.T) {
+	s, m := newTwoFATestService(t, nil)
+
+	stale, err := m.GetUser("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The password is changed while the old one is rehashed
+	changed, err := s.HashPassword("changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.UpdatePassword("bob", stale.Password, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.rehashPassword(&stale, "bob-password")
+
+	account, err := m.GetUser("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if account.Password != changed {
+		t.Error("rehashPassword() replaced the changed password")
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	s, m := newTwoFATestService(t, nil)
+
+	before, err := m.GetUser("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.ChangeEmail("bob", newTestContext(t, map[string]string{
+		"password": "bob-password",
+		"email":    "bob@chapper.test",
+	}))
+	if err != nil {
+		t.Fatalf("ChangeEmail() error = %v", err)
+	}
+
+	account, err := m.GetUser("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if account.Email.String != "bob@chapper.test" || account.EmailVerified {
+		t.Errorf("ChangeEmail() set email %q, verified %v, want an unverified bob@chapper.test", account.Email.String, account.EmailVerified)
+	}
+
+	if account.Password != before.Password {
+		t.Error("ChangeEmail() changed the password")
+	}
+}
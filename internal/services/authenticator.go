@@ -0,0 +1,102 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"fmt"
+	"net/http"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/ldap"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+)
+
+// errUnknownUser is returned by authenticators which don't know the user, the next
+// authenticator of the chain is asked instead
+var errUnknownUser = errors.New("unknown-user", "the user is unknown to the authenticator", http.StatusNotFound)
+
+// newAuthenticatorChain returns the configured authenticators in order. Local
+// passwords are skipped if password logins are disabled, the directory is skipped if
+// it is unavailable
+func newAuthenticatorChain(cfg *config.Config, directory *ldap.Directory) []string {
+	chain := []string{}
+	for _, authenticator := range cfg.General.Authenticators {
+		switch {
+		case authenticator == config.AuthenticatorLocal && cfg.General.DisablePasswordLogin:
+			continue
+		case authenticator == config.AuthenticatorLDAP && directory == nil:
+			continue
+		}
+
+		chain = append(chain, authenticator)
+	}
+	return chain
+}
+
+// authenticate verifies the password of the user identified by username with the
+// authenticators of the chain in order. The first authenticator which knows the user
+// decides, later authenticators are not asked. Unknown users fail like wrong passwords
+// and cost a password hash as well, so that neither the response nor its timing reveals
+// which users exist
+func (s AuthService) authenticate(username, password string) (models.User, error) {
+	for _, authenticator := range s.authenticators {
+		var (
+			account models.User
+			err     error
+		)
+
+		switch authenticator {
+		case config.AuthenticatorLocal:
+			account, err = s.authenticateLocal(username, password)
+		case config.AuthenticatorLDAP:
+			account, err = s.authenticateLDAP(username, password)
+		}
+
+		if err == errUnknownUser {
+			continue
+		}
+		return account, err
+	}
+
+	s.ComparePassword(password, s.dummyHash)
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("login of unknown user '%s'", username))
+	return models.User{}, errors.ErrInvalidPassword
+}
+
+// authenticateLocal compares the password with the password hash of the user. Users
+// without password hash, like users of the identity provider or the directory, are
+// unknown to this authenticator
+func (s AuthService) authenticateLocal(username, password string) (models.User, error) {
+	account, err := s.store.GetUser(username)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return models.User{}, errUnknownUser
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return models.User{}, errors.ErrGetUser
+	}
+
+	if account.Password == "" {
+		return models.User{}, errUnknownUser
+	}
+
+	valid, err := s.ComparePassword(password, account.Password)
+	if !valid || err != nil {
+		s.logger.Errorc(authCtx, err)
+		return models.User{}, errors.ErrInvalidPassword
+	}
+
+	// Hashes of imported users or with outdated parameters are replaced, only now the
+	// plain password is known
+	if s.hash.NeedsRehash(account.Password) {
+		s.rehashPassword(&account, password)
+	}
+
+	return account, nil
+}
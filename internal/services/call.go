@@ -20,6 +20,12 @@ func NewCallService() CallService {
 	}
 }
 
-func (s CallService) NewCall(username, roomHash string, w http.ResponseWriter, r *http.Request) error {
-	return s.bridge.Connect(username, roomHash, w, r)
+func (s CallService) NewCall(username, sessionID, roomHash string, w http.ResponseWriter, r *http.Request) error {
+	return s.bridge.Connect(username, sessionID, roomHash, w, r)
+}
+
+// CloseSession disconnects all calls which were joined with the login session
+// identified by id
+func (s CallService) CloseSession(id string) {
+	s.bridge.CloseSession(id)
 }
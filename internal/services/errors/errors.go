@@ -17,31 +17,138 @@ var (
 	// ErrUpdateVerifyToken indicates the service failed to update the 2FA verify token
 	ErrUpdateVerifyToken = New("update-verify-token", "failed to update 2fa verify token", http.StatusInternalServerError)
 
+	ErrMissingCode       = New("missing-code", "2fa code or challenge missing", http.StatusBadRequest)
+	ErrInvalidCode       = New("invalid-code", "the 2fa code is invalid", http.StatusUnauthorized)
+	ErrInvalidChallenge  = New("invalid-challenge", "the login challenge is invalid or expired", http.StatusUnauthorized)
+	ErrGenerateTOTP      = New("generate-totp", "failed to generate totp secret", http.StatusInternalServerError)
+	ErrTwoFAEnabled      = New("twofa-enabled", "2fa is already enabled", http.StatusConflict)
+	ErrNoTwoFAEnrollment = New("no-twofa-enrollment", "2fa enrollment was not started", http.StatusBadRequest)
+	ErrTwoFADisabled     = New("twofa-disabled", "2fa is not enabled", http.StatusBadRequest)
+
+	ErrUpdateRecoveryCodes = New("update-recovery-codes", "failed to update recovery codes", http.StatusInternalServerError)
+
+	ErrWebAuthnUnavailable      = New("webauthn-unavailable", "webauthn is not configured", http.StatusServiceUnavailable)
+	ErrWebAuthn                 = New("webauthn", "failed to start webauthn ceremony", http.StatusInternalServerError)
+	ErrMissingWebAuthnData      = New("missing-webauthn-data", "data missing to complete webauthn ceremony", http.StatusBadRequest)
+	ErrInvalidWebAuthnResponse  = New("invalid-webauthn-response", "the authenticator response is invalid", http.StatusUnauthorized)
+	ErrNoSuchWebAuthnCredential = New("no-such-webauthn-credential", "the webauthn credential does not exist", http.StatusNotFound)
+	ErrCreateWebAuthnCredential = New("create-webauthn-credential", "failed to create webauthn credential", http.StatusInternalServerError)
+	ErrGetWebAuthnCredential    = New("get-webauthn-credential", "failed to get webauthn credentials", http.StatusInternalServerError)
+	ErrDeleteWebAuthnCredential = New("delete-webauthn-credential", "failed to delete webauthn credential", http.StatusInternalServerError)
+
+	ErrOIDCUnavailable     = New("oidc-unavailable", "single sign-on is not configured", http.StatusServiceUnavailable)
+	ErrOIDC                = New("oidc", "failed to reach the identity provider", http.StatusBadGateway)
+	ErrMissingOIDCData     = New("missing-oidc-data", "code or state missing to complete single sign-on", http.StatusBadRequest)
+	ErrInvalidOIDCResponse = New("invalid-oidc-response", "the identity provider response is invalid", http.StatusUnauthorized)
+	ErrGetIdentity         = New("get-identity", "failed to get identity", http.StatusInternalServerError)
+
+	ErrLDAP = New("ldap", "failed to reach the ldap directory", http.StatusBadGateway)
+
+	ErrPasswordLoginDisabled = New("password-login-disabled", "password login is disabled, use single sign-on", http.StatusForbidden)
+	ErrUserDisabled          = New("user-disabled", "the user is disabled", http.StatusForbidden)
+	ErrRegistrationClosed    = New("registration-closed", "registration is closed", http.StatusForbidden)
+
 	// ErrInvalidPassword indicates
 	ErrInvalidPassword = New("invalid-password", "the user provided an invalid password", http.StatusUnauthorized)
+	ErrMissingPassword = New("missing-password", "the current password or a 2fa code is required", http.StatusBadRequest)
+	ErrTooManyAttempts = New("too-many-attempts", "too many failed attempts, try again later", http.StatusTooManyRequests)
 	ErrHashPassword    = New("hash-password", "failed to hash password", http.StatusInternalServerError)
 	ErrSignToken       = New("sign-token", "failed to sign jwt token", http.StatusInternalServerError)
 
+	ErrMissingAccessToken = New("missing-access-token", "access token missing or malformed", http.StatusBadRequest)
+	ErrInvalidAccessToken = New("invalid-access-token", "the access token is invalid or expired", http.StatusUnauthorized)
+
+	ErrMissingRefreshToken = New("missing-refresh-token", "refresh token missing", http.StatusBadRequest)
+	ErrInvalidRefreshToken = New("invalid-refresh-token", "the refresh token is invalid or expired", http.StatusUnauthorized)
+	ErrRefreshTokenReused  = New("refresh-token-reused", "the refresh token was already used, all tokens of this login were revoked", http.StatusUnauthorized)
+	ErrCreateRefreshToken  = New("create-refresh-token", "failed to create refresh token", http.StatusInternalServerError)
+	ErrGetRefreshToken     = New("get-refresh-token", "failed to get refresh token", http.StatusInternalServerError)
+	ErrRevokeRefreshToken  = New("revoke-refresh-token", "failed to revoke refresh token", http.StatusInternalServerError)
+
+	ErrInvalidSession = New("invalid-session", "the session was revoked or expired", http.StatusUnauthorized)
+	ErrNoSuchSession  = New("no-such-session", "the session does not exist", http.StatusNotFound)
+	ErrCreateSession  = New("create-session", "failed to create session", http.StatusInternalServerError)
+	ErrGetSession     = New("get-session", "failed to get sessions", http.StatusInternalServerError)
+	ErrDeleteSession  = New("delete-session", "failed to revoke session", http.StatusInternalServerError)
+
+	ErrMissingAccessTokenData = New("missing-access-token-data", "data missing to create personal access token", http.StatusBadRequest)
+	ErrInvalidScope           = New("invalid-scope", "the scope is unknown", http.StatusBadRequest)
+	ErrInsufficientScope      = New("insufficient-scope", "the personal access token lacks the scope to access this route", http.StatusForbidden)
+	ErrNoSuchAccessToken      = New("no-such-access-token", "the personal access token does not exist", http.StatusNotFound)
+	ErrCreateAccessToken      = New("create-access-token", "failed to create personal access token", http.StatusInternalServerError)
+	ErrGetAccessToken         = New("get-access-token", "failed to get personal access tokens", http.StatusInternalServerError)
+	ErrDeleteAccessToken      = New("delete-access-token", "failed to delete personal access token", http.StatusInternalServerError)
+	ErrNoSuchBot              = New("no-such-bot", "the bot does not exist or belongs to another user", http.StatusNotFound)
+	ErrGetBot                 = New("get-bot", "failed to get bots", http.StatusInternalServerError)
+	ErrBotLimit               = New("bot-limit", "the user can't create more bots", http.StatusForbidden)
+
+	ErrMissingMailToken = New("missing-mail-token", "mail token missing", http.StatusBadRequest)
+	ErrInvalidMailToken = New("invalid-mail-token", "the mail token is invalid, expired or already used", http.StatusBadRequest)
+	ErrCreateMailToken  = New("create-mail-token", "failed to create mail token", http.StatusInternalServerError)
+	ErrSendMail         = New("send-mail", "failed to send mail", http.StatusInternalServerError)
+	ErrMissingEmail     = New("missing-email", "the user has no email address", http.StatusBadRequest)
+	ErrInvalidEmail     = New("invalid-email", "the email address is invalid", http.StatusBadRequest)
+	ErrEmailVerified    = New("email-verified", "the email address is already verified", http.StatusConflict)
+	ErrEmailNotVerified = New("email-not-verified", "the email address has to be verified before login", http.StatusForbidden)
+
 	ErrMissingUserData = New("missing-user-data", "data missing to login or register", http.StatusBadRequest)
 	ErrBindUser        = New("bind-user", "failed to bind to user model", http.StatusInternalServerError)
 	ErrCreateUser      = New("create-user", "failed to create user", http.StatusInternalServerError)
 	ErrGetUser         = New("get-user", "failed to get user", http.StatusInternalServerError)
+	ErrUpdateUser      = New("update-user", "failed to update user", http.StatusInternalServerError)
+	ErrInvalidUsername = New("invalid-username", "the username is empty, too long or contains invalid characters", http.StatusBadRequest)
+	ErrUsernameTaken   = New("username-taken", "the username is already taken", http.StatusConflict)
+
+	ErrGetSettings    = New("get-settings", "failed to get settings", http.StatusInternalServerError)
+	ErrUpdateSettings = New("update-settings", "failed to update settings", http.StatusInternalServerError)
 
 	ErrMissingInviteData = New("missing-invite-data", "data missing to create invite", http.StatusBadRequest)
 	ErrBindInvite        = New("bind-invite", "failed to bind to invite model", http.StatusInternalServerError)
 	ErrCreateInvite      = New("create-invite", "failed to create invite", http.StatusInternalServerError)
+	ErrGetInvite         = New("get-invite", "failed to get invite", http.StatusInternalServerError)
+	ErrDeleteInvite      = New("delete-invite", "failed to delete invite", http.StatusInternalServerError)
+	ErrMissingInviteCode = New("missing-invite-code", "invite code missing to join server", http.StatusBadRequest)
+	ErrNoSuchInvite      = New("no-such-invite", "the invite does not exist", http.StatusNotFound)
+	ErrInviteExpired     = New("invite-expired", "the invite is expired", http.StatusGone)
 
 	ErrBindRoom        = New("bind-room", "failed to bind to room model", http.StatusInternalServerError)
 	ErrMissingRoomData = New("missing-room-data", "data missing to create room", http.StatusBadRequest)
 	ErrCreateRoom      = New("create-room", "failed to create room", http.StatusInternalServerError)
 	ErrUpdateRoom      = New("update-room", "failed to update room", http.StatusInternalServerError)
+	ErrGetRoom         = New("get-room", "failed to get room", http.StatusInternalServerError)
+	ErrDeleteRoom      = New("delete-room", "failed to delete room", http.StatusInternalServerError)
+	ErrNoSuchRoom      = New("no-such-room", "the room does not exist", http.StatusNotFound)
 
 	ErrBindServer        = New("bind-server", "failed to bind to server model", http.StatusInternalServerError)
 	ErrMissingServerData = New("missing-server-data", "data missing to create server", http.StatusBadRequest)
 	ErrCreateServer      = New("create-server", "failed to create server", http.StatusInternalServerError)
+	ErrGetServer         = New("get-server", "failed to get server", http.StatusInternalServerError)
+	ErrDeleteServer      = New("delete-server", "failed to delete server", http.StatusInternalServerError)
+	ErrNoSuchServer      = New("no-such-server", "the server does not exist", http.StatusNotFound)
+
+	ErrAddMember     = New("add-member", "failed to add member", http.StatusInternalServerError)
+	ErrGetMember     = New("get-member", "failed to get member", http.StatusInternalServerError)
+	ErrRemoveMember  = New("remove-member", "failed to remove member", http.StatusInternalServerError)
+	ErrNoSuchMember  = New("no-such-member", "the user is not a member of the server", http.StatusNotFound)
+	ErrAlreadyMember = New("already-member", "the user is already a member of the server", http.StatusConflict)
+
+	ErrBindRole               = New("bind-role", "failed to bind to role model", http.StatusInternalServerError)
+	ErrMissingRoleData        = New("missing-role-data", "data missing to create role", http.StatusBadRequest)
+	ErrCreateRole             = New("create-role", "failed to create role", http.StatusInternalServerError)
+	ErrGetRole                = New("get-role", "failed to get role", http.StatusInternalServerError)
+	ErrUpdateRole             = New("update-role", "failed to update role", http.StatusInternalServerError)
+	ErrDeleteRole             = New("delete-role", "failed to delete role", http.StatusInternalServerError)
+	ErrAssignRole             = New("assign-role", "failed to assign role", http.StatusInternalServerError)
+	ErrRemoveRole             = New("remove-role", "failed to remove role", http.StatusInternalServerError)
+	ErrNoSuchRole             = New("no-such-role", "the role does not exist", http.StatusNotFound)
+	ErrBuiltinRole            = New("builtin-role", "built-in roles cannot be changed or deleted", http.StatusForbidden)
+	ErrForbidden              = New("forbidden", "missing privileges to perform this action", http.StatusForbidden)
+	ErrInsufficientPrivileges = New("insufficient-privileges", "cannot grant or remove privileges the user does not have", http.StatusForbidden)
+	ErrLastSuperadmin         = New("last-superadmin", "the role of the last superadmin cannot be removed", http.StatusConflict)
 
 	ErrCreateAvatar = New("create-avatar", "failed to create avatar", http.StatusInternalServerError)
 	ErrInvalidHash  = New("invalid-hash", "invalid or empty hash", http.StatusBadRequest)
+	ErrInvalidID    = New("invalid-id", "invalid or empty id", http.StatusBadRequest)
 )
 
 // ServiceError is a custom error returned form the service layer
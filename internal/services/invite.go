@@ -10,7 +10,7 @@ import (
 	"chapper.dev/server/internal/config"
 	"chapper.dev/server/internal/log"
 	"chapper.dev/server/internal/models"
-	"chapper.dev/server/internal/modules/hash"
+	"chapper.dev/server/internal/modules/id"
 	"chapper.dev/server/internal/services/errors"
 	"chapper.dev/server/internal/store"
 	"chapper.dev/server/internal/utils"
@@ -28,23 +28,26 @@ var (
 
 // InviteService provides a service to create, get and delete invites
 type InviteService struct {
-	store  *store.Store
-	config *config.Config
-	logger *log.Logger
+	store   store.Store
+	config  *config.Config
+	logger  *log.Logger
+	members MemberService
 }
 
 // NewInviteService returns a new invite service
-func NewInviteService(store *store.Store, config *config.Config, logger *log.Logger) InviteService {
+func NewInviteService(store store.Store, config *config.Config, logger *log.Logger) InviteService {
 	return InviteService{
-		store:  store,
-		config: config,
-		logger: logger,
+		store:   store,
+		config:  config,
+		logger:  logger,
+		members: NewMemberService(store, logger),
 	}
 }
 
-// CreateInvite creates a new invite link
+// CreateInvite creates a new invite link. The user needs the privilege to create
+// invites in the virtual server of the invite
 func (s InviteService) CreateInvite(username string, c echo.Context) (*models.Invite, error) {
-	var invite *models.Invite
+	var invite = new(models.Invite)
 
 	// Bind to invite model
 	err := c.Bind(invite)
@@ -59,12 +62,35 @@ func (s InviteService) CreateInvite(username string, c echo.Context) (*models.In
 		return nil, errors.ErrMissingInviteData
 	}
 
+	_, err = s.store.GetServer(invite.Server)
+	if err == store.ErrNotFound {
+		return nil, errors.ErrNoSuchServer
+	}
+
+	if err != nil {
+		s.logger.Errorc(inviteCtx, err)
+		return nil, errors.ErrGetServer
+	}
+
+	privileges, _, err := s.members.GetPrivileges(username, invite.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	if !privileges.CanCreateInvite {
+		return nil, errors.ErrForbidden
+	}
+
 	// Get expire time and set invite values
 	expireTime := time.Now().Add(DefaultExpireTimespan)
 
 	invite.CreatedBy = username
 	invite.ExpiresAt = utils.ToNullTime(expireTime)
-	invite.Hash = hash.Adler32(invite.Server + expireTime.String())
+	invite.Hash, err = id.InviteCode()
+	if err != nil {
+		s.logger.Errorc(inviteCtx, err)
+		return nil, errors.ErrCreateInvite
+	}
 
 	// Finally store the invite to the database
 	err = s.store.CreateInvite(invite)
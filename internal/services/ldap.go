@@ -0,0 +1,224 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/ldap"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+)
+
+// authenticateLDAP binds as the user at the directory. Users are created on their first
+// login and their roles are synced with their groups on every login
+func (s AuthService) authenticateLDAP(username, password string) (models.User, error) {
+	entry, err := s.ldap.Authenticate(username, password)
+	switch err {
+	case nil:
+	case ldap.ErrUserNotFound:
+		return models.User{}, errUnknownUser
+	case ldap.ErrInvalidCredentials:
+		return models.User{}, errors.ErrInvalidPassword
+	default:
+		s.logger.Errorc(authCtx, err)
+		return models.User{}, errors.ErrLDAP
+	}
+
+	name, err := s.ldapUser(entry)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	account, err := s.store.GetUser(name)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return models.User{}, errors.ErrGetUser
+	}
+
+	// The directory just accepted the user, so a user disabled by an earlier sync was
+	// added back in the meantime
+	if account.Disabled {
+		err = s.store.UpdateUserDisabled(account.Username, false)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return models.User{}, errors.ErrUpdateUser
+		}
+
+		account.Disabled = false
+		s.logger.Infoc(authCtx, fmt.Sprintf("enabled user '%s', who returned to the directory", account.Username))
+	}
+
+	// The roles have to be synced before the privileges are added to the tokens
+	err = s.syncRoles(account.Username, s.config.LDAP.RoleMapping, entry.GroupNames())
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return account, nil
+}
+
+// ldapUser returns the username of the user linked to the entry. A new user is created
+// if the entry is unknown. Existing users are never linked, like with single sign-on
+func (s AuthService) ldapUser(entry *ldap.Entry) (string, error) {
+	identity, err := s.store.GetIdentity(s.ldap.URL(), entry.Username)
+	if err == nil {
+		return identity.Username, nil
+	}
+
+	if err != store.ErrNotFound {
+		s.logger.Errorc(authCtx, err)
+		return "", errors.ErrGetIdentity
+	}
+
+	if !models.ValidUsername(entry.Username) {
+		s.logger.Infoc(authCtx, fmt.Sprintf("ldap entry '%s' has the invalid username '%s'", entry.DN, entry.Username))
+		return "", errors.ErrInvalidUsername
+	}
+
+	_, err = s.store.GetUser(entry.Username)
+	if err == nil {
+		s.logger.Infoc(authCtx, fmt.Sprintf("ldap entry '%s' has the username of an existing user", entry.DN))
+		return "", errors.ErrUsernameTaken
+	}
+
+	if err != store.ErrNotFound {
+		s.logger.Errorc(authCtx, err)
+		return "", errors.ErrGetUser
+	}
+
+	email := entry.Email
+	if !models.ValidEmail(email) {
+		email = ""
+	}
+
+	// Users of the directory have no password, the local authenticator skips them. The
+	// user is created together with the identity, like with single sign-on
+	err = s.users.CreateAccount(&store.Account{
+		User: models.PublicUser{
+			Username: entry.Username,
+			Email:    email,
+		},
+		Identity: &models.Identity{
+			Issuer:    s.ldap.URL(),
+			Subject:   entry.Username,
+			Username:  entry.Username,
+			CreatedAt: time.Now().UTC(),
+		},
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return "", err
+	}
+
+	// The directory is trusted to manage the email addresses of its users
+	if email != "" {
+		err = s.store.UpdateEmailVerified(entry.Username, email)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+		}
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("created user '%s' for ldap entry '%s'", entry.Username, entry.DN))
+	return entry.Username, nil
+}
+
+// RunLDAPSync syncs the users of the directory every SYNC_INTERVAL until ctx is done.
+// It returns immediately if the directory or the sync is disabled
+func (s AuthService) RunLDAPSync(ctx context.Context) {
+	interval := s.config.LDAP.SyncInterval.Duration
+	if s.ldap == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.SyncLDAP()
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncLDAP disables the users of the directory which were removed or no longer match
+// the user filter and revokes their sessions. Users who returned are enabled again.
+// The roles of all other users are synced with their groups
+func (s AuthService) SyncLDAP() error {
+	if s.ldap == nil {
+		return nil
+	}
+
+	entries, err := s.ldap.Users()
+	if err != nil {
+		return fmt.Errorf("ldap sync failed: %w", err)
+	}
+
+	active := make(map[string]ldap.Entry, len(entries))
+	for _, entry := range entries {
+		active[entry.Username] = entry
+	}
+
+	identities, err := s.store.GetIdentities(s.ldap.URL())
+	if err != nil {
+		return err
+	}
+
+	disabled, enabled := 0, 0
+	for _, identity := range identities {
+		account, err := s.store.GetUser(identity.Username)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			continue
+		}
+
+		entry, ok := active[identity.Subject]
+		if !ok {
+			if account.Disabled {
+				continue
+			}
+
+			err = s.store.UpdateUserDisabled(account.Username, true)
+			if err != nil {
+				s.logger.Errorc(authCtx, err)
+				continue
+			}
+			disabled++
+
+			err = s.RevokeSessions(account.Username, "")
+			if err != nil {
+				s.logger.Errorc(authCtx, err)
+			}
+			continue
+		}
+
+		if account.Disabled {
+			err = s.store.UpdateUserDisabled(account.Username, false)
+			if err != nil {
+				s.logger.Errorc(authCtx, err)
+				continue
+			}
+			enabled++
+		}
+
+		err = s.syncRoles(account.Username, s.config.LDAP.RoleMapping, entry.GroupNames())
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+		}
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("ldap sync checked %d users, disabled %d and enabled %d", len(identities), disabled, enabled))
+	return nil
+}
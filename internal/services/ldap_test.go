@@ -0,0 +1,250 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"testing"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/ldap/ldaptest"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store/memory"
+)
+
+const testLDAPBaseDN = "dc=chapper,dc=test"
+
+func testLDAPPerson(uid string, groups ...string) ldaptest.Entry {
+	return ldaptest.Entry{
+		DN:       "uid=" + uid + ",ou=people," + testLDAPBaseDN,
+		Password: uid + "-secret",
+		Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {uid},
+			"mail":        {uid + "@chapper.test"},
+			"memberOf":    groups,
+		},
+	}
+}
+
+// newLDAPTestService returns a service which authenticates at the directory. The group
+// 'moderators' is mapped to the role 'Mod'
+func newLDAPTestService(t *testing.T, entries ...ldaptest.Entry) (AuthService, *memory.Store, *ldaptest.Server) {
+	server, err := ldaptest.NewServer(entries...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	s, m := newTestAuthService(t, func(c *config.Config) {
+		c.LDAP = config.LDAPOptions{
+			URL:            server.URL,
+			BaseDN:         testLDAPBaseDN,
+			UserFilter:     "(objectClass=person)",
+			EmailAttribute: "mail",
+			GroupAttribute: "memberOf",
+			RoleMapping:    map[string]string{"moderators": "Mod"},
+		}
+	})
+
+	err = m.CreateRole(&models.Role{Name: "Mod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, m, server
+}
+
+func loginLDAP(t *testing.T, s AuthService, username string) (*Tokens, error) {
+	tokens, _, err := s.Login(newTestContext(t, map[string]string{
+		"username": username,
+		"password": username + "-secret",
+	}))
+	return tokens, err
+}
+
+func TestLoginLDAP(t *testing.T) {
+	s, m, server := newLDAPTestService(t,
+		testLDAPPerson("alice", "cn=moderators,ou=groups,"+testLDAPBaseDN),
+	)
+
+	_, err := loginLDAP(t, s, "alice")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	user, err := m.GetUser("alice")
+	if err != nil {
+		t.Fatalf("user was not created: %v", err)
+	}
+
+	if user.Password != "" {
+		t.Error("user of the directory has a password")
+	}
+
+	if user.Email.String != "alice@chapper.test" || !user.EmailVerified {
+		t.Errorf("email = %q, verified = %v, want verified alice@chapper.test", user.Email.String, user.EmailVerified)
+	}
+
+	if !hasRole(t, m, "alice", "Mod") {
+		t.Error("group was not mapped to role Mod")
+	}
+
+	_, _, err = s.Login(newTestContext(t, map[string]string{
+		"username": "alice",
+		"password": "wrong",
+	}))
+	if err != errors.ErrInvalidPassword {
+		t.Errorf("Login() with wrong password error = %v, want %v", err, errors.ErrInvalidPassword)
+	}
+
+	// Roles follow the groups with every login
+	server.SetEntries(testLDAPPerson("alice"))
+
+	_, err = loginLDAP(t, s, "alice")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if hasRole(t, m, "alice", "Mod") {
+		t.Error("role Mod was kept after alice left the group")
+	}
+}
+
+func TestLoginLDAPExistingUsername(t *testing.T) {
+	s, m, _ := newLDAPTestService(t, testLDAPPerson("alice"))
+
+	// The local user is asked first and rejects the password of the directory
+	err := s.users.CreateUser(models.PublicUser{Username: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = loginLDAP(t, s, "alice")
+	if err != errors.ErrInvalidPassword {
+		t.Fatalf("Login() error = %v, want %v", err, errors.ErrInvalidPassword)
+	}
+
+	identities, err := m.GetIdentities(s.ldap.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(identities) != 0 {
+		t.Errorf("entry was linked to the existing user: %v", identities)
+	}
+}
+
+func TestSyncLDAP(t *testing.T) {
+	s, m, server := newLDAPTestService(t, testLDAPPerson("alice"), testLDAPPerson("bob"))
+
+	for _, username := range []string{"alice", "bob"} {
+		_, err := loginLDAP(t, s, username)
+		if err != nil {
+			t.Fatalf("Login() of %s error = %v", username, err)
+		}
+	}
+
+	// alice was removed, bob stopped matching the user filter and joined a group
+	bob := testLDAPPerson("bob", "cn=moderators,ou=groups,"+testLDAPBaseDN)
+	bob.Attributes["objectClass"] = []string{"inetOrgPerson"}
+	server.SetEntries(bob)
+
+	err := s.SyncLDAP()
+	if err != nil {
+		t.Fatalf("SyncLDAP() error = %v", err)
+	}
+
+	for _, username := range []string{"alice", "bob"} {
+		user, err := m.GetUser(username)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !user.Disabled {
+			t.Errorf("%s was not disabled", username)
+		}
+
+		sessions, err := m.GetSessions(username)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sessions) != 0 {
+			t.Errorf("sessions of %s were not revoked", username)
+		}
+	}
+
+	if hasRole(t, m, "bob", "Mod") {
+		t.Error("disabled bob received role Mod")
+	}
+
+	// Both return, bob with the group
+	bob.Attributes["objectClass"] = []string{"person"}
+	server.SetEntries(testLDAPPerson("alice"), bob)
+
+	err = s.SyncLDAP()
+	if err != nil {
+		t.Fatalf("SyncLDAP() error = %v", err)
+	}
+
+	for _, username := range []string{"alice", "bob"} {
+		user, err := m.GetUser(username)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if user.Disabled {
+			t.Errorf("%s was not enabled again", username)
+		}
+	}
+
+	if !hasRole(t, m, "bob", "Mod") {
+		t.Error("group of bob was not mapped to role Mod")
+	}
+
+	_, err = loginLDAP(t, s, "alice")
+	if err != nil {
+		t.Errorf("Login() of enabled alice error = %v", err)
+	}
+}
+
+func TestLoginLDAPReturnedUser(t *testing.T) {
+	s, m, server := newLDAPTestService(t, testLDAPPerson("alice"))
+
+	_, err := loginLDAP(t, s, "alice")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	server.SetEntries()
+
+	err = s.SyncLDAP()
+	if err != nil {
+		t.Fatalf("SyncLDAP() error = %v", err)
+	}
+
+	_, err = loginLDAP(t, s, "alice")
+	if err == nil {
+		t.Fatal("Login() of removed alice succeeded")
+	}
+
+	// A login after the return enables alice before the next sync
+	server.SetEntries(testLDAPPerson("alice"))
+
+	_, err = loginLDAP(t, s, "alice")
+	if err != nil {
+		t.Fatalf("Login() of returned alice error = %v", err)
+	}
+
+	user, err := m.GetUser("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Disabled {
+		t.Error("alice is still disabled")
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"chapper.dev/server/internal/services/errors"
+
+	"github.com/labstack/echo/v4"
+)
+
+// registerKeyPrefix separates the registrations of an IP address from its failed
+// logins
+const registerKeyPrefix = "register:"
+
+// beginAttempt counts an attempt of the user identified by username and of the client
+// which sent the request as failed before the credentials are checked, so that
+// concurrent requests can't try more credentials than the limit allows. An empty
+// username only counts the client. It returns ErrTooManyAttempts if the user or the
+// client has to wait before the next attempt, the time to wait is sent in the
+// Retry-After header. Successful attempts are forgotten again with endAttempt
+func (s AuthService) beginAttempt(username string, c echo.Context) error {
+	ip := c.RealIP()
+
+	wait, ok := s.addresses.Attempt(ip)
+	if !ok {
+		return s.tooManyAttempts(wait, c)
+	}
+
+	if wait >= s.config.Limit.Lockout.Duration {
+		s.logger.Infoc(authCtx, fmt.Sprintf("too many failed attempts from '%s', locked out", ip))
+	}
+
+	if username == "" {
+		return nil
+	}
+
+	wait, ok = s.accounts.Attempt(username)
+	if !ok {
+		s.addresses.Undo(ip)
+		return s.tooManyAttempts(wait, c)
+	}
+
+	if wait >= s.config.Limit.Lockout.Duration {
+		s.logger.Infoc(authCtx, fmt.Sprintf("too many failed attempts for user '%s', locked out", username))
+	}
+
+	return nil
+}
+
+// endAttempt forgets the attempt counted by beginAttempt, because the credentials were
+// valid. Earlier failed attempts are kept
+func (s AuthService) endAttempt(username string, c echo.Context) {
+	s.addresses.Undo(c.RealIP())
+	if username != "" {
+		s.accounts.Undo(username)
+	}
+}
+
+// resetAttempts forgets the failed attempts of the user identified by username. The
+// failed attempts of the client are kept, otherwise an attacker could reset them by
+// logging into an own account
+func (s AuthService) resetAttempts(username string) {
+	s.accounts.Reset(username)
+}
+
+// checkRegistration returns ErrTooManyAttempts if the client which sent the request
+// registered too many users. Every registration counts, successful or not
+func (s AuthService) checkRegistration(c echo.Context) error {
+	wait, ok := s.addresses.Attempt(registerKeyPrefix + c.RealIP())
+	if !ok {
+		return s.tooManyAttempts(wait, c)
+	}
+
+	return nil
+}
+
+// tooManyAttempts returns ErrTooManyAttempts and sets the Retry-After header if the
+// client has to wait
+func (s AuthService) tooManyAttempts(wait time.Duration, c echo.Context) error {
+	if wait <= 0 {
+		return nil
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return errors.ErrTooManyAttempts
+}
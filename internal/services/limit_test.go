@@ -0,0 +1,149 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/modules/hash"
+	"chapper.dev/server/internal/services/errors"
+)
+
+// limitTwoAttempts delays attempts after two failed ones
+func limitTwoAttempts(c *config.Config) {
+	c.Limit.AccountAttempts = 2
+}
+
+// checkLimited asserts that the next attempt of alice has to wait, even with the right
+// password, and that the wait is shared with logins
+func checkLimited(t *testing.T, s AuthService, attempt func(body map[string]string) error) {
+	err := attempt(map[string]string{"password": "alice-password"})
+	if err != errors.ErrTooManyAttempts {
+		t.Fatalf("attempt with the right password after failed ones error = %v, want %v", err, errors.ErrTooManyAttempts)
+	}
+
+	c := newTestContext(t, map[string]string{"username": "alice", "password": "alice-password"})
+	_, _, err = s.Login(c)
+	if err != errors.ErrTooManyAttempts {
+		t.Errorf("Login() after failed attempts error = %v, want %v", err, errors.ErrTooManyAttempts)
+	}
+
+	if c.Response().Header().Get("Retry-After") == "" {
+		t.Error("Login() sent no Retry-After header")
+	}
+}
+
+func TestDisableTwoFALimited(t *testing.T) {
+	s, _ := newTwoFATestService(t, limitTwoAttempts)
+
+	disable := func(body map[string]string) error {
+		return s.DisableTwoFA("alice", newTestContext(t, body))
+	}
+
+	err := disable(map[string]string{"password": "wrong"})
+	if err != errors.ErrInvalidPassword {
+		t.Fatalf("DisableTwoFA() with a wrong password error = %v, want %v", err, errors.ErrInvalidPassword)
+	}
+
+	err = disable(map[string]string{"code": "invalid"})
+	if err != errors.ErrInvalidCode {
+		t.Fatalf("DisableTwoFA() with a wrong code error = %v, want %v", err, errors.ErrInvalidCode)
+	}
+
+	checkLimited(t, s, disable)
+}
+
+func TestChangeEmailLimited(t *testing.T) {
+	s, _ := newTwoFATestService(t, limitTwoAttempts)
+
+	change := func(body map[string]string) error {
+		body["email"] = "alice@chapper.test"
+		return s.ChangeEmail("alice", newTestContext(t, body))
+	}
+
+	// A right password forgets the failed attempts
+	for _, password := range []string{"wrong", "alice-password", "wrong", "wrong"} {
+		var want error = errors.ErrInvalidPassword
+		if password == "alice-password" {
+			want = nil
+		}
+
+		err := change(map[string]string{"password": password})
+		if err != want {
+			t.Fatalf("ChangeEmail() with password %q error = %v, want %v", password, err, want)
+		}
+	}
+
+	checkLimited(t, s, change)
+}
+
+// slowHash compares passwords slowly, so that concurrent logins overlap
+type slowHash struct {
+	hash.Chain
+}
+
+func (h slowHash) Valid(input, hashed string) (bool, error) {
+	time.Sleep(50 * time.Millisecond)
+	return h.Chain.Valid(input, hashed)
+}
+
+func TestLoginConcurrentAttemptsLimited(t *testing.T) {
+	const logins = 10
+
+	s, _ := newTwoFATestService(t, limitTwoAttempts)
+	s.hash = slowHash{s.hash.(hash.Chain)}
+
+	errs := make(chan error, logins)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < logins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c := newTestContext(t, map[string]string{"username": "bob", "password": "wrong"})
+			_, _, err := s.Login(c)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	compared := 0
+	for err := range errs {
+		switch err {
+		case errors.ErrInvalidPassword:
+			compared++
+		case errors.ErrTooManyAttempts:
+		default:
+			t.Fatalf("Login() error = %v, want %v or %v", err, errors.ErrInvalidPassword, errors.ErrTooManyAttempts)
+		}
+	}
+
+	if compared != 2 {
+		t.Errorf("%d concurrent logins compared the password, want 2", compared)
+	}
+}
+
+func TestLoginForgetsValidAttempts(t *testing.T) {
+	s, _ := newTwoFATestService(t, func(c *config.Config) {
+		c.Limit.IPAttempts = 2
+	})
+
+	// Valid passwords don't count as attempts of the client
+	for i := 0; i < 3; i++ {
+		_, _, err := s.Login(newTestContext(t, map[string]string{"username": "bob", "password": "bob-password"}))
+		if err != nil {
+			t.Fatalf("Login() %d error = %v", i, err)
+		}
+	}
+
+	_, _, err := s.Login(newTestContext(t, map[string]string{"username": "bob", "password": "wrong"}))
+	if err != errors.ErrInvalidPassword {
+		t.Errorf("Login() with a wrong password after valid ones error = %v, want %v", err, errors.ErrInvalidPassword)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/mail"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+	"chapper.dev/server/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// mailTokenBytes is the number of random bytes of a token sent by mail
+	mailTokenBytes = 32
+
+	// verifyEmailTTL is the time users have to verify their email address
+	verifyEmailTTL = 24 * time.Hour
+
+	// resetPasswordTTL is the time users have to reset their password after they
+	// requested it
+	resetPasswordTTL = time.Hour
+)
+
+const verifyEmailBody = `Hi %s,
+
+please verify your email address for %s by opening the following link:
+
+%s
+
+The link expires in 24 hours. If you didn't create an account, you can
+ignore this mail.
+`
+
+const resetPasswordBody = `Hi %s,
+
+a password reset was requested for your account on %s. You can choose
+a new password by opening the following link:
+
+%s
+
+The link expires in one hour. If you didn't request a password reset, you
+can ignore this mail.
+`
+
+type mailTokenRequest struct {
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// VerifyEmail marks the email address of a user as verified with the token sent to
+// the address
+func (s AuthService) VerifyEmail(c echo.Context) error {
+	var req mailTokenRequest
+	err := c.Bind(&req)
+	if err != nil || req.Token == "" {
+		return errors.ErrMissingMailToken
+	}
+
+	token, err := s.useMailToken(req.Token, models.MailVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	// The user changed the email address after the mail was sent
+	err = s.store.UpdateEmailVerified(token.Username, token.Email)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return errors.ErrInvalidMailToken
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrUpdateUser
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' verified the email address", token.Username))
+	return nil
+}
+
+// ResendVerification sends a new verification mail to the user identified by username.
+// The link of earlier mails becomes invalid
+func (s AuthService) ResendVerification(username string) error {
+	account, err := s.store.GetUser(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrGetUser
+	}
+
+	if account.Email.String == "" {
+		return errors.ErrMissingEmail
+	}
+
+	if account.EmailVerified {
+		return errors.ErrEmailVerified
+	}
+
+	return s.sendVerification(account.Username, account.Email.String)
+}
+
+// RequestPasswordReset sends a password reset mail to the user provided in the request
+// body. It doesn't fail if the user doesn't exist or has no email address, so that it
+// can't be used to find out which users exist
+func (s AuthService) RequestPasswordReset(c echo.Context) error {
+	if s.config.General.DisablePasswordLogin {
+		return errors.ErrPasswordLoginDisabled
+	}
+
+	var req mailTokenRequest
+	err := c.Bind(&req)
+	if err != nil || req.Username == "" {
+		return errors.ErrMissingUserData
+	}
+
+	account, err := s.store.GetUser(req.Username)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Errorc(authCtx, err)
+		}
+		return nil
+	}
+
+	if account.Email.String == "" {
+		s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' requested a password reset, but has no email address", account.Username))
+		return nil
+	}
+
+	token, err := s.newMailToken(account.Username, models.MailResetPassword, account.Email.String, resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.mailLink("reset-password", token)
+	err = s.mailer.Send(mail.Message{
+		To:      account.Email.String,
+		Subject: fmt.Sprintf("Reset your password on %s", s.config.General.Name),
+		Body:    fmt.Sprintf(resetPasswordBody, account.Username, s.config.General.Name, link),
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with the token sent by RequestPasswordReset. All
+// refresh tokens of the user and all personal access tokens of the user and of the bots
+// of the user are revoked
+func (s AuthService) ResetPassword(c echo.Context) error {
+	if s.config.General.DisablePasswordLogin {
+		return errors.ErrPasswordLoginDisabled
+	}
+
+	var req mailTokenRequest
+	err := c.Bind(&req)
+	if err != nil || req.Token == "" {
+		return errors.ErrMissingMailToken
+	}
+
+	if req.Password == "" {
+		return errors.ErrMissingUserData
+	}
+
+	token, err := s.useMailToken(req.Token, models.MailResetPassword)
+	if err != nil {
+		return err
+	}
+
+	account, err := s.store.GetUser(token.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrGetUser
+	}
+
+	// The user changed the email address after the mail was sent
+	if account.Email.String != token.Email {
+		return errors.ErrInvalidMailToken
+	}
+
+	hashed, err := s.HashPassword(req.Password)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrHashPassword
+	}
+
+	// The password was changed after the mail was sent
+	err = s.store.UpdatePassword(account.Username, account.Password, hashed)
+	if err == store.ErrNotFound {
+		return errors.ErrInvalidMailToken
+	}
+
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrUpdateUser
+	}
+
+	err = s.store.RevokeUserRefreshTokens(account.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrRevokeRefreshToken
+	}
+
+	err = s.RevokeSessions(account.Username, "")
+	if err != nil {
+		return err
+	}
+
+	err = s.revokePersonalAccessTokens(account.Username)
+	if err != nil {
+		return err
+	}
+
+	// Receiving the mail proves the user owns the address
+	if !account.EmailVerified {
+		s.store.UpdateEmailVerified(account.Username, token.Email)
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' reset the password", account.Username))
+	return nil
+}
+
+// sendVerification sends a mail with a verification link to email
+func (s AuthService) sendVerification(username, email string) error {
+	token, err := s.newMailToken(username, models.MailVerifyEmail, email, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.mailLink("verify-email", token)
+	err = s.mailer.Send(mail.Message{
+		To:      email,
+		Subject: fmt.Sprintf("Verify your email address on %s", s.config.General.Name),
+		Body:    fmt.Sprintf(verifyEmailBody, username, s.config.General.Name, link),
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrSendMail
+	}
+
+	return nil
+}
+
+// newMailToken creates a new token of the provided kind for the user identified by
+// username, which is sent to email
+func (s AuthService) newMailToken(username, kind, email string, ttl time.Duration) (string, error) {
+	token, err := utils.RandomCryptoString(mailTokenBytes)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return "", errors.ErrCreateMailToken
+	}
+
+	err = s.store.CreateMailToken(&models.MailToken{
+		Hash:      hashToken(token),
+		Username:  username,
+		Kind:      kind,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return "", errors.ErrCreateMailToken
+	}
+
+	return token, nil
+}
+
+// useMailToken returns the saved token of the provided kind which matches token and
+// deletes it. Each token can only be used once
+func (s AuthService) useMailToken(token, kind string) (*models.MailToken, error) {
+	hash := hashToken(token)
+	saved, err := s.store.GetMailToken(hash)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Errorc(authCtx, err)
+		}
+		return nil, errors.ErrInvalidMailToken
+	}
+
+	if saved.Kind != kind {
+		return nil, errors.ErrInvalidMailToken
+	}
+
+	// Only one of multiple concurrent requests with the same token deletes it
+	err = s.store.DeleteMailToken(hash)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Errorc(authCtx, err)
+		}
+		return nil, errors.ErrInvalidMailToken
+	}
+
+	if saved.IsExpired() {
+		return nil, errors.ErrInvalidMailToken
+	}
+
+	return saved, nil
+}
+
+// mailLink returns the link to the page of the web client which handles the token
+func (s AuthService) mailLink(page, token string) string {
+	return fmt.Sprintf("%s/%s?token=%s", s.config.Mail.URL, page, url.QueryEscape(token))
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/log"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+
+	"github.com/labstack/echo/v4"
+)
+
+var memberCtx = log.NewContext("member-srv")
+
+// MemberService provides a service to manage the members of virtual servers
+type MemberService struct {
+	store  store.Store
+	logger *log.Logger
+}
+
+// NewMemberService returns a new member service
+func NewMemberService(store store.Store, logger *log.Logger) MemberService {
+	return MemberService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetPrivileges returns the effective privileges of the user identified by 'username'
+// in the virtual server identified by 'serverHash' and if the user is a member of it.
+// The privileges of global roles apply to every server, the privileges of server roles
+// only apply to members
+func (s MemberService) GetPrivileges(username, serverHash string) (models.Privileges, bool, error) {
+	global, err := s.store.GetUserRoles(username)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return models.Privileges{}, false, errors.ErrGetRole
+	}
+
+	privileges := models.MergeRoles(global)
+
+	_, err = s.store.GetMember(serverHash, username)
+	if err == store.ErrNotFound {
+		return privileges, false, nil
+	}
+
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return models.Privileges{}, false, errors.ErrGetMember
+	}
+
+	roles, err := s.store.GetMemberRoles(serverHash, username)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return models.Privileges{}, false, errors.ErrGetRole
+	}
+
+	return privileges.Merge(models.MergeRoles(roles)), true, nil
+}
+
+// GetMembers returns all members including their roles of the virtual server
+// identified by the server hash path parameter
+func (s MemberService) GetMembers(c echo.Context) ([]models.Member, error) {
+	serverHash := c.Param("server-hash")
+
+	members, err := s.store.GetMembers(serverHash)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return nil, errors.ErrGetMember
+	}
+
+	for i := range members {
+		members[i].Roles, err = s.store.GetMemberRoles(serverHash, members[i].Username)
+		if err != nil {
+			s.logger.Errorc(memberCtx, err)
+			return nil, errors.ErrGetRole
+		}
+	}
+
+	return members, nil
+}
+
+// KickMember removes the user identified by the username path parameter from the
+// virtual server identified by the server hash path parameter. Users can only kick
+// members whose privileges they have themselves
+func (s MemberService) KickMember(granter models.Privileges, c echo.Context) error {
+	var (
+		serverHash = c.Param("server-hash")
+		username   = c.Param("username")
+	)
+
+	privileges, isMember, err := s.GetPrivileges(username, serverHash)
+	if err != nil {
+		return err
+	}
+
+	if !isMember {
+		return errors.ErrNoSuchMember
+	}
+
+	if !granter.Covers(privileges) {
+		return errors.ErrInsufficientPrivileges
+	}
+
+	err = s.store.RemoveMember(serverHash, username)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return errors.ErrRemoveMember
+	}
+
+	return nil
+}
+
+// GetUserServers returns all virtual servers the user identified by 'username' is a
+// member of
+func (s MemberService) GetUserServers(username string) ([]models.Server, error) {
+	servers, err := s.store.GetUserServers(username)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return nil, errors.ErrGetServer
+	}
+
+	return servers, nil
+}
+
+// JoinServer adds the user identified by 'username' to the virtual server of the
+// invite, which is provided in the request body. The user gets the member role of the
+// server. One time invites are used up before the user is added, so that a one time
+// invite can't add two users
+func (s MemberService) JoinServer(username string, c echo.Context) (*models.Server, error) {
+	var join = new(struct {
+		Invite string `json:"invite"`
+	})
+
+	err := c.Bind(join)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return nil, errors.ErrBindInvite
+	}
+
+	invite, server, err := s.checkInvite(join.Invite)
+	if err != nil {
+		return nil, err
+	}
+
+	// Members don't use up the invite
+	_, err = s.store.GetMember(server.Hash, username)
+	if err == nil {
+		return nil, errors.ErrAlreadyMember
+	}
+
+	if err != store.ErrNotFound {
+		s.logger.Errorc(memberCtx, err)
+		return nil, errors.ErrGetMember
+	}
+
+	err = s.useInvite(invite)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.addMember(server.Hash, username, models.ServerMember().Name)
+	if err != nil {
+		s.restoreInvite(invite)
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// checkInvite returns the invite identified by 'code' and its virtual server if the
+// invite exists and is not expired
+func (s MemberService) checkInvite(code string) (*models.Invite, *models.Server, error) {
+	if code == "" {
+		return nil, nil, errors.ErrMissingInviteCode
+	}
+
+	invite, err := s.store.GetInvite(code)
+	if err == store.ErrNotFound {
+		return nil, nil, errors.ErrNoSuchInvite
+	}
+
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return nil, nil, errors.ErrGetInvite
+	}
+
+	if invite.ExpiresAt.Valid && invite.ExpiresAt.Time.Before(time.Now()) {
+		return nil, nil, errors.ErrInviteExpired
+	}
+
+	server, err := s.store.GetServer(invite.Server)
+	if err == store.ErrNotFound {
+		return nil, nil, errors.ErrNoSuchServer
+	}
+
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return nil, nil, errors.ErrGetServer
+	}
+
+	return invite, server, nil
+}
+
+// useInvite deletes the invite if it is a one time invite. It returns ErrNoSuchInvite
+// if the invite was used up in the meantime
+func (s MemberService) useInvite(invite *models.Invite) error {
+	if !invite.OneTimeUse {
+		return nil
+	}
+
+	err := s.store.DeleteInvite(invite.Hash)
+	if err == store.ErrNotFound {
+		return errors.ErrNoSuchInvite
+	}
+
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return errors.ErrDeleteInvite
+	}
+
+	return nil
+}
+
+// restoreInvite creates the used up one time invite again, because the registration or
+// join which used it failed
+func (s MemberService) restoreInvite(invite *models.Invite) {
+	if !invite.OneTimeUse {
+		return
+	}
+
+	err := s.store.CreateInvite(invite)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+	}
+}
+
+// addMember adds the user identified by 'username' to the virtual server identified by
+// 'serverHash' and assigns the built-in server roles with the provided names
+func (s MemberService) addMember(serverHash, username string, roleNames ...string) error {
+	_, err := s.store.GetMember(serverHash, username)
+	if err == nil {
+		return errors.ErrAlreadyMember
+	}
+
+	if err != store.ErrNotFound {
+		s.logger.Errorc(memberCtx, err)
+		return errors.ErrGetMember
+	}
+
+	err = s.store.AddMember(serverHash, username)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return errors.ErrAddMember
+	}
+
+	roles, err := s.store.GetServerRoles(serverHash)
+	if err != nil {
+		s.logger.Errorc(memberCtx, err)
+		return errors.ErrGetRole
+	}
+
+	for _, role := range roles {
+		if !role.Builtin || !isIn(role.Name, roleNames) {
+			continue
+		}
+
+		err = s.store.AssignRole(username, role.ID)
+		if err != nil {
+			s.logger.Errorc(memberCtx, err)
+			return errors.ErrAssignRole
+		}
+	}
+
+	return nil
+}
+
+func isIn(s string, list []string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
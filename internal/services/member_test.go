@@ -0,0 +1,113 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store/memory"
+	"chapper.dev/server/internal/testutil"
+)
+
+// newMemberTestEnv returns an environment with the server 'chapper' and its one time
+// invite 'once'
+func newMemberTestEnv(t *testing.T) *testutil.Env {
+	env := testutil.New(t, nil)
+
+	err := env.Store.CreateServer(&models.Server{Hash: "chapper", Name: "Chapper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.Store.CreateInvite(&models.Invite{Hash: "once", Server: "chapper", OneTimeUse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return env
+}
+
+// barrierStore lets every caller of GetInvite wait until all callers read the invite,
+// so that concurrent joins race for the same invite
+type barrierStore struct {
+	*memory.Store
+	read *sync.WaitGroup
+}
+
+func (s barrierStore) GetInvite(hash string) (*models.Invite, error) {
+	invite, err := s.Store.GetInvite(hash)
+	s.read.Done()
+	s.read.Wait()
+	return invite, err
+}
+
+func TestJoinServerOneTimeInvite(t *testing.T) {
+	const users = 10
+
+	env := newMemberTestEnv(t)
+
+	read := new(sync.WaitGroup)
+	read.Add(users)
+	s := NewMemberService(barrierStore{env.Store, read}, env.Logger)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		joined int
+	)
+
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(username string) {
+			defer wg.Done()
+
+			_, err := s.JoinServer(username, newTestContext(t, map[string]string{"invite": "once"}))
+			if err != nil && err != errors.ErrNoSuchInvite {
+				t.Errorf("JoinServer() of %s error = %v", username, err)
+			}
+
+			if err == nil {
+				mu.Lock()
+				joined++
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("user-%d", i))
+	}
+	wg.Wait()
+
+	members, err := env.Store.GetMembers("chapper")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if joined != 1 || len(members) != 1 {
+		t.Errorf("%d joins succeeded and the server has %d members, want one", joined, len(members))
+	}
+}
+
+func TestJoinServerAsMember(t *testing.T) {
+	env := newMemberTestEnv(t)
+	s := NewMemberService(env.Store, env.Logger)
+
+	err := env.Store.AddMember("chapper", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.JoinServer("alice", newTestContext(t, map[string]string{"invite": "once"}))
+	if err != errors.ErrAlreadyMember {
+		t.Fatalf("JoinServer() of a member error = %v, want %v", err, errors.ErrAlreadyMember)
+	}
+
+	// The invite was not used up
+	_, err = s.JoinServer("bob", newTestContext(t, map[string]string{"invite": "once"}))
+	if err != nil {
+		t.Errorf("JoinServer() of bob error = %v", err)
+	}
+}
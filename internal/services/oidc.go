@@ -0,0 +1,250 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/oidc"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OIDCLogin is returned when a single sign-on starts. The client sends the user to the
+// URL of the identity provider, which redirects back with a code and the state
+type OIDCLogin struct {
+	URL   string `json:"url"`
+	State string `json:"state"`
+}
+
+type oidcCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// BeginOIDCLogin starts a single sign-on with the identity provider
+func (s AuthService) BeginOIDCLogin() (*OIDCLogin, error) {
+	if s.oidc == nil {
+		return nil, errors.ErrOIDCUnavailable
+	}
+
+	flow, err := oidc.NewFlow()
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrCreateVerifyToken
+	}
+
+	session, err := json.Marshal(flow)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrCreateVerifyToken
+	}
+
+	// The state identifies the challenge, which carries the nonce and the PKCE code
+	// verifier until the code is exchanged
+	state, err := s.newChallenge("", models.ChallengeOIDC, string(session))
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.oidc.AuthURL(state, flow)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrOIDC
+	}
+
+	return &OIDCLogin{
+		URL:   url,
+		State: state,
+	}, nil
+}
+
+// FinishOIDCLogin exchanges the code for the ID token of the user. Users are created
+// with their first login and their roles are synced from the claims of the ID token
+func (s AuthService) FinishOIDCLogin(c echo.Context) (*Tokens, error) {
+	if s.oidc == nil {
+		return nil, errors.ErrOIDCUnavailable
+	}
+
+	var req oidcCallbackRequest
+	err := c.Bind(&req)
+	if err != nil || req.Code == "" || req.State == "" {
+		return nil, errors.ErrMissingOIDCData
+	}
+
+	challenge, err := s.getChallenge(req.State, models.ChallengeOIDC)
+	if err != nil {
+		return nil, err
+	}
+
+	// The state can only be used once
+	err = s.useChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	var flow oidc.Flow
+	err = json.Unmarshal([]byte(challenge.Session), &flow)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrInvalidChallenge
+	}
+
+	claims, err := s.oidc.Exchange(req.Code, flow)
+	if err != nil {
+		s.logger.Infoc(authCtx, fmt.Sprintf("single sign-on failed: %v", err))
+		return nil, errors.ErrInvalidOIDCResponse
+	}
+
+	username, err := s.oidcUser(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// The roles have to be synced before the privileges are added to the tokens
+	err = s.syncRoles(username, s.config.OIDC.RoleMapping, claims.Strings(s.config.OIDC.RolesClaim))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(username, c)
+}
+
+// oidcUser returns the username of the user linked to the subject of the claims. A new
+// user is created if the subject is unknown. Existing users are never linked, because
+// anyone could claim their username at the identity provider
+func (s AuthService) oidcUser(claims oidc.Claims) (string, error) {
+	issuer, subject := claims.String("iss"), claims.Subject()
+
+	identity, err := s.store.GetIdentity(issuer, subject)
+	if err == nil {
+		return identity.Username, nil
+	}
+
+	if err != store.ErrNotFound {
+		s.logger.Errorc(authCtx, err)
+		return "", errors.ErrGetIdentity
+	}
+
+	username := claims.String(s.config.OIDC.UsernameClaim)
+	if username == "" {
+		username = subject
+	}
+
+	if !models.ValidUsername(username) {
+		s.logger.Infoc(authCtx, fmt.Sprintf("single sign-on of subject '%s' provided the invalid username '%s'", subject, username))
+		return "", errors.ErrInvalidUsername
+	}
+
+	_, err = s.store.GetUser(username)
+	if err == nil {
+		s.logger.Infoc(authCtx, fmt.Sprintf("single sign-on of subject '%s' provided the username '%s' of an existing user", subject, username))
+		return "", errors.ErrUsernameTaken
+	}
+
+	if err != store.ErrNotFound {
+		s.logger.Errorc(authCtx, err)
+		return "", errors.ErrGetUser
+	}
+
+	email := claims.String("email")
+	if !models.ValidEmail(email) {
+		email = ""
+	}
+
+	// Users of the identity provider have no password, password logins always fail. The
+	// user is created together with the identity, a user without it couldn't login again
+	err = s.users.CreateAccount(&store.Account{
+		User: models.PublicUser{
+			Username: username,
+			Email:    email,
+		},
+		Identity: &models.Identity{
+			Issuer:    issuer,
+			Subject:   subject,
+			Username:  username,
+			CreatedAt: time.Now().UTC(),
+		},
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return "", err
+	}
+
+	if email != "" && claims.Bool("email_verified") {
+		err = s.store.UpdateEmailVerified(username, email)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+		}
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("created user '%s' for subject '%s' of the identity provider", username, subject))
+	return username, nil
+}
+
+// syncRoles assigns the roles mapped from the values, like claimed roles or groups, to
+// the user and removes mapped roles which are no longer present. Roles which are not
+// part of the mapping are managed in Chapper and left alone
+func (s AuthService) syncRoles(username string, mapping map[string]string, values []string) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+
+	claimed := make(map[string]bool)
+	for _, value := range values {
+		if role, ok := mapping[value]; ok {
+			claimed[role] = true
+		}
+	}
+
+	roles, err := s.store.GetUserRoles(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrGetRole
+	}
+
+	assigned := make(map[string]uint)
+	for _, role := range roles {
+		assigned[role.Name] = role.ID
+	}
+
+	synced := make(map[string]bool)
+	for _, name := range mapping {
+		if synced[name] {
+			continue
+		}
+		synced[name] = true
+
+		id, isAssigned := assigned[name]
+
+		switch {
+		case claimed[name] && !isAssigned:
+			role, err := s.store.GetRoleByName(name)
+			if err != nil {
+				s.logger.Errorc(authCtx, fmt.Errorf("failed to get role '%s' of the role mapping: %w", name, err))
+				continue
+			}
+
+			err = s.store.AssignRole(username, role.ID)
+			if err != nil {
+				s.logger.Errorc(authCtx, err)
+				return errors.ErrAssignRole
+			}
+		case !claimed[name] && isAssigned:
+			err = s.store.RemoveRole(username, id)
+			if err != nil {
+				s.logger.Errorc(authCtx, err)
+				return errors.ErrRemoveRole
+			}
+		}
+	}
+
+	return nil
+}
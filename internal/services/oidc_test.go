@@ -0,0 +1,311 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store/memory"
+
+	j "github.com/dgrijalva/jwt-go"
+)
+
+// testIdP is a stand-in identity provider which logs in the subject of its claims
+type testIdP struct {
+	*httptest.Server
+
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	nonces map[string]string
+	claims j.MapClaims
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &testIdP{
+		key:    key,
+		nonces: make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/keys",
+		})
+	})
+
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		code := query.Get("state")
+
+		idp.mu.Lock()
+		idp.nonces[code] = query.Get("nonce")
+		idp.mu.Unlock()
+
+		http.Redirect(w, r, query.Get("redirect_uri")+"?code="+code+"&state="+query.Get("state"), http.StatusFound)
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		idp.mu.Lock()
+		claims := j.MapClaims{
+			"iss":   idp.URL,
+			"aud":   "chapper",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": idp.nonces[r.PostForm.Get("code")],
+		}
+		for name, value := range idp.claims {
+			claims[name] = value
+		}
+		idp.mu.Unlock()
+
+		token := j.NewWithClaims(j.SigningMethodRS256, claims)
+		token.Header["kid"] = "key-1"
+
+		idToken, _ := token.SignedString(idp.key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+			}},
+		})
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// newOIDCTestService returns a service which logs in at the identity provider. The
+// groups 'moderators' are mapped to the role 'Mod'
+func newOIDCTestService(t *testing.T, idp *testIdP) (AuthService, *memory.Store) {
+	s, m := newTestAuthService(t, func(c *config.Config) {
+		c.OIDC = config.OIDCOptions{
+			Issuer:      idp.URL,
+			ClientID:    "chapper",
+			RedirectURL: "https://chapper.test/oidc/callback",
+			RoleMapping: map[string]string{"moderators": "Mod"},
+		}
+	})
+
+	err := m.CreateRole(&models.Role{Name: "Mod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, m
+}
+
+// authorize visits the URL of the identity provider and returns the code and state of
+// the redirect
+func (idp *testIdP) authorize(t *testing.T, login *OIDCLogin) map[string]string {
+	client := idp.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	res, err := client.Get(login.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]string{
+		"code":  location.Query().Get("code"),
+		"state": location.Query().Get("state"),
+	}
+}
+
+func (idp *testIdP) setClaims(claims j.MapClaims) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.claims = claims
+}
+
+// loginOIDC runs a single sign-on and returns the result of the callback
+func loginOIDC(t *testing.T, s AuthService, idp *testIdP) (*Tokens, error) {
+	login, err := s.BeginOIDCLogin()
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin() error = %v", err)
+	}
+
+	return s.FinishOIDCLogin(newTestContext(t, idp.authorize(t, login)))
+}
+
+func TestFinishOIDCLogin(t *testing.T) {
+	idp := newTestIdP(t)
+	s, m := newOIDCTestService(t, idp)
+
+	idp.setClaims(j.MapClaims{
+		"sub":                "subject-1",
+		"preferred_username": "alice",
+		"email":              "alice@chapper.test",
+		"email_verified":     true,
+		"groups":             []string{"moderators"},
+	})
+
+	tokens, err := loginOIDC(t, s, idp)
+	if err != nil {
+		t.Fatalf("FinishOIDCLogin() error = %v", err)
+	}
+
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Error("FinishOIDCLogin() returned no tokens")
+	}
+
+	user, err := m.GetUser("alice")
+	if err != nil {
+		t.Fatalf("user was not created: %v", err)
+	}
+
+	if user.Password != "" {
+		t.Error("user of the identity provider has a password")
+	}
+
+	if user.Email.String != "alice@chapper.test" || !user.EmailVerified {
+		t.Errorf("email = %q, verified = %v, want verified alice@chapper.test", user.Email.String, user.EmailVerified)
+	}
+
+	identity, err := m.GetIdentity(idp.URL, "subject-1")
+	if err != nil || identity.Username != "alice" {
+		t.Fatalf("GetIdentity() = %v, %v, want identity of alice", identity, err)
+	}
+
+	if !hasRole(t, m, "alice", "Mod") {
+		t.Error("claimed group was not mapped to role Mod")
+	}
+
+	// The next login finds the user by subject, even if the username claim changed,
+	// and removes roles which are no longer claimed
+	idp.setClaims(j.MapClaims{
+		"sub":                "subject-1",
+		"preferred_username": "renamed",
+	})
+
+	_, err = loginOIDC(t, s, idp)
+	if err != nil {
+		t.Fatalf("FinishOIDCLogin() error = %v", err)
+	}
+
+	_, err = m.GetUser("renamed")
+	if err == nil {
+		t.Error("second login created another user")
+	}
+
+	if hasRole(t, m, "alice", "Mod") {
+		t.Error("role Mod was kept after the group was no longer claimed")
+	}
+}
+
+func TestFinishOIDCLoginReusedState(t *testing.T) {
+	idp := newTestIdP(t)
+	s, _ := newOIDCTestService(t, idp)
+
+	idp.setClaims(j.MapClaims{
+		"sub":                "subject-1",
+		"preferred_username": "alice",
+	})
+
+	login, err := s.BeginOIDCLogin()
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin() error = %v", err)
+	}
+	callback := idp.authorize(t, login)
+
+	_, err = s.FinishOIDCLogin(newTestContext(t, callback))
+	if err != nil {
+		t.Fatalf("FinishOIDCLogin() error = %v", err)
+	}
+
+	_, err = s.FinishOIDCLogin(newTestContext(t, callback))
+	if err != errors.ErrInvalidChallenge {
+		t.Fatalf("FinishOIDCLogin() with a used state error = %v, want %v", err, errors.ErrInvalidChallenge)
+	}
+}
+
+func TestFinishOIDCLoginUnknownState(t *testing.T) {
+	idp := newTestIdP(t)
+	s, _ := newOIDCTestService(t, idp)
+
+	_, err := s.FinishOIDCLogin(newTestContext(t, map[string]string{
+		"code":  "code",
+		"state": "unknown",
+	}))
+	if err != errors.ErrInvalidChallenge {
+		t.Fatalf("FinishOIDCLogin() error = %v, want %v", err, errors.ErrInvalidChallenge)
+	}
+}
+
+func TestFinishOIDCLoginExistingUsername(t *testing.T) {
+	idp := newTestIdP(t)
+	s, m := newOIDCTestService(t, idp)
+
+	err := s.users.CreateUser(models.PublicUser{Username: "alice", Password: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp.setClaims(j.MapClaims{
+		"sub":                "subject-1",
+		"preferred_username": "alice",
+	})
+
+	_, err = loginOIDC(t, s, idp)
+	if err != errors.ErrUsernameTaken {
+		t.Fatalf("FinishOIDCLogin() error = %v, want %v", err, errors.ErrUsernameTaken)
+	}
+
+	_, err = m.GetIdentity(idp.URL, "subject-1")
+	if err == nil {
+		t.Error("subject was linked to the existing user")
+	}
+}
+
+func hasRole(t *testing.T, m *memory.Store, username, name string) bool {
+	roles, err := m.GetUserRoles(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, role := range roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
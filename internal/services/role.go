@@ -0,0 +1,287 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"strconv"
+
+	"chapper.dev/server/internal/log"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+
+	"github.com/labstack/echo/v4"
+)
+
+var roleCtx = log.NewContext("role-srv")
+
+// RoleService provides a service to create, get, update, delete and assign roles
+type RoleService struct {
+	store  store.Store
+	logger *log.Logger
+}
+
+// NewRoleService returns a new role service
+func NewRoleService(store store.Store, logger *log.Logger) RoleService {
+	return RoleService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// CreateRole creates a new role in the virtual server identified by 'server' or a
+// global role if 'server' is empty. The privileges of the new role can't exceed the
+// privileges of the creator
+func (s RoleService) CreateRole(granter models.Privileges, server string, c echo.Context) (*models.Role, error) {
+	var role = new(models.Role)
+
+	err := c.Bind(role)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return nil, errors.ErrBindRole
+	}
+
+	if role.IsEmpty() {
+		s.logger.Infoc(roleCtx, "data missing to create role")
+		return nil, errors.ErrMissingRoleData
+	}
+
+	if !granter.Covers(role.Privileges) {
+		return nil, errors.ErrInsufficientPrivileges
+	}
+
+	role.Server = server
+	role.Builtin = false
+	err = s.store.CreateRole(role)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return nil, errors.ErrCreateRole
+	}
+
+	return role, nil
+}
+
+// GetRole returns ONE role of the virtual server identified by 'server' or ONE global
+// role if 'server' is empty. The role is identified by the role ID path parameter
+func (s RoleService) GetRole(server string, c echo.Context) (*models.Role, error) {
+	roleID, err := roleIDParam(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getRole(roleID, server)
+}
+
+// GetRoles returns all roles of the virtual server identified by 'server' or all global
+// roles if 'server' is empty
+func (s RoleService) GetRoles(server string) ([]models.Role, error) {
+	roles, err := s.store.GetServerRoles(server)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return nil, errors.ErrGetRole
+	}
+
+	return roles, nil
+}
+
+// UpdateRole updates ONE role identified by the role ID path parameter. Built-in roles
+// can't be renamed and the admin roles can't be changed at all
+func (s RoleService) UpdateRole(granter models.Privileges, server string, c echo.Context) error {
+	roleID, err := roleIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	role, err := s.getRole(roleID, server)
+	if err != nil {
+		return err
+	}
+
+	var newRole = new(models.Role)
+	err = c.Bind(newRole)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return errors.ErrBindRole
+	}
+
+	if newRole.IsEmpty() {
+		return errors.ErrMissingRoleData
+	}
+
+	if role.IsAdmin() || (role.Builtin && newRole.Name != role.Name) {
+		return errors.ErrBuiltinRole
+	}
+
+	if !granter.Covers(newRole.Privileges) {
+		return errors.ErrInsufficientPrivileges
+	}
+
+	err = s.store.UpdateRole(roleID, newRole)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return errors.ErrUpdateRole
+	}
+
+	return nil
+}
+
+// DeleteRole deletes ONE role identified by the role ID path parameter. Built-in roles
+// can't be deleted. Like with AssignRole, users can only delete roles whose privileges
+// they have themselves
+func (s RoleService) DeleteRole(granter models.Privileges, server string, c echo.Context) error {
+	roleID, err := roleIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	role, err := s.getRole(roleID, server)
+	if err != nil {
+		return err
+	}
+
+	if role.Builtin {
+		return errors.ErrBuiltinRole
+	}
+
+	if !granter.Covers(role.Privileges) {
+		return errors.ErrInsufficientPrivileges
+	}
+
+	err = s.store.DeleteRole(roleID)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return errors.ErrDeleteRole
+	}
+
+	return nil
+}
+
+// GetUserRoles returns all global roles of the user identified by the username path
+// parameter
+func (s RoleService) GetUserRoles(c echo.Context) ([]models.Role, error) {
+	username := c.Param("username")
+	if username == "" {
+		return nil, errors.ErrMissingUserData
+	}
+
+	roles, err := s.store.GetUserRoles(username)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return nil, errors.ErrGetRole
+	}
+
+	return roles, nil
+}
+
+// AssignRole assigns the role identified by the role ID path parameter to the user
+// identified by the username path parameter. Roles of a virtual server can only be
+// assigned to its members. Users can only assign roles whose privileges they have
+// themselves
+func (s RoleService) AssignRole(granter models.Privileges, server string, c echo.Context) error {
+	roleID, err := roleIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	role, err := s.getRole(roleID, server)
+	if err != nil {
+		return err
+	}
+
+	if !granter.Covers(role.Privileges) {
+		return errors.ErrInsufficientPrivileges
+	}
+
+	username := c.Param("username")
+	_, err = s.store.GetUser(username)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return errors.ErrGetUser
+	}
+
+	if server != "" {
+		_, err = s.store.GetMember(server, username)
+		if err == store.ErrNotFound {
+			return errors.ErrNoSuchMember
+		}
+
+		if err != nil {
+			s.logger.Errorc(roleCtx, err)
+			return errors.ErrGetMember
+		}
+	}
+
+	err = s.store.AssignRole(username, roleID)
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return errors.ErrAssignRole
+	}
+
+	return nil
+}
+
+// RemoveRole removes the role identified by the role ID path parameter from the user
+// identified by the username path parameter. Like with AssignRole, users can only remove
+// roles whose privileges they have themselves. The superadmin role is never removed
+// from its last holder
+func (s RoleService) RemoveRole(granter models.Privileges, server string, c echo.Context) error {
+	roleID, err := roleIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	role, err := s.getRole(roleID, server)
+	if err != nil {
+		return err
+	}
+
+	if !granter.Covers(role.Privileges) {
+		return errors.ErrInsufficientPrivileges
+	}
+
+	if role.IsAdmin() && role.Server == "" {
+		err = s.store.RemoveRoleUnlessLast(c.Param("username"), roleID)
+		if err == store.ErrLastHolder {
+			return errors.ErrLastSuperadmin
+		}
+	} else {
+		err = s.store.RemoveRole(c.Param("username"), roleID)
+	}
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return errors.ErrRemoveRole
+	}
+
+	return nil
+}
+
+// getRole returns the role identified by 'roleID'. Roles of other virtual servers are
+// treated as not existing
+func (s RoleService) getRole(roleID uint, server string) (*models.Role, error) {
+	role, err := s.store.GetRole(roleID)
+	if err == store.ErrNotFound {
+		return nil, errors.ErrNoSuchRole
+	}
+
+	if err != nil {
+		s.logger.Errorc(roleCtx, err)
+		return nil, errors.ErrGetRole
+	}
+
+	if role.Server != server {
+		return nil, errors.ErrNoSuchRole
+	}
+
+	return role, nil
+}
+
+// roleIDParam returns the role ID path parameter
+func roleIDParam(c echo.Context) (uint, error) {
+	roleID, err := strconv.ParseUint(c.Param("role-id"), 10, 64)
+	if err != nil || roleID == 0 {
+		return 0, errors.ErrInvalidID
+	}
+	return uint(roleID), nil
+}
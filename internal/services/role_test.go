@@ -0,0 +1,52 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"strconv"
+	"testing"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+	"chapper.dev/server/internal/testutil"
+)
+
+func TestDeleteRole(t *testing.T) {
+	env := testutil.New(t, nil)
+	s := NewRoleService(env.Store, env.Logger)
+
+	// The moderator role may delete roles, but doesn't have the privileges of the
+	// admin role
+	moderator := models.Privileges{CanDeleteRole: true, CanDeleteRoom: true}
+	for _, tt := range []struct {
+		role *models.Role
+		want error
+	}{
+		{&models.Role{Server: "chapper", Name: "admin-like", Privileges: models.Privileges{CanDeleteRole: true, CanDeleteServer: true}}, errors.ErrInsufficientPrivileges},
+		{&models.Role{Server: "chapper", Name: "helper", Privileges: models.Privileges{CanDeleteRoom: true}}, nil},
+	} {
+		role, want := tt.role, tt.want
+
+		err := env.Store.CreateRole(role)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := newTestContext(t, nil)
+		c.SetParamNames("role-id")
+		c.SetParamValues(strconv.FormatUint(uint64(role.ID), 10))
+
+		err = s.DeleteRole(moderator, "chapper", c)
+		if err != want {
+			t.Errorf("DeleteRole() of %s error = %v, want %v", role.Name, err, want)
+		}
+
+		_, err = env.Store.GetRole(role.ID)
+		if deleted := err == store.ErrNotFound; deleted != (want == nil) {
+			t.Errorf("DeleteRole() of %s deleted the role: %v, want %v", role.Name, deleted, want == nil)
+		}
+	}
+}
@@ -7,7 +7,7 @@ package services
 import (
 	"chapper.dev/server/internal/log"
 	"chapper.dev/server/internal/models"
-	"chapper.dev/server/internal/modules/hash"
+	"chapper.dev/server/internal/modules/id"
 	"chapper.dev/server/internal/services/errors"
 	"chapper.dev/server/internal/store"
 
@@ -18,47 +18,67 @@ var roomCtx = log.NewContext("room-srv")
 
 // RoomService provides a service to create, get, update and delete rooms
 type RoomService struct {
-	store  *store.Store
+	store  store.Store
 	logger *log.Logger
 }
 
 // NewRoomService returns a new room service
-func NewRoomService(store *store.Store, logger *log.Logger) RoomService {
+func NewRoomService(store store.Store, logger *log.Logger) RoomService {
 	return RoomService{
 		store:  store,
 		logger: logger,
 	}
 }
 
-// CreateRoom creates and inserts a new room into the database
-func (s RoomService) CreateRoom(c echo.Context) error {
+// CreateRoom creates and inserts a new room into the virtual server identified by the
+// server hash path parameter
+func (s RoomService) CreateRoom(c echo.Context) (*models.Room, error) {
 	var room = new(models.Room)
 
 	// Bind to room model
 	err := c.Bind(room)
 	if err != nil {
 		s.logger.Errorc(roomCtx, err)
-		return errors.ErrBindRoom
+		return nil, errors.ErrBindRoom
 	}
 
 	// Check if some data is missing or invalid
 	if room.IsEmpty() || room.Invalid() {
 		s.logger.Infoc(roomCtx, "data missing to create room")
-		return errors.ErrMissingRoomData
+		return nil, errors.ErrMissingRoomData
+	}
+
+	// Make sure the server exists, superadmins pass the authorization without
+	// membership
+	server, err := s.store.GetServer(c.Param("server-hash"))
+	if err == store.ErrNotFound {
+		return nil, errors.ErrNoSuchServer
+	}
+
+	if err != nil {
+		s.logger.Errorc(roomCtx, err)
+		return nil, errors.ErrGetServer
+	}
+
+	// Create room ID and insert into database
+	room.Server = server.Hash
+	room.Hash, err = id.New()
+	if err != nil {
+		s.logger.Errorc(roomCtx, err)
+		return nil, errors.ErrCreateRoom
 	}
 
-	// Calculate room hash and insert into database
-	room.Hash = hash.FNV64(room.Name)
 	err = s.store.CreateRoom(room)
 	if err != nil {
 		s.logger.Errorc(roomCtx, err)
-		return errors.ErrCreateRoom
+		return nil, errors.ErrCreateRoom
 	}
 
-	return nil
+	return room, nil
 }
 
-// GetRoom returns ONE room from the database identified by the provided hash
+// GetRoom returns ONE room identified by the room hash path parameter of the virtual
+// server identified by the server hash path parameter
 func (s RoomService) GetRoom(c echo.Context) (*models.Room, error) {
 	roomHash := c.Param("room-hash")
 
@@ -67,26 +87,57 @@ func (s RoomService) GetRoom(c echo.Context) (*models.Room, error) {
 		return nil, errors.ErrInvalidHash
 	}
 
-	return s.store.GetRoom(roomHash)
+	room, err := s.store.GetRoom(roomHash)
+	if err == store.ErrNotFound {
+		return nil, errors.ErrNoSuchRoom
+	}
+
+	if err != nil {
+		s.logger.Errorc(roomCtx, err)
+		return nil, errors.ErrGetRoom
+	}
+
+	// Rooms of other servers are treated as not existing
+	if room.Server != c.Param("server-hash") {
+		return nil, errors.ErrNoSuchRoom
+	}
+
+	return room, nil
 }
 
-// GetRooms returns multiple rooms from the database
-func (s RoomService) GetRooms() ([]models.Room, error) {
-	return s.store.GetRooms()
+// GetRooms returns all rooms of the virtual server identified by the server hash path
+// parameter
+func (s RoomService) GetRooms(c echo.Context) ([]models.Room, error) {
+	rooms, err := s.store.GetRooms(c.Param("server-hash"))
+	if err != nil {
+		s.logger.Errorc(roomCtx, err)
+		return nil, errors.ErrGetRoom
+	}
+
+	return rooms, nil
 }
 
-// UpdateRoom updates ONE room in the database indentified by the provided hash
+// UpdateRoom updates ONE room identified by the room hash path parameter of the virtual
+// server identified by the server hash path parameter
 func (s RoomService) UpdateRoom(c echo.Context) error {
-	var newRoom *models.Room
-	roomHash := c.Param("room-hash")
+	room, err := s.GetRoom(c)
+	if err != nil {
+		return err
+	}
 
-	err := c.Bind(newRoom)
+	var newRoom = new(models.Room)
+	err = c.Bind(newRoom)
 	if err != nil {
 		s.logger.Errorc(roomCtx, err)
 		return errors.ErrBindRoom
 	}
 
-	err = s.store.UpdateRoom(roomHash, newRoom)
+	if newRoom.IsEmpty() || newRoom.Invalid() {
+		s.logger.Infoc(roomCtx, "data missing to update room")
+		return errors.ErrMissingRoomData
+	}
+
+	err = s.store.UpdateRoom(room.Hash, newRoom)
 	if err != nil {
 		s.logger.Errorc(roomCtx, err)
 		return errors.ErrUpdateRoom
@@ -95,8 +146,19 @@ func (s RoomService) UpdateRoom(c echo.Context) error {
 	return nil
 }
 
-// DeleteRoom deletes ONE room from the database identified by the provided room hash
+// DeleteRoom deletes ONE room identified by the room hash path parameter of the virtual
+// server identified by the server hash path parameter
 func (s RoomService) DeleteRoom(c echo.Context) error {
-	roomHash := c.Param("room-hash")
-	return s.store.DeleteRoom(roomHash)
+	room, err := s.GetRoom(c)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.DeleteRoom(room.Hash)
+	if err != nil {
+		s.logger.Errorc(roomCtx, err)
+		return errors.ErrDeleteRoom
+	}
+
+	return nil
 }
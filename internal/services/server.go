@@ -7,7 +7,7 @@ package services
 import (
 	"chapper.dev/server/internal/log"
 	"chapper.dev/server/internal/models"
-	"chapper.dev/server/internal/modules/hash"
+	"chapper.dev/server/internal/modules/id"
 	"chapper.dev/server/internal/services/errors"
 	"chapper.dev/server/internal/store"
 
@@ -18,43 +18,65 @@ var serverCtx = log.NewContext("server-srv")
 
 // ServerService wraps dependencies
 type ServerService struct {
-	store  *store.Store
-	logger *log.Logger
+	store   store.Store
+	logger  *log.Logger
+	members MemberService
 }
 
 // NewServerService returns a new server service
-func NewServerService(store *store.Store, logger *log.Logger) ServerService {
+func NewServerService(store store.Store, logger *log.Logger) ServerService {
 	return ServerService{
-		store:  store,
-		logger: logger,
+		store:   store,
+		logger:  logger,
+		members: NewMemberService(store, logger),
 	}
 }
 
-// CreateServer creates a new virtual server
-func (s ServerService) CreateServer(c echo.Context) error {
-	var server *models.Server
+// CreateServer creates a new virtual server with its built-in admin and member roles.
+// The creator becomes the first member and gets both roles
+func (s ServerService) CreateServer(username string, c echo.Context) (*models.Server, error) {
+	var server = new(models.Server)
 
 	err := c.Bind(server)
 	if err != nil {
 		s.logger.Errorc(serverCtx, err)
-		return errors.ErrBindServer
+		return nil, errors.ErrBindServer
 	}
 
 	if server.IsEmpty() {
 		s.logger.Infoc(serverCtx, "data missing to create server")
-		return errors.ErrMissingServerData
+		return nil, errors.ErrMissingServerData
 	}
 
-	serverHash := hash.FNV64(server.Name)
-	server.Hash = serverHash
+	server.Hash, err = id.New()
+	if err != nil {
+		s.logger.Errorc(serverCtx, err)
+		return nil, errors.ErrCreateServer
+	}
 
 	err = s.store.CreateServer(server)
 	if err != nil {
 		s.logger.Errorc(serverCtx, err)
-		return errors.ErrCreateServer
+		return nil, errors.ErrCreateServer
 	}
 
-	return nil
+	for _, role := range []models.Role{models.ServerAdmin(), models.ServerMember()} {
+		role.Server = server.Hash
+		role.Builtin = true
+
+		err = s.store.CreateRole(&role)
+		if err != nil {
+			s.logger.Errorc(serverCtx, err)
+			return nil, errors.ErrCreateRole
+		}
+	}
+
+	err = s.members.addMember(server.Hash, username, models.ServerAdmin().Name, models.ServerMember().Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return server, nil
 }
 
 // GetServer returns one virtual server identified by 'hash'
@@ -66,7 +88,17 @@ func (s ServerService) GetServer(c echo.Context) (*models.Server, error) {
 		return nil, errors.ErrInvalidHash
 	}
 
-	return s.store.GetServer(serverHash)
+	server, err := s.store.GetServer(serverHash)
+	if err == store.ErrNotFound {
+		return nil, errors.ErrNoSuchServer
+	}
+
+	if err != nil {
+		s.logger.Errorc(serverCtx, err)
+		return nil, errors.ErrGetServer
+	}
+
+	return server, nil
 }
 
 // GetServers returns all virtual servers
@@ -74,12 +106,37 @@ func (s ServerService) GetServers() ([]models.Server, error) {
 	return s.store.GetServers()
 }
 
+// ResolveID returns the ID of the server or room identified by 'value'. Legacy hashes
+// are resolved to the ID which replaced them, everything else is returned unchanged
+func (s ServerService) ResolveID(kind, value string) string {
+	if value == "" || id.Valid(value) {
+		return value
+	}
+
+	resolved, err := s.store.ResolveAlias(kind, value)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Errorc(serverCtx, err)
+		}
+		return value
+	}
+
+	return resolved
+}
+
 // UpdateServer updates one virtual server identified by 'hash'
 func (s ServerService) UpdateServer(hash string) error {
 	return nil
 }
 
-// DeleteServer deletes one virtual server dentified by 'hash'
+// DeleteServer deletes one virtual server dentified by 'hash' including its members
+// and roles
 func (s ServerService) DeleteServer(hash string) error {
-	return s.store.DeleteServer(hash)
+	err := s.store.DeleteServer(hash)
+	if err != nil {
+		s.logger.Errorc(serverCtx, err)
+		return errors.ErrDeleteServer
+	}
+
+	return nil
 }
@@ -0,0 +1,40 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/store/memory"
+	"chapper.dev/server/internal/testutil"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newTestAuthService returns an authentication service backed by the memory store. The
+// config can be changed by configure before it is validated
+func newTestAuthService(t *testing.T, configure func(*config.Config)) (AuthService, *memory.Store) {
+	env := testutil.New(t, configure)
+	return NewAuthService(env.Store, env.Config, env.Keys, env.Logger), env.Store
+}
+
+// newTestContext returns the context of a request with body encoded as JSON
+func newTestContext(t *testing.T, body interface{}) echo.Context {
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/jwt"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sessionTouchInterval is the minimum time between two updates of the time a session
+// was last seen
+const sessionTouchInterval = time.Minute
+
+// deviceNameHeader is the request header clients use to name the device of a new login
+const deviceNameHeader = "X-Device-Name"
+
+// SessionCloser closes the real-time connections which were opened by a login session,
+// like messaging websockets and calls
+type SessionCloser interface {
+	CloseSession(id string)
+}
+
+// CheckSession returns an error if the session of the access token was revoked or
+// expired. It updates the time the session was last seen
+func (s AuthService) CheckSession(claims *jwt.Claims) error {
+	// Personal access tokens have no session, they were checked when they were parsed
+	if claims.TokenID != "" {
+		return nil
+	}
+
+	if claims.SessionID == "" {
+		return errors.ErrInvalidSession
+	}
+
+	session, err := s.store.GetSession(claims.SessionID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return errors.ErrInvalidSession
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrGetSession
+	}
+
+	if session.Username != claims.Username || session.IsExpired() {
+		return errors.ErrInvalidSession
+	}
+
+	now := time.Now().UTC()
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		err = s.store.TouchSession(session.ID, now, session.ExpiresAt)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+		}
+	}
+
+	return nil
+}
+
+// GetSessions returns all active sessions of the user of the access token. The session
+// of the access token is marked as current
+func (s AuthService) GetSessions(claims *jwt.Claims) ([]models.Session, error) {
+	sessions, err := s.store.GetSessions(claims.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetSession
+	}
+
+	active := []models.Session{}
+	for _, session := range sessions {
+		if session.IsExpired() {
+			continue
+		}
+
+		session.Current = session.ID == claims.SessionID
+		active = append(active, session)
+	}
+
+	return active, nil
+}
+
+// RevokeSession revokes the session identified by id of the user identified by username
+func (s AuthService) RevokeSession(username, id string) error {
+	err := s.revokeSession(username, id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return errors.ErrNoSuchSession
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrDeleteSession
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' revoked a session", username))
+	return nil
+}
+
+// RevokeSessions revokes all sessions of the user identified by username, except the
+// session identified by keep
+func (s AuthService) RevokeSessions(username, keep string) error {
+	sessions, err := s.store.GetSessions(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrGetSession
+	}
+
+	for _, session := range sessions {
+		if session.ID == keep {
+			continue
+		}
+
+		err = s.revokeSession(username, session.ID)
+		if err != nil && err != store.ErrNotFound {
+			s.logger.Errorc(authCtx, err)
+			return errors.ErrDeleteSession
+		}
+	}
+
+	return nil
+}
+
+// newSession creates a new session for a login of the user identified by username.
+// The device is described by the request
+func (s AuthService) newSession(username, id string, c echo.Context) error {
+	now := time.Now().UTC()
+	req := c.Request()
+
+	return s.store.CreateSession(&models.Session{
+		ID:         id,
+		Username:   username,
+		Name:       truncate(req.Header.Get(deviceNameHeader), 100),
+		IP:         truncate(c.RealIP(), 45),
+		UserAgent:  truncate(req.UserAgent(), 255),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.config.Router.RefreshTokenTTL.Duration),
+	})
+}
+
+// revokeSession deletes the session identified by id, revokes its refresh tokens and
+// closes its real-time connections
+func (s AuthService) revokeSession(username, id string) error {
+	err := s.store.DeleteSession(username, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.RevokeRefreshTokens(id)
+	if err != nil {
+		return err
+	}
+
+	for _, closer := range s.closers {
+		closer.CloseSession(id)
+	}
+
+	return nil
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/id"
+	"chapper.dev/server/internal/modules/jwt"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+	"chapper.dev/server/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// refreshTokenBytes is the number of random bytes of a refresh token
+const refreshTokenBytes = 32
+
+// Tokens is the pair of a short-lived access token and the refresh token which renews it
+type Tokens struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens. The used refresh token is
+// replaced and can't be used again. Using a replaced token again revokes all tokens
+// which were issued for the same login
+func (s AuthService) Refresh(c echo.Context) (*Tokens, error) {
+	token, err := s.bindRefreshToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Revoked {
+		return nil, errors.ErrInvalidRefreshToken
+	}
+
+	// A replaced token is only presented again if it was stolen, or if the legitimate
+	// client lost the response of a refresh. Both cases require a new login
+	if token.ReplacedBy != "" {
+		s.revokeFamily(token)
+		return nil, errors.ErrRefreshTokenReused
+	}
+
+	if token.IsExpired() {
+		s.logger.Infoc(authCtx, fmt.Sprintf("refresh token of user '%s' expired", token.Username))
+		return nil, errors.ErrInvalidRefreshToken
+	}
+
+	tokens, next, err := s.newTokens(token.Username, token.Family)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.RotateRefreshToken(token.Hash, next)
+	if err != nil {
+		if err == store.ErrNotFound {
+			// Another request rotated the token in the meantime
+			s.revokeFamily(token)
+			return nil, errors.ErrRefreshTokenReused
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrCreateRefreshToken
+	}
+
+	err = s.store.TouchSession(token.Family, time.Now().UTC(), next.ExpiresAt)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes the session of the provided refresh token, which revokes all tokens
+// which were issued for the same login
+func (s AuthService) Logout(c echo.Context) error {
+	token, err := s.bindRefreshToken(c)
+	if err != nil {
+		return err
+	}
+
+	err = s.revokeSession(token.Username, token.Family)
+	if err == store.ErrNotFound {
+		// The session was already revoked
+		err = s.store.RevokeRefreshTokens(token.Family)
+	}
+
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrRevokeRefreshToken
+	}
+
+	return nil
+}
+
+// issueTokens returns a new pair of tokens for a new login of the user identified by
+// username. The login is recorded as a session of the device which sent the request.
+// The failed login attempts of the user are forgotten
+func (s AuthService) issueTokens(username string, c echo.Context) (*Tokens, error) {
+	s.resetAttempts(username)
+
+	family, err := id.New()
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrCreateRefreshToken
+	}
+
+	err = s.newSession(username, family, c)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrCreateSession
+	}
+
+	tokens, refreshToken, err := s.newTokens(username, family)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.CreateRefreshToken(refreshToken)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrCreateRefreshToken
+	}
+
+	return tokens, nil
+}
+
+// newTokens signs a new access token and generates a new refresh token of the provided
+// family. The access token carries the family as session ID. The refresh token is not
+// saved
+func (s AuthService) newTokens(username, family string) (*Tokens, *models.RefreshToken, error) {
+	// Disabled users get no new tokens, which also ends logins from before they were disabled
+	account, err := s.store.GetUser(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, nil, errors.ErrGetUser
+	}
+
+	if account.Disabled {
+		return nil, nil, errors.ErrUserDisabled
+	}
+
+	// Get the effective privileges of all assigned roles
+	privileges, err := s.users.GetPrivileges(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, nil, errors.ErrGetRole
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.config.Router.AccessTokenTTL.Duration)
+
+	// Sign a new JWT token with the active key of the keyring
+	signedToken, err := s.keys.Sign(&jwt.Claims{
+		Username:   username,
+		SessionID:  family,
+		Privileges: privileges,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, nil, errors.ErrSignToken
+	}
+
+	refreshToken, err := utils.RandomCryptoString(refreshTokenBytes)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, nil, errors.ErrCreateRefreshToken
+	}
+
+	tokens := &Tokens{
+		AccessToken:  signedToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}
+
+	return tokens, &models.RefreshToken{
+		Hash:      hashToken(refreshToken),
+		Family:    family,
+		Username:  username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.Router.RefreshTokenTTL.Duration),
+	}, nil
+}
+
+// bindRefreshToken returns the saved refresh token which matches the one in the
+// request body
+func (s AuthService) bindRefreshToken(c echo.Context) (*models.RefreshToken, error) {
+	var req refreshRequest
+
+	err := c.Bind(&req)
+	if err != nil || req.RefreshToken == "" {
+		return nil, errors.ErrMissingRefreshToken
+	}
+
+	token, err := s.store.GetRefreshToken(hashToken(req.RefreshToken))
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, errors.ErrInvalidRefreshToken
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetRefreshToken
+	}
+
+	return token, nil
+}
+
+// revokeFamily revokes the session of token and all refresh tokens of the same family
+func (s AuthService) revokeFamily(token *models.RefreshToken) {
+	s.logger.Infoc(authCtx, fmt.Sprintf("reuse of refresh token of user '%s' detected, revoking login", token.Username))
+
+	err := s.revokeSession(token.Username, token.Family)
+	if err == store.ErrNotFound {
+		err = s.store.RevokeRefreshTokens(token.Family)
+	}
+
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+	}
+}
+
+// hashToken returns the hex encoded SHA-256 hash of a random token. The tokens have
+// enough entropy, so a fast hash is sufficient
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
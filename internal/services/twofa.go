@@ -0,0 +1,485 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"time"
+
+	"chapper.dev/server/internal/constants"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/hash"
+	"chapper.dev/server/internal/modules/twofa"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// challengeTTL is the time users have to provide their 2FA code after they
+	// provided their password
+	challengeTTL = 5 * time.Minute
+
+	// qrCodeSize is the width and height of the QR code in pixels
+	qrCodeSize = 256
+
+	// recoveryCodeCount is the number of recovery codes generated at once
+	recoveryCodeCount = 10
+)
+
+// recoveryCodeHashConfig is used to hash recovery codes. The codes are random, so the
+// hash can be a lot cheaper than the one of passwords
+var recoveryCodeHashConfig = hash.Argon2Config{
+	Memory:      8 * constants.Argon2Kibibyte,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// TwoFAEnrollment holds the data a user needs to add the TOTP secret to an
+// authenticator app
+type TwoFAEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+	Image  string `json:"image"`
+}
+
+// Challenge is returned by the login of users with a second factor. It lists the
+// methods which can be used to complete the login
+type Challenge struct {
+	Challenge string   `json:"challenge"`
+	Methods   []string `json:"methods"`
+}
+
+const (
+	// MethodTOTP completes the login with a TOTP or recovery code
+	MethodTOTP = "totp"
+
+	// MethodWebAuthn completes the login with a WebAuthn assertion
+	MethodWebAuthn = "webauthn"
+)
+
+type codeRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+type confirmTwoFARequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// RegisterTwoFA starts the 2FA enrollment of the user identified by username. The new
+// secret is only used after the user confirmed it with ActivateTwoFA. Starting a new
+// enrollment replaces a pending one
+func (s AuthService) RegisterTwoFA(username string) (*TwoFAEnrollment, error) {
+	account, err := s.store.GetUser(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	if account.UsesTwoFA() {
+		return nil, errors.ErrTwoFAEnabled
+	}
+
+	options := s.config.Router.OTPOptions()
+	key, err := s.GenerateTOTP(s.config.Router.OTPIssuer, account.Username, options)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGenerateTOTP
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGenerateTOTP
+	}
+
+	buf := new(bytes.Buffer)
+	err = png.Encode(buf, img)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGenerateTOTP
+	}
+
+	err = s.store.UpdateTwoFAVerify(account.Username, key.Secret(), models.TOTPParams{
+		Algorithm: options.Algorithm,
+		Digits:    options.Digits,
+		Period:    options.Period,
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrUpdateVerifyToken
+	}
+
+	return &TwoFAEnrollment{
+		Secret: key.Secret(),
+		URL:    key.URL(),
+		Image:  "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// ActivateTwoFA enables 2FA for the user identified by username if the code in the
+// request body is valid for the secret of the pending enrollment. It returns the new
+// recovery codes of the user
+func (s AuthService) ActivateTwoFA(username string, c echo.Context) ([]string, error) {
+	var req codeRequest
+
+	err := c.Bind(&req)
+	if err != nil || req.Code == "" {
+		return nil, errors.ErrMissingCode
+	}
+
+	account, err := s.store.GetUser(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	if account.UsesTwoFA() {
+		return nil, errors.ErrTwoFAEnabled
+	}
+
+	if !account.EnrollsTwoFA() {
+		return nil, errors.ErrNoTwoFAEnrollment
+	}
+
+	if !s.validateTOTP(&account, account.TwoFAVerify.String, req.Code) {
+		return nil, errors.ErrInvalidCode
+	}
+
+	// Generate the codes first, so that 2FA is never enabled without recovery codes
+	codes, err := s.newRecoveryCodes(account.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.UpdateTwoFASecret(account.Username, account.TwoFAVerify.String)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrUpdateUser
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' enabled 2FA", account.Username))
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user identified by
+// username and returns the new ones. Like for DisableTwoFA, the request body has to
+// contain either the password of the user, a valid 2FA code or a recovery code
+func (s AuthService) RegenerateRecoveryCodes(username string, c echo.Context) ([]string, error) {
+	account, err := s.confirmTwoFA(username, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(account.Username)
+}
+
+// DisableTwoFA disables 2FA for the user identified by username and removes the
+// recovery codes. The request body has to contain either the password of the user, a
+// valid 2FA code or a recovery code
+func (s AuthService) DisableTwoFA(username string, c echo.Context) error {
+	account, err := s.confirmTwoFA(username, c)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.UpdateTwoFASecret(account.Username, "")
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrUpdateUser
+	}
+
+	err = s.store.SetRecoveryCodes(account.Username, nil)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrUpdateRecoveryCodes
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' disabled 2FA", account.Username))
+	return nil
+}
+
+// LoginCode exchanges the challenge returned by Login and a valid 2FA or recovery code
+// for tokens. A challenge can be used until it expires or a valid code was provided
+func (s AuthService) LoginCode(c echo.Context) (*Tokens, error) {
+	var req codeRequest
+
+	err := c.Bind(&req)
+	if err != nil || req.Challenge == "" || req.Code == "" {
+		return nil, errors.ErrMissingCode
+	}
+
+	err = s.beginAttempt("", c)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.getChallenge(req.Challenge, models.ChallengePassword)
+	if err != nil {
+		return nil, err
+	}
+	s.endAttempt("", c)
+
+	// The code is counted as an attempt of the user as well
+	err = s.beginAttempt(challenge.Username, c)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.store.GetUser(challenge.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	if !account.UsesTwoFA() || !s.validateCode(&account, req.Code) {
+		return nil, errors.ErrInvalidCode
+	}
+	s.endAttempt(challenge.Username, c)
+
+	err = s.useChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(account.Username, c)
+}
+
+// newChallenge creates a new challenge of the provided kind for the user identified by
+// username. The session of a WebAuthn ceremony can be attached to the challenge
+func (s AuthService) newChallenge(username, kind, session string) (string, error) {
+	challenge, err := s.GenerateVerifyToken()
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return "", errors.ErrCreateVerifyToken
+	}
+
+	err = s.store.CreateLoginChallenge(&models.LoginChallenge{
+		Hash:      hashToken(challenge),
+		Username:  username,
+		Kind:      kind,
+		Session:   session,
+		ExpiresAt: time.Now().UTC().Add(challengeTTL),
+	})
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return "", errors.ErrUpdateVerifyToken
+	}
+
+	return challenge, nil
+}
+
+// useChallenge deletes the challenge, so that it can't be used again. Only one of
+// multiple concurrent requests with the same challenge deletes it, the others receive
+// ErrInvalidChallenge
+func (s AuthService) useChallenge(challenge *models.LoginChallenge) error {
+	err := s.store.DeleteLoginChallenge(challenge.Hash)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Errorc(authCtx, err)
+		}
+		return errors.ErrInvalidChallenge
+	}
+
+	return nil
+}
+
+// getChallenge returns the saved challenge which matches token if it is not expired
+// and of one of the provided kinds
+func (s AuthService) getChallenge(token string, kinds ...string) (*models.LoginChallenge, error) {
+	hash := hashToken(token)
+	challenge, err := s.store.GetLoginChallenge(hash)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Errorc(authCtx, err)
+		}
+		return nil, errors.ErrInvalidChallenge
+	}
+
+	if challenge.IsExpired() {
+		s.store.DeleteLoginChallenge(hash)
+		return nil, errors.ErrInvalidChallenge
+	}
+
+	for _, kind := range kinds {
+		if challenge.Kind == kind {
+			return challenge, nil
+		}
+	}
+
+	return nil, errors.ErrInvalidChallenge
+}
+
+// confirmTwoFA binds the request body and checks the password, 2FA code or recovery
+// code of the user identified by username, who has to use 2FA
+func (s AuthService) confirmTwoFA(username string, c echo.Context) (*models.User, error) {
+	var req confirmTwoFARequest
+
+	err := c.Bind(&req)
+	if err != nil || (req.Password == "" && req.Code == "") {
+		return nil, errors.ErrMissingCode
+	}
+
+	account, err := s.confirmUser(username, req.Password, req.Code, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if !account.UsesTwoFA() {
+		return nil, errors.ErrTwoFADisabled
+	}
+
+	return account, nil
+}
+
+// confirmUser checks the password of the user identified by username. Without password
+// a 2FA code or recovery code is checked instead, if the user uses 2FA. Wrong passwords
+// and codes count as failed login attempts, so that a stolen access token doesn't allow
+// to guess them faster than a login would
+func (s AuthService) confirmUser(username, password, code string, c echo.Context) (*models.User, error) {
+	err := s.beginAttempt(username, c)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.store.GetUser(username)
+	if err != nil {
+		s.endAttempt(username, c)
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	if password != "" {
+		valid, err := s.ComparePassword(password, account.Password)
+		if !valid || err != nil {
+			return nil, errors.ErrInvalidPassword
+		}
+	} else if !account.UsesTwoFA() || !s.validateCode(&account, code) {
+		return nil, errors.ErrInvalidCode
+	}
+	s.endAttempt(username, c)
+	s.resetAttempts(username)
+
+	return &account, nil
+}
+
+// validateCode returns if code is a valid 2FA code or an unused recovery code of the
+// account. Valid recovery codes are used up
+func (s AuthService) validateCode(account *models.User, code string) bool {
+	if s.validateTOTP(account, account.TwoFASecret.String, code) {
+		return true
+	}
+
+	id := twofa.RecoveryCodeID(code)
+	if id == "" {
+		return false
+	}
+
+	recoveryCode, err := s.store.GetRecoveryCode(account.Username, id)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Errorc(authCtx, err)
+		}
+		return false
+	}
+
+	valid, err := s.codes.Valid(twofa.NormalizeRecoveryCode(code), recoveryCode.Hash)
+	if !valid || err != nil {
+		return false
+	}
+
+	// Only one of multiple concurrent requests with the same code can delete it
+	err = s.store.DeleteRecoveryCode(account.Username, id)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Errorc(authCtx, err)
+		}
+		return false
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' used a recovery code", account.Username))
+	return true
+}
+
+// validateTOTP returns if code is a valid TOTP code for secret with the TOTP parameters
+// of the account. Each code is only valid once, even if it is valid for multiple
+// periods
+func (s AuthService) validateTOTP(account *models.User, secret, code string) bool {
+	options := twofa.Options{
+		Algorithm: account.Algorithm,
+		Digits:    account.Digits,
+		Period:    account.Period,
+		Skew:      s.config.Router.OTPSkew,
+	}
+
+	step, ok := s.ValidateTOTP(code, secret, options)
+	if !ok {
+		return false
+	}
+
+	err := s.store.UpdateTwoFAStep(account.Username, step)
+	if err != nil {
+		if err == store.ErrNotFound {
+			s.logger.Infoc(authCtx, fmt.Sprintf("rejected reused 2FA code of user '%s'", account.Username))
+		} else {
+			s.logger.Errorc(authCtx, err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// newRecoveryCodes replaces the recovery codes of the user identified by username with
+// new ones and returns them
+func (s AuthService) newRecoveryCodes(username string) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashed := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	ids := make(map[string]bool)
+
+	for len(codes) < recoveryCodeCount {
+		code, err := twofa.GenerateRecoveryCode()
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return nil, errors.ErrUpdateRecoveryCodes
+		}
+
+		// The IDs of the codes of one user have to be unique
+		id := twofa.RecoveryCodeID(code)
+		if ids[id] {
+			continue
+		}
+		ids[id] = true
+
+		h, err := s.codes.Hash(twofa.NormalizeRecoveryCode(code))
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return nil, errors.ErrUpdateRecoveryCodes
+		}
+
+		codes = append(codes, code)
+		hashed = append(hashed, models.RecoveryCode{
+			Username: username,
+			ID:       id,
+			Hash:     h,
+		})
+	}
+
+	err := s.store.SetRecoveryCodes(username, hashed)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrUpdateRecoveryCodes
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"sync"
+	"testing"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store/memory"
+)
+
+// newTwoFATestService returns a service with the user alice, who uses 2FA and whose
+// password is 'alice-password', and the user bob, who doesn't use 2FA
+func newTwoFATestService(t *testing.T, configure func(*config.Config)) (AuthService, *memory.Store) {
+	s, m := newTestAuthService(t, configure)
+
+	for _, username := range []string{"alice", "bob"} {
+		password, err := s.HashPassword(username + "-password")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = s.users.CreateUser(models.PublicUser{Username: username, Password: password})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := m.UpdateTwoFASecret("alice", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, m
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	s, _ := newTwoFATestService(t, nil)
+
+	regenerate := func(username string, body map[string]string) ([]string, error) {
+		return s.RegenerateRecoveryCodes(username, newTestContext(t, body))
+	}
+
+	_, err := regenerate("alice", map[string]string{})
+	if err != errors.ErrMissingCode {
+		t.Errorf("RegenerateRecoveryCodes() without password or code error = %v, want %v", err, errors.ErrMissingCode)
+	}
+
+	_, err = regenerate("alice", map[string]string{"password": "wrong"})
+	if err != errors.ErrInvalidPassword {
+		t.Errorf("RegenerateRecoveryCodes() with a wrong password error = %v, want %v", err, errors.ErrInvalidPassword)
+	}
+
+	_, err = regenerate("alice", map[string]string{"code": "invalid"})
+	if err != errors.ErrInvalidCode {
+		t.Errorf("RegenerateRecoveryCodes() with a wrong code error = %v, want %v", err, errors.ErrInvalidCode)
+	}
+
+	_, err = regenerate("bob", map[string]string{"password": "bob-password"})
+	if err != errors.ErrTwoFADisabled {
+		t.Errorf("RegenerateRecoveryCodes() of bob error = %v, want %v", err, errors.ErrTwoFADisabled)
+	}
+
+	codes, err := regenerate("alice", map[string]string{"password": "alice-password"})
+	if err != nil || len(codes) < 2 {
+		t.Fatalf("RegenerateRecoveryCodes() with the password = %v, %v, want new codes", codes, err)
+	}
+
+	// A recovery code confirms the user as well and is replaced with all others
+	replaced, err := regenerate("alice", map[string]string{"code": codes[0]})
+	if err != nil || len(replaced) != len(codes) {
+		t.Fatalf("RegenerateRecoveryCodes() with a recovery code = %v, %v, want new codes", replaced, err)
+	}
+
+	_, err = regenerate("alice", map[string]string{"code": codes[1]})
+	if err != errors.ErrInvalidCode {
+		t.Errorf("RegenerateRecoveryCodes() with a replaced recovery code error = %v, want %v", err, errors.ErrInvalidCode)
+	}
+}
+
+// challengeBarrierStore lets every caller of GetLoginChallenge wait until all callers
+// read the challenge, so that concurrent requests race for the same challenge
+type challengeBarrierStore struct {
+	*memory.Store
+	read *sync.WaitGroup
+}
+
+func (s challengeBarrierStore) GetLoginChallenge(hash string) (*models.LoginChallenge, error) {
+	challenge, err := s.Store.GetLoginChallenge(hash)
+	s.read.Done()
+	s.read.Wait()
+	return challenge, err
+}
+
+func TestLoginCodeUsesChallengeOnce(t *testing.T) {
+	s, m := newTwoFATestService(t, nil)
+
+	codes, err := s.RegenerateRecoveryCodes("alice", newTestContext(t, map[string]string{"password": "alice-password"}))
+	if err != nil || len(codes) < 2 {
+		t.Fatalf("RegenerateRecoveryCodes() = %v, %v", codes, err)
+	}
+
+	_, challenge, err := s.Login(newTestContext(t, map[string]string{"username": "alice", "password": "alice-password"}))
+	if err != nil || challenge == nil {
+		t.Fatalf("Login() = %v, %v, want a challenge", challenge, err)
+	}
+
+	// Two valid recovery codes are sent with the same challenge at once
+	read := new(sync.WaitGroup)
+	read.Add(2)
+	s.store = challengeBarrierStore{m, read}
+
+	errs := make(chan error, 2)
+	for _, code := range codes[:2] {
+		go func(code string) {
+			_, err := s.LoginCode(newTestContext(t, map[string]string{
+				"challenge": challenge.Challenge,
+				"code":      code,
+			}))
+			errs <- err
+		}(code)
+	}
+
+	var logins int
+	for i := 0; i < 2; i++ {
+		err := <-errs
+		switch err {
+		case nil:
+			logins++
+		case errors.ErrInvalidChallenge:
+		default:
+			t.Errorf("LoginCode() error = %v, want nil or %v", err, errors.ErrInvalidChallenge)
+		}
+	}
+
+	if logins != 1 {
+		t.Errorf("%d logins with the same challenge succeeded, want one", logins)
+	}
+}
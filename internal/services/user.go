@@ -8,17 +8,18 @@ import (
 	"chapper.dev/server/internal/config"
 	"chapper.dev/server/internal/models"
 	"chapper.dev/server/internal/modules/avatar"
+	"chapper.dev/server/internal/services/errors"
 	"chapper.dev/server/internal/store"
 )
 
 // UserService is the top-level service struct
 type UserService struct {
-	store  *store.Store
+	store  store.Store
 	config *config.Config
 }
 
 // NewUserService returns a new user service
-func NewUserService(s *store.Store, c *config.Config) UserService {
+func NewUserService(s store.Store, c *config.Config) UserService {
 	return UserService{
 		store:  s,
 		config: c,
@@ -36,39 +37,100 @@ func (s UserService) GetUserPublicKey(username string) (string, error) {
 }
 
 // CreateUser creates a new 'user' or returns an error if the new user could not be
-// created
+// created. The first user of the instance gets the superadmin role, every other user
+// gets the basic role
 func (s UserService) CreateUser(user models.PublicUser) error {
-	// TODO <2020/10/09>: Can we optimize/improve this?
-	// settings, err := s.store.GetSettings()
-	// if err != nil {
-	// 	return err
-	// }
-
-	// if !settings.SuperadminExists {
-	// 	user.Role = append(user.Role, models.Superadmin())
-	// 	settings.SuperadminExists = true
-
-	// 	err := s.store.SetSettings(settings)
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// } else {
-	// 	user.Role = append(user.Role, models.Basic())
-	// }
-
-	err := s.store.CreateUser(user)
+	return s.CreateAccount(&store.Account{User: user})
+}
+
+// CreateAccount creates a new user like CreateUser. The identity and the bot owner of
+// the account are saved in the same transaction as the user. Bots never claim the
+// superadmin role, they get the basic role in the same transaction as well
+func (s UserService) CreateAccount(account *store.Account) error {
+	user := account.User
+
+	if account.BotOwner != "" {
+		role, err := s.store.GetRoleByName(models.Basic().Name)
+		if err != nil {
+			return errors.ErrGetRole
+		}
+		account.RoleID = role.ID
+	}
+
+	err := s.store.CreateAccount(account)
 	if err != nil {
-		return err
+		return errors.ErrCreateUser
+	}
+
+	if account.RoleID == 0 {
+		// Claim the superadmin role only after the user was created, so that a failed
+		// registration doesn't use up the claim
+		isSuperadmin, err := s.store.ClaimSuperadmin()
+		if err != nil {
+			return errors.ErrUpdateSettings
+		}
+
+		roleName := models.Basic().Name
+		if isSuperadmin {
+			roleName = models.Superadmin().Name
+		}
+
+		role, err := s.store.GetRoleByName(roleName)
+		if err != nil {
+			return errors.ErrGetRole
+		}
+
+		err = s.store.AssignRole(user.Username, role.ID)
+		if err != nil {
+			return errors.ErrAssignRole
+		}
 	}
 
 	a := avatar.New(240, user.Username)
-	return a.Generate(s.config.Router.AvatarPath)
+	err = a.Generate(s.config.Router.AvatarPath)
+	if err != nil {
+		return errors.ErrCreateAvatar
+	}
+
+	return nil
 }
 
-func (s UserService) UpdateTwoFAVerify(username, verify string) error {
-	return s.store.UpdateTwoFAVerify(username, verify)
+// RenameUser changes the username of the user identified by 'username' to 'newName'
+// and replaces the generated avatar, which is based on the username
+func (s UserService) RenameUser(username, newName string) error {
+	err := s.store.RenameUser(username, newName)
+	if err != nil {
+		if err == store.ErrDuplicate {
+			return errors.ErrUsernameTaken
+		}
+		return errors.ErrUpdateUser
+	}
+
+	err = avatar.Remove(s.config.Router.AvatarPath, username)
+	if err != nil {
+		return errors.ErrCreateAvatar
+	}
+
+	a := avatar.New(240, newName)
+	err = a.Generate(s.config.Router.AvatarPath)
+	if err != nil {
+		return errors.ErrCreateAvatar
+	}
+
+	return nil
+}
+
+// GetPrivileges returns the effective privileges of the user identified by 'username',
+// which are the merged privileges of all assigned roles
+func (s UserService) GetPrivileges(username string) (models.Privileges, error) {
+	roles, err := s.store.GetUserRoles(username)
+	if err != nil {
+		return models.Privileges{}, err
+	}
+
+	return models.MergeRoles(roles), nil
 }
 
-func (s UserService) PutUserServer(username string) ([]models.Server, error) {
-	return nil, nil
+func (s UserService) UpdateTwoFAVerify(username, verify string, params models.TOTPParams) error {
+	return s.store.UpdateTwoFAVerify(username, verify, params)
 }
@@ -0,0 +1,438 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/webauthn"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store"
+	"chapper.dev/server/internal/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// webAuthnIDBytes is the number of random bytes of a WebAuthn user handle
+const webAuthnIDBytes = 32
+
+// Ceremony is returned when a WebAuthn registration or login starts. The options are
+// passed to the browser and the challenge has to be sent back with the response of the
+// authenticator
+type Ceremony struct {
+	Challenge string      `json:"challenge"`
+	Options   interface{} `json:"options"`
+}
+
+type ceremonyRequest struct {
+	Challenge  string          `json:"challenge"`
+	Username   string          `json:"username"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+// BeginWebAuthnRegistration starts the registration of a new WebAuthn credential of the
+// user identified by username
+func (s AuthService) BeginWebAuthnRegistration(username string) (*Ceremony, error) {
+	if s.webauthn == nil {
+		return nil, errors.ErrWebAuthnUnavailable
+	}
+
+	account, err := s.store.GetUser(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	// The user handle is created with the first credential. It is independent of the
+	// username, so that renaming the user doesn't invalidate the credentials
+	if !account.WebAuthnID.Valid {
+		id, err := utils.RandomCryptoString(webAuthnIDBytes)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return nil, errors.ErrWebAuthn
+		}
+
+		err = s.store.UpdateWebAuthnID(account.Username, id)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return nil, errors.ErrUpdateUser
+		}
+		account.WebAuthnID.SetValid(id)
+	}
+
+	user, err := s.webAuthnUser(&account)
+	if err != nil {
+		return nil, err
+	}
+
+	options, session, err := s.webauthn.BeginRegistration(user)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrWebAuthn
+	}
+
+	challenge, err := s.newChallenge(account.Username, models.ChallengeWebAuthnRegister, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ceremony{
+		Challenge: challenge,
+		Options:   options,
+	}, nil
+}
+
+// FinishWebAuthnRegistration verifies the response of the authenticator and saves the
+// new credential of the user identified by username
+func (s AuthService) FinishWebAuthnRegistration(username string, c echo.Context) (*models.WebAuthnCredential, error) {
+	if s.webauthn == nil {
+		return nil, errors.ErrWebAuthnUnavailable
+	}
+
+	req, err := bindCeremony(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name == "" {
+		return nil, errors.ErrMissingWebAuthnData
+	}
+
+	challenge, err := s.getChallenge(req.Challenge, models.ChallengeWebAuthnRegister)
+	if err != nil {
+		return nil, err
+	}
+
+	// The challenge belongs to the user who started the registration
+	if challenge.Username != username {
+		return nil, errors.ErrInvalidChallenge
+	}
+
+	account, err := s.store.GetUser(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	user, err := s.webAuthnUser(&account)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.useChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webauthn.FinishRegistration(user, challenge.Session, bytes.NewReader(req.Credential))
+	if err != nil {
+		s.logger.Infoc(authCtx, fmt.Sprintf("WebAuthn registration of user '%s' failed: %v", username, err))
+		return nil, errors.ErrInvalidWebAuthnResponse
+	}
+
+	saved := &models.WebAuthnCredential{
+		ID:              encode(credential.ID),
+		Username:        account.Username,
+		Name:            req.Name,
+		PublicKey:       encode(credential.PublicKey),
+		AttestationType: credential.AttestationType,
+		AAGUID:          encode(credential.AAGUID),
+		SignCount:       credential.SignCount,
+		CreatedAt:       time.Now().UTC(),
+	}
+
+	err = s.store.CreateWebAuthnCredential(saved)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrCreateWebAuthnCredential
+	}
+
+	s.logger.Infoc(authCtx, fmt.Sprintf("user '%s' registered WebAuthn credential '%s'", username, req.Name))
+	return saved, nil
+}
+
+// GetWebAuthnCredentials returns the WebAuthn credentials of the user identified by
+// username
+func (s AuthService) GetWebAuthnCredentials(username string) ([]models.WebAuthnCredential, error) {
+	credentials, err := s.store.GetWebAuthnCredentials(username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetWebAuthnCredential
+	}
+	return credentials, nil
+}
+
+// DeleteWebAuthnCredential deletes the WebAuthn credential identified by the path
+// parameter 'credential-id' of the user identified by username
+func (s AuthService) DeleteWebAuthnCredential(username string, c echo.Context) error {
+	id := c.Param("credential-id")
+	if id == "" {
+		return errors.ErrInvalidID
+	}
+
+	err := s.store.DeleteWebAuthnCredential(username, id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return errors.ErrNoSuchWebAuthnCredential
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return errors.ErrDeleteWebAuthnCredential
+	}
+
+	return nil
+}
+
+// BeginWebAuthnLogin starts a login with a WebAuthn assertion. If the request body
+// contains the challenge returned by Login, the assertion is used as second factor.
+// If it contains a username instead, the assertion is the only factor and the
+// authenticator has to verify the user. The response is the same whether or not the
+// user exists and has credentials
+func (s AuthService) BeginWebAuthnLogin(c echo.Context) (*Ceremony, error) {
+	if s.webauthn == nil {
+		return nil, errors.ErrWebAuthnUnavailable
+	}
+
+	req, err := bindCeremony(c)
+	if err != nil && err != errors.ErrMissingWebAuthnData {
+		return nil, err
+	}
+
+	var challenge *models.LoginChallenge
+	username := req.Username
+
+	if req.Challenge != "" {
+		challenge, err = s.getChallenge(req.Challenge, models.ChallengePassword)
+		if err != nil {
+			return nil, err
+		}
+		username = challenge.Username
+	}
+
+	if username == "" {
+		return nil, errors.ErrMissingWebAuthnData
+	}
+
+	account, err := s.store.GetUser(username)
+	if err != nil && err != store.ErrNotFound {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	user := webauthn.User{Name: username}
+	if err == nil {
+		user, err = s.webAuthnUser(&account)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Unknown users and users without credentials get a decoy ceremony, which looks
+	// like a real one but can't be finished, so that the response doesn't reveal who
+	// has credentials
+	var (
+		options interface{}
+		session string
+	)
+	if len(user.Credentials) == 0 {
+		options, session, err = s.webauthn.BeginDecoyLogin(username, challenge == nil)
+	} else {
+		options, session, err = s.webauthn.BeginLogin(user, challenge == nil)
+	}
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrWebAuthn
+	}
+
+	// Attach the session to the challenge of the password login or start a new
+	// passwordless login
+	if challenge != nil {
+		err = s.store.UpdateLoginChallenge(challenge.Hash, session)
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return nil, errors.ErrUpdateVerifyToken
+		}
+
+		return &Ceremony{
+			Challenge: req.Challenge,
+			Options:   options,
+		}, nil
+	}
+
+	token, err := s.newChallenge(username, models.ChallengePasswordless, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ceremony{
+		Challenge: token,
+		Options:   options,
+	}, nil
+}
+
+// FinishWebAuthnLogin verifies the response of the authenticator and exchanges the
+// challenge for tokens
+func (s AuthService) FinishWebAuthnLogin(c echo.Context) (*Tokens, error) {
+	if s.webauthn == nil {
+		return nil, errors.ErrWebAuthnUnavailable
+	}
+
+	req, err := bindCeremony(c)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.getChallenge(req.Challenge, models.ChallengePassword, models.ChallengePasswordless)
+	if err != nil {
+		return nil, err
+	}
+
+	// BeginWebAuthnLogin was not called for this challenge
+	if challenge.Session == "" {
+		return nil, errors.ErrInvalidChallenge
+	}
+
+	// Decoy ceremonies of unknown users fail like those of users without credentials
+	account, err := s.store.GetUser(challenge.Username)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, errors.ErrInvalidWebAuthnResponse
+		}
+
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetUser
+	}
+
+	user, err := s.webAuthnUser(&account)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webauthn.FinishLogin(user, challenge.Session, bytes.NewReader(req.Credential))
+	if err != nil {
+		s.logger.Infoc(authCtx, fmt.Sprintf("WebAuthn login of user '%s' failed: %v", account.Username, err))
+		return nil, errors.ErrInvalidWebAuthnResponse
+	}
+
+	if credential.CloneWarning {
+		s.logger.Infoc(authCtx, fmt.Sprintf("signature counter of a WebAuthn credential of user '%s' didn't increase, the authenticator might be cloned", account.Username))
+		return nil, errors.ErrInvalidWebAuthnResponse
+	}
+
+	// Passwordless logins skip Login, which checks this for all other logins. It is
+	// checked after the assertion, so that it doesn't reveal which users exist
+	if s.config.General.RequireVerifiedEmail && !account.EmailVerified {
+		return nil, errors.ErrEmailNotVerified
+	}
+
+	err = s.useChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.UpdateWebAuthnSignCount(encode(credential.ID), credential.SignCount)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+	}
+
+	return s.issueTokens(account.Username, c)
+}
+
+// secondFactors returns the methods the user can use as second factor
+func (s AuthService) secondFactors(account *models.User) ([]string, error) {
+	methods := []string{}
+	if account.UsesTwoFA() {
+		methods = append(methods, MethodTOTP)
+	}
+
+	credentials, err := s.store.GetWebAuthnCredentials(account.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return nil, errors.ErrGetWebAuthnCredential
+	}
+
+	if len(credentials) > 0 {
+		methods = append(methods, MethodWebAuthn)
+	}
+
+	return methods, nil
+}
+
+// webAuthnUser returns the user and its credentials in the form the WebAuthn ceremonies
+// expect
+func (s AuthService) webAuthnUser(account *models.User) (webauthn.User, error) {
+	user := webauthn.User{
+		Name: account.Username,
+	}
+
+	if !account.WebAuthnID.Valid {
+		return user, nil
+	}
+
+	id, err := decode(account.WebAuthnID.String)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return user, errors.ErrWebAuthn
+	}
+	user.ID = id
+
+	credentials, err := s.store.GetWebAuthnCredentials(account.Username)
+	if err != nil {
+		s.logger.Errorc(authCtx, err)
+		return user, errors.ErrGetWebAuthnCredential
+	}
+
+	for _, c := range credentials {
+		credential := webauthn.Credential{
+			AttestationType: c.AttestationType,
+			SignCount:       c.SignCount,
+		}
+
+		credential.ID, err = decode(c.ID)
+		if err == nil {
+			credential.PublicKey, err = decode(c.PublicKey)
+		}
+		if err == nil {
+			credential.AAGUID, err = decode(c.AAGUID)
+		}
+		if err != nil {
+			s.logger.Errorc(authCtx, err)
+			return user, errors.ErrWebAuthn
+		}
+
+		user.Credentials = append(user.Credentials, credential)
+	}
+
+	return user, nil
+}
+
+func bindCeremony(c echo.Context) (ceremonyRequest, error) {
+	var req ceremonyRequest
+
+	err := c.Bind(&req)
+	if err != nil {
+		return req, errors.ErrMissingWebAuthnData
+	}
+
+	if req.Challenge == "" || len(req.Credential) == 0 {
+		return req, errors.ErrMissingWebAuthnData
+	}
+
+	return req, nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/modules/webauthn/webauthntest"
+	"chapper.dev/server/internal/services/errors"
+	"chapper.dev/server/internal/store/memory"
+)
+
+// newWebAuthnTestService returns a service with the user alice, whose password is
+// 'alice-password', and an authenticator of the relying party without credentials
+func newWebAuthnTestService(t *testing.T) (AuthService, *memory.Store, *webauthntest.Authenticator) {
+	s, m := newTestAuthService(t, nil)
+
+	password, err := s.HashPassword("alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.users.CreateUser(models.PublicUser{Username: "alice", Password: password})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, m, webauthntest.New(s.config.Router.WebAuthnOrigin)
+}
+
+// registerWebAuthn registers a new credential of the authenticator for the user
+func registerWebAuthn(t *testing.T, s AuthService, a *webauthntest.Authenticator, username string) {
+	ceremony, err := s.BeginWebAuthnRegistration(username)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration() error = %v", err)
+	}
+
+	response, err := a.Register(marshalTest(t, ceremony.Options))
+	if err != nil {
+		t.Fatalf("authenticator failed to register: %v", err)
+	}
+
+	_, err = s.FinishWebAuthnRegistration(username, newTestContext(t, map[string]interface{}{
+		"challenge":  ceremony.Challenge,
+		"name":       "Security key",
+		"credential": json.RawMessage(response),
+	}))
+	if err != nil {
+		t.Fatalf("FinishWebAuthnRegistration() error = %v", err)
+	}
+}
+
+// finishWebAuthn asserts the credential of the authenticator for the ceremony and
+// exchanges the challenge for tokens
+func finishWebAuthn(t *testing.T, s AuthService, a *webauthntest.Authenticator, ceremony *Ceremony) (*Tokens, error) {
+	response, err := a.Login(marshalTest(t, ceremony.Options))
+	if err != nil {
+		t.Fatalf("authenticator failed to login: %v", err)
+	}
+
+	return s.FinishWebAuthnLogin(newTestContext(t, map[string]interface{}{
+		"challenge":  ceremony.Challenge,
+		"credential": json.RawMessage(response),
+	}))
+}
+
+func marshalTest(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestWebAuthnSecondFactor(t *testing.T) {
+	s, _, a := newWebAuthnTestService(t)
+	registerWebAuthn(t, s, a, "alice")
+
+	tokens, challenge, err := s.Login(newTestContext(t, map[string]string{
+		"username": "alice",
+		"password": "alice-password",
+	}))
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if tokens != nil || challenge == nil || len(challenge.Methods) != 1 || challenge.Methods[0] != MethodWebAuthn {
+		t.Fatalf("Login() = %v, %+v, want a challenge with method webauthn", tokens, challenge)
+	}
+
+	ceremony, err := s.BeginWebAuthnLogin(newTestContext(t, map[string]string{
+		"challenge": challenge.Challenge,
+	}))
+	if err != nil {
+		t.Fatalf("BeginWebAuthnLogin() error = %v", err)
+	}
+
+	// The authenticator doesn't verify the user, the password was the first factor
+	a.UserVerified = false
+
+	tokens, err = finishWebAuthn(t, s, a, ceremony)
+	if err != nil {
+		t.Fatalf("FinishWebAuthnLogin() error = %v", err)
+	}
+
+	if tokens.AccessToken == "" {
+		t.Error("FinishWebAuthnLogin() returned no tokens")
+	}
+
+	// The challenge is used up
+	_, err = finishWebAuthn(t, s, a, ceremony)
+	if err != errors.ErrInvalidChallenge {
+		t.Errorf("FinishWebAuthnLogin() with a used challenge error = %v, want %v", err, errors.ErrInvalidChallenge)
+	}
+}
+
+func TestWebAuthnPasswordless(t *testing.T) {
+	s, m, a := newWebAuthnTestService(t)
+	registerWebAuthn(t, s, a, "alice")
+	clone := a.Clone()
+
+	begin := func() *Ceremony {
+		ceremony, err := s.BeginWebAuthnLogin(newTestContext(t, map[string]string{
+			"username": "alice",
+		}))
+		if err != nil {
+			t.Fatalf("BeginWebAuthnLogin() error = %v", err)
+		}
+		return ceremony
+	}
+
+	_, err := finishWebAuthn(t, s, a, begin())
+	if err != nil {
+		t.Fatalf("FinishWebAuthnLogin() error = %v", err)
+	}
+
+	credentials, err := m.GetWebAuthnCredentials("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(credentials) != 1 || credentials[0].SignCount != 1 {
+		t.Errorf("credentials = %+v, want one with signature counter 1", credentials)
+	}
+
+	// Without password the authenticator has to verify the user
+	a.UserVerified = false
+
+	_, err = finishWebAuthn(t, s, a, begin())
+	if err != errors.ErrInvalidWebAuthnResponse {
+		t.Errorf("FinishWebAuthnLogin() without user verification error = %v, want %v", err, errors.ErrInvalidWebAuthnResponse)
+	}
+
+	// The clone still has the counter of the registration
+	_, err = finishWebAuthn(t, s, clone, begin())
+	if err != errors.ErrInvalidWebAuthnResponse {
+		t.Errorf("FinishWebAuthnLogin() with a cloned authenticator error = %v, want %v", err, errors.ErrInvalidWebAuthnResponse)
+	}
+}
+
+func TestBeginWebAuthnLoginDecoy(t *testing.T) {
+	s, _, a := newWebAuthnTestService(t)
+	registerWebAuthn(t, s, a, "alice")
+
+	password, err := s.HashPassword("bob-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.users.CreateUser(models.PublicUser{Username: "bob", Password: password})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	real, err := s.BeginWebAuthnLogin(newTestContext(t, map[string]string{"username": "alice"}))
+	if err != nil {
+		t.Fatalf("BeginWebAuthnLogin() of alice error = %v", err)
+	}
+
+	// bob has no credentials, nobody doesn't exist. Both get a ceremony like alice,
+	// which fails like a response of the wrong authenticator
+	for _, username := range []string{"bob", "nobody"} {
+		ceremony, err := s.BeginWebAuthnLogin(newTestContext(t, map[string]string{"username": username}))
+		if err != nil {
+			t.Fatalf("BeginWebAuthnLogin() of %s error = %v", username, err)
+		}
+
+		if len(ceremony.Challenge) != len(real.Challenge) {
+			t.Errorf("challenge of %s looks different", username)
+		}
+
+		if string(shapeOf(t, ceremony.Options)) != string(shapeOf(t, real.Options)) {
+			t.Errorf("options of %s %s look different from %s", username, marshalTest(t, ceremony.Options), marshalTest(t, real.Options))
+		}
+
+		response, err := a.Login(marshalTest(t, real.Options))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = s.FinishWebAuthnLogin(newTestContext(t, map[string]interface{}{
+			"challenge":  ceremony.Challenge,
+			"credential": json.RawMessage(response),
+		}))
+		if err != errors.ErrInvalidWebAuthnResponse {
+			t.Errorf("FinishWebAuthnLogin() of %s error = %v, want %v", username, err, errors.ErrInvalidWebAuthnResponse)
+		}
+	}
+}
+
+// shapeOf returns the options encoded as JSON with all strings replaced by their
+// length, so that options of different ceremonies can be compared
+func shapeOf(t *testing.T, options interface{}) []byte {
+	var v interface{}
+	err := json.Unmarshal(marshalTest(t, options), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var shape func(interface{}) interface{}
+	shape = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				v[key] = shape(value)
+			}
+		case []interface{}:
+			for i, value := range v {
+				v[i] = shape(value)
+			}
+		case string:
+			return len(v)
+		}
+		return v
+	}
+
+	return marshalTest(t, shape(v))
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/models"
+)
+
+// CreatePersonalAccessToken inserts a new personal access token into the database
+func (s *SQL) CreatePersonalAccessToken(token *models.PersonalAccessToken) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		INSERT INTO personal_access_tokens
+		(id, hash, username, name, scopes, created_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		token.ID,
+		token.Hash,
+		token.Username,
+		token.Name,
+		token.Scopes,
+		token.CreatedBy,
+		token.CreatedAt,
+		token.ExpiresAt,
+	)
+	return err
+}
+
+// GetPersonalAccessToken selects ONE personal access token with provided 'hash' from
+// the database
+func (s *SQL) GetPersonalAccessToken(hash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := s.conn.Get(&token,
+		s.conn.Rebind(`SELECT id, hash, username, name, scopes, created_by, created_at,
+		last_used_at, expires_at
+		FROM personal_access_tokens
+		WHERE hash = ?`),
+		hash,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+// GetPersonalAccessTokens selects all personal access tokens of the user with provided
+// 'username' from the database
+func (s *SQL) GetPersonalAccessTokens(username string) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := s.conn.Select(&tokens,
+		s.conn.Rebind(`SELECT id, hash, username, name, scopes, created_by, created_at,
+		last_used_at, expires_at
+		FROM personal_access_tokens
+		WHERE username = ?
+		ORDER BY created_at`),
+		username,
+	)
+	return tokens, err
+}
+
+// TouchPersonalAccessToken updates the time ONE personal access token with provided
+// 'id' was last used
+func (s *SQL) TouchPersonalAccessToken(id string, lastUsedAt time.Time) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE personal_access_tokens
+		SET last_used_at = ?
+		WHERE id = ?`),
+		lastUsedAt,
+		id,
+	)
+	return err
+}
+
+// DeletePersonalAccessToken deletes ONE personal access token with provided 'id' of
+// the user with provided 'username' from the database
+func (s *SQL) DeletePersonalAccessToken(username, id string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM personal_access_tokens
+		WHERE username = ? AND id = ?`),
+		username,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePersonalAccessTokens deletes all personal access tokens of the user with
+// provided 'username' from the database
+func (s *SQL) DeletePersonalAccessTokens(username string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM personal_access_tokens
+		WHERE username = ?`),
+		username,
+	)
+	return err
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+const (
+	// AliasServer is the alias kind of legacy server hashes
+	AliasServer = "server"
+
+	// AliasRoom is the alias kind of legacy room hashes
+	AliasRoom = "room"
+)
+
+// ResolveAlias selects the ID which replaced the legacy hash 'alias' of the provided
+// 'kind' from the database
+func (s *SQL) ResolveAlias(kind, alias string) (string, error) {
+	var id string
+	err := s.conn.Get(&id,
+		s.conn.Rebind(`SELECT id
+		FROM aliases
+		WHERE kind = ? AND alias = ?`),
+		kind,
+		alias,
+	)
+	return id, notFound(err)
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import "chapper.dev/server/internal/models"
+
+// GetIdentity selects ONE identity with provided 'issuer' and 'subject' from the
+// database
+func (s *SQL) GetIdentity(issuer, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	err := s.conn.Get(&identity,
+		s.conn.Rebind(`SELECT issuer, subject, username, created_at
+		FROM identities
+		WHERE issuer = ? AND subject = ?`),
+		issuer,
+		subject,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &identity, nil
+}
+
+// GetIdentities selects all identities with provided 'issuer' from the database
+func (s *SQL) GetIdentities(issuer string) ([]models.Identity, error) {
+	var identities []models.Identity
+	err := s.conn.Select(&identities,
+		s.conn.Rebind(`SELECT issuer, subject, username, created_at
+		FROM identities
+		WHERE issuer = ?`),
+		issuer,
+	)
+	return identities, err
+}
@@ -9,11 +9,11 @@ import (
 )
 
 // CreateInvite creates a new invite
-func (s *Store) CreateInvite(invite *models.Invite) error {
-	_, err := s.conn.Exec(`
+func (s *SQL) CreateInvite(invite *models.Invite) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
 		INSERT INTO invites
 		(hash, created_by, server, one_time_use, expires_at)
-		VALUES (?, ?, ?, ?, ?)`,
+		VALUES (?, ?, ?, ?, ?)`),
 		invite.Hash,
 		invite.CreatedBy,
 		invite.Server,
@@ -22,3 +22,41 @@ func (s *Store) CreateInvite(invite *models.Invite) error {
 	)
 	return err
 }
+
+// GetInvite selects ONE invite with provided 'inviteHash' from the database
+func (s *SQL) GetInvite(inviteHash string) (*models.Invite, error) {
+	var invite models.Invite
+	err := s.conn.Get(&invite,
+		s.conn.Rebind(`SELECT hash, created_by, server, one_time_use, expires_at
+		FROM invites
+		WHERE hash = ?`),
+		inviteHash,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &invite, nil
+}
+
+// DeleteInvite deletes ONE invite with provided 'inviteHash' from the database. It
+// returns ErrNotFound if no invite was deleted
+func (s *SQL) DeleteInvite(inviteHash string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM invites
+		WHERE hash = ?`),
+		inviteHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateMailToken inserts a new mail token into the database and deletes all other
+// tokens of the same kind of the user
+func (s *SQL) CreateMailToken(token *models.MailToken) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(tx.Rebind(`
+			DELETE FROM mail_tokens
+			WHERE username = ? AND kind = ?`),
+			token.Username,
+			token.Kind,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(tx.Rebind(`
+			INSERT INTO mail_tokens
+			(hash, username, kind, email, expires_at)
+			VALUES (?, ?, ?, ?, ?)`),
+			token.Hash,
+			token.Username,
+			token.Kind,
+			token.Email,
+			token.ExpiresAt,
+		)
+		return err
+	})
+}
+
+// GetMailToken selects ONE mail token with provided 'hash' from the database
+func (s *SQL) GetMailToken(hash string) (*models.MailToken, error) {
+	var token models.MailToken
+	err := s.conn.Get(&token,
+		s.conn.Rebind(`SELECT hash, username, kind, email, expires_at
+		FROM mail_tokens
+		WHERE hash = ?`),
+		hash,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+// DeleteMailToken deletes ONE mail token with provided 'hash' and all expired tokens
+// from the database
+func (s *SQL) DeleteMailToken(hash string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM mail_tokens
+		WHERE hash = ?`),
+		hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	_, err = s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM mail_tokens
+		WHERE expires_at < ?`),
+		time.Now().UTC(),
+	)
+	return err
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AddMember inserts a new member of the server with provided 'serverHash' into the
+// database
+func (s *SQL) AddMember(serverHash, username string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		INSERT INTO members
+		(server, username, joined_at)
+		VALUES (?, ?, ?)`),
+		serverHash,
+		username,
+		time.Now().UTC(),
+	)
+	return err
+}
+
+// GetMember selects ONE member with provided 'username' of the server with provided
+// 'serverHash' from the database
+func (s *SQL) GetMember(serverHash, username string) (*models.Member, error) {
+	var member models.Member
+	err := s.conn.Get(&member,
+		s.conn.Rebind(`SELECT server, username, joined_at
+		FROM members
+		WHERE server = ? AND username = ?`),
+		serverHash,
+		username,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &member, nil
+}
+
+// GetMembers selects all members of the server with provided 'serverHash' from the
+// database
+func (s *SQL) GetMembers(serverHash string) ([]models.Member, error) {
+	var members []models.Member
+	err := s.conn.Select(&members,
+		s.conn.Rebind(`SELECT server, username, joined_at
+		FROM members
+		WHERE server = ?
+		ORDER BY joined_at, username`),
+		serverHash,
+	)
+	return members, err
+}
+
+// RemoveMember deletes ONE member with provided 'username' and its roles of the server
+// with provided 'serverHash' from the database
+func (s *SQL) RemoveMember(serverHash, username string) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(tx.Rebind(`
+			DELETE FROM user_roles
+			WHERE username = ? AND role_id IN (SELECT id FROM roles WHERE server = ?)`),
+			username,
+			serverHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(tx.Rebind(`
+			DELETE FROM members
+			WHERE server = ? AND username = ?`),
+			serverHash,
+			username,
+		)
+		return err
+	})
+}
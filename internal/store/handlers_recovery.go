@@ -0,0 +1,82 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"chapper.dev/server/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SetRecoveryCodes deletes all recovery codes of the user with provided 'username' and
+// inserts the new 'codes' into the database
+func (s *SQL) SetRecoveryCodes(username string, codes []models.RecoveryCode) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(tx.Rebind(`
+			DELETE FROM recovery_codes
+			WHERE username = ?`),
+			username,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, code := range codes {
+			_, err = tx.Exec(tx.Rebind(`
+				INSERT INTO recovery_codes
+				(username, id, hash)
+				VALUES (?, ?, ?)`),
+				username,
+				code.ID,
+				code.Hash,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetRecoveryCode selects ONE recovery code with provided 'id' of the user with
+// provided 'username' from the database
+func (s *SQL) GetRecoveryCode(username, id string) (*models.RecoveryCode, error) {
+	var code models.RecoveryCode
+	err := s.conn.Get(&code,
+		s.conn.Rebind(`SELECT username, id, hash
+		FROM recovery_codes
+		WHERE username = ? AND id = ?`),
+		username,
+		id,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &code, nil
+}
+
+// DeleteRecoveryCode deletes ONE recovery code with provided 'id' of the user with
+// provided 'username' from the database
+func (s *SQL) DeleteRecoveryCode(username, id string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM recovery_codes
+		WHERE username = ? AND id = ?`),
+		username,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
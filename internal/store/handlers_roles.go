@@ -0,0 +1,257 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"chapper.dev/server/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// roleColumns selects a role joined with its privileges. The privileges columns are
+// prefixed so that sqlx scans them into the nested Privileges struct
+const roleColumns = `
+	roles.id, roles.server, roles.name, roles.description, roles.builtin,
+	privileges.id AS "privileges.id",
+	privileges.role_id AS "privileges.role_id",
+	privileges.can_create_server AS "privileges.can_create_server",
+	privileges.can_delete_server AS "privileges.can_delete_server",
+	privileges.can_edit_server AS "privileges.can_edit_server",
+	privileges.can_see_all_servers AS "privileges.can_see_all_servers",
+	privileges.can_create_room AS "privileges.can_create_room",
+	privileges.can_delete_room AS "privileges.can_delete_room",
+	privileges.can_edit_room AS "privileges.can_edit_room",
+	privileges.can_create_invite AS "privileges.can_create_invite",
+	privileges.can_delete_invite AS "privileges.can_delete_invite",
+	privileges.can_kick_user_from_room AS "privileges.can_kick_user_from_room",
+	privileges.can_kick_user_from_server AS "privileges.can_kick_user_from_server",
+	privileges.can_ban_user_from_room AS "privileges.can_ban_user_from_room",
+	privileges.can_ban_user_from_server AS "privileges.can_ban_user_from_server",
+	privileges.can_create_role AS "privileges.can_create_role",
+	privileges.can_delete_role AS "privileges.can_delete_role",
+	privileges.can_assign_role_to_user AS "privileges.can_assign_role_to_user",
+	privileges.can_remove_role_from_user AS "privileges.can_remove_role_from_user"
+	FROM roles
+	JOIN privileges ON privileges.role_id = roles.id`
+
+// CreateRole inserts a new role and its privileges into the database. The ID of the
+// role is set to the generated ID
+func (s *SQL) CreateRole(role *models.Role) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		id, err := s.insert(tx, `
+			INSERT INTO roles
+			(server, name, description, builtin)
+			VALUES (?, ?, ?, ?)`,
+			role.Server,
+			role.Name,
+			role.Description,
+			role.Builtin,
+		)
+		if err != nil {
+			return err
+		}
+
+		role.ID = id
+		role.Privileges.RoleID = id
+
+		_, err = tx.NamedExec(`
+			INSERT INTO privileges (
+				role_id, can_create_server, can_delete_server, can_edit_server,
+				can_see_all_servers, can_create_room, can_delete_room, can_edit_room,
+				can_create_invite, can_delete_invite, can_kick_user_from_room,
+				can_kick_user_from_server, can_ban_user_from_room, can_ban_user_from_server,
+				can_create_role, can_delete_role, can_assign_role_to_user,
+				can_remove_role_from_user
+			) VALUES (
+				:role_id, :can_create_server, :can_delete_server, :can_edit_server,
+				:can_see_all_servers, :can_create_room, :can_delete_room, :can_edit_room,
+				:can_create_invite, :can_delete_invite, :can_kick_user_from_room,
+				:can_kick_user_from_server, :can_ban_user_from_room, :can_ban_user_from_server,
+				:can_create_role, :can_delete_role, :can_assign_role_to_user,
+				:can_remove_role_from_user
+			)`, role.Privileges)
+		return err
+	})
+}
+
+// GetRole selects ONE role with provided 'roleID' from the database
+func (s *SQL) GetRole(roleID uint) (*models.Role, error) {
+	var role models.Role
+	err := s.conn.Get(&role, s.conn.Rebind(`SELECT `+roleColumns+` WHERE roles.id = ?`), roleID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &role, nil
+}
+
+// GetRoleByName selects ONE global role with provided 'name' from the database
+func (s *SQL) GetRoleByName(name string) (*models.Role, error) {
+	var role models.Role
+	err := s.conn.Get(&role, s.conn.Rebind(`SELECT `+roleColumns+`
+		WHERE roles.server = '' AND roles.name = ?`),
+		name,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &role, nil
+}
+
+// GetRoles selects all global roles from the database
+func (s *SQL) GetRoles() ([]models.Role, error) {
+	var roles []models.Role
+	err := s.conn.Select(&roles, `SELECT `+roleColumns+` WHERE roles.server = '' ORDER BY roles.id`)
+	return roles, err
+}
+
+// GetServerRoles selects all roles of the server with provided 'serverHash' from the
+// database
+func (s *SQL) GetServerRoles(serverHash string) ([]models.Role, error) {
+	var roles []models.Role
+	err := s.conn.Select(&roles, s.conn.Rebind(`SELECT `+roleColumns+`
+		WHERE roles.server = ?
+		ORDER BY roles.id`),
+		serverHash,
+	)
+	return roles, err
+}
+
+// UpdateRole updates ONE role and its privileges with provided 'roleID' in the database
+func (s *SQL) UpdateRole(roleID uint, new *models.Role) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(tx.Rebind(`
+			UPDATE roles
+			SET name = ?, description = ?
+			WHERE id = ?`),
+			new.Name,
+			new.Description,
+			roleID,
+		)
+		if err != nil {
+			return err
+		}
+
+		new.Privileges.RoleID = roleID
+		_, err = tx.NamedExec(`
+			UPDATE privileges SET
+				can_create_server = :can_create_server,
+				can_delete_server = :can_delete_server,
+				can_edit_server = :can_edit_server,
+				can_see_all_servers = :can_see_all_servers,
+				can_create_room = :can_create_room,
+				can_delete_room = :can_delete_room,
+				can_edit_room = :can_edit_room,
+				can_create_invite = :can_create_invite,
+				can_delete_invite = :can_delete_invite,
+				can_kick_user_from_room = :can_kick_user_from_room,
+				can_kick_user_from_server = :can_kick_user_from_server,
+				can_ban_user_from_room = :can_ban_user_from_room,
+				can_ban_user_from_server = :can_ban_user_from_server,
+				can_create_role = :can_create_role,
+				can_delete_role = :can_delete_role,
+				can_assign_role_to_user = :can_assign_role_to_user,
+				can_remove_role_from_user = :can_remove_role_from_user
+			WHERE role_id = :role_id`, new.Privileges)
+		return err
+	})
+}
+
+// DeleteRole deletes ONE role, its privileges and all assignments with provided
+// 'roleID' from the database
+func (s *SQL) DeleteRole(roleID uint) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM user_roles WHERE role_id = ?`,
+			`DELETE FROM privileges WHERE role_id = ?`,
+			`DELETE FROM roles WHERE id = ?`,
+		} {
+			_, err := tx.Exec(tx.Rebind(query), roleID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetUserRoles selects all global roles assigned to the user with provided 'username'
+func (s *SQL) GetUserRoles(username string) ([]models.Role, error) {
+	return s.GetMemberRoles("", username)
+}
+
+// GetMemberRoles selects all roles of the server with provided 'serverHash' assigned to
+// the user with provided 'username'
+func (s *SQL) GetMemberRoles(serverHash, username string) ([]models.Role, error) {
+	var roles []models.Role
+	err := s.conn.Select(&roles, s.conn.Rebind(`SELECT `+roleColumns+`
+		JOIN user_roles ON user_roles.role_id = roles.id
+		WHERE user_roles.username = ? AND roles.server = ?
+		ORDER BY roles.id`),
+		username,
+		serverHash,
+	)
+	return roles, err
+}
+
+// AssignRole assigns the role with provided 'roleID' to the user with provided
+// 'username'
+func (s *SQL) AssignRole(username string, roleID uint) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		INSERT INTO user_roles
+		(username, role_id)
+		VALUES (?, ?)`),
+		username,
+		roleID,
+	)
+	return err
+}
+
+// RemoveRole removes the role with provided 'roleID' from the user with provided
+// 'username'
+func (s *SQL) RemoveRole(username string, roleID uint) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM user_roles
+		WHERE username = ? AND role_id = ?`),
+		username,
+		roleID,
+	)
+	return err
+}
+
+// RemoveRoleUnlessLast removes the role with provided 'roleID' from the user with
+// provided 'username' if other users hold the role as well
+func (s *SQL) RemoveRoleUnlessLast(username string, roleID uint) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		// Locks the role, so that concurrent removals can't both see another holder
+		_, err := tx.Exec(tx.Rebind(`UPDATE roles SET id = id WHERE id = ?`), roleID)
+		if err != nil {
+			return err
+		}
+
+		var others int
+		err = tx.Get(&others, tx.Rebind(`
+			SELECT COUNT(*)
+			FROM user_roles
+			WHERE role_id = ? AND username <> ?`),
+			roleID,
+			username,
+		)
+		if err != nil {
+			return err
+		}
+
+		if others == 0 {
+			return ErrLastHolder
+		}
+
+		_, err = tx.Exec(tx.Rebind(`
+			DELETE FROM user_roles
+			WHERE username = ? AND role_id = ?`),
+			username,
+			roleID,
+		)
+		return err
+	})
+}
@@ -9,12 +9,13 @@ import (
 )
 
 // CreateRoom inserts a new room entry into the database
-func (s *Store) CreateRoom(room *models.Room) error {
-	_, err := s.conn.Exec(`
+func (s *SQL) CreateRoom(room *models.Room) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
 		INSERT INTO rooms
-		(hash, name, type, description)
-		VALUES (?, ?, ?, ?)`,
+		(hash, server, name, type, description)
+		VALUES (?, ?, ?, ?, ?)`),
 		room.Hash,
+		room.Server,
 		room.Name,
 		room.Type,
 		room.Description,
@@ -23,30 +24,40 @@ func (s *Store) CreateRoom(room *models.Room) error {
 }
 
 // GetRoom selects ONE room entry with provided 'roomHash' from the database
-func (s *Store) GetRoom(roomHash string) (*models.Room, error) {
-	var room *models.Room
-	err := s.conn.Get(room,
-		`SELECT hash, name, type, description 
-		FROM rooms 
-		WHERE hash = ?`,
+func (s *SQL) GetRoom(roomHash string) (*models.Room, error) {
+	var room models.Room
+	err := s.conn.Get(&room,
+		s.conn.Rebind(`SELECT hash, server, name, type, description
+		FROM rooms
+		WHERE hash = ?`),
 		roomHash,
 	)
-	return room, err
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &room, nil
 }
 
-// GetRooms selects multiple room entries from the database
-func (s *Store) GetRooms() ([]models.Room, error) {
+// GetRooms selects all room entries of the server with provided 'serverHash' from the
+// database
+func (s *SQL) GetRooms(serverHash string) ([]models.Room, error) {
 	var rooms []models.Room
-	err := s.conn.Select(&rooms, `SELECT hash, name, type, description FROM rooms`)
+	err := s.conn.Select(&rooms,
+		s.conn.Rebind(`SELECT hash, server, name, type, description
+		FROM rooms
+		WHERE server = ?
+		ORDER BY name`),
+		serverHash,
+	)
 	return rooms, err
 }
 
 // UpdateRoom updates ONE room entry with provided 'roomHash' in the database
-func (s *Store) UpdateRoom(roomHash string, new *models.Room) error {
-	_, err := s.conn.Exec(`
+func (s *SQL) UpdateRoom(roomHash string, new *models.Room) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
 		UPDATE rooms
 		SET name = ?, type = ?, description = ?
-		WHERE hash = ?`,
+		WHERE hash = ?`),
 		new.Name,
 		new.Type,
 		new.Description,
@@ -56,10 +67,10 @@ func (s *Store) UpdateRoom(roomHash string, new *models.Room) error {
 }
 
 // DeleteRoom deletes ONE room entry with provided 'roomHash' from the database
-func (s *Store) DeleteRoom(roomHash string) error {
-	_, err := s.conn.Exec(`
+func (s *SQL) DeleteRoom(roomHash string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
 		DELETE FROM rooms
-		WHERE hash = ?`,
+		WHERE hash = ?`),
 		roomHash,
 	)
 	return err
@@ -6,14 +6,16 @@ package store
 
 import (
 	"chapper.dev/server/internal/models"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // CreateServer inserts a new server entry into the database
-func (s *Store) CreateServer(server *models.Server) error {
-	_, err := s.conn.Exec(`
+func (s *SQL) CreateServer(server *models.Server) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
 		INSERT INTO servers
 		(hash, name, description, image)
-		VALUES (?, ?, ?, ?)`,
+		VALUES (?, ?, ?, ?)`),
 		server.Hash,
 		server.Name,
 		server.Description,
@@ -23,30 +25,33 @@ func (s *Store) CreateServer(server *models.Server) error {
 }
 
 // GetServer selects ONE server entry with provided 'serverHash' from the database
-func (s *Store) GetServer(serverHash string) (*models.Server, error) {
-	var server *models.Server
-	err := s.conn.Get(server,
-		`SELECT hash, name, description, image
+func (s *SQL) GetServer(serverHash string) (*models.Server, error) {
+	var server models.Server
+	err := s.conn.Get(&server,
+		s.conn.Rebind(`SELECT hash, name, description, image
 		FROM servers
-		WHERE hash = ?`,
+		WHERE hash = ?`),
 		serverHash,
 	)
-	return server, err
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &server, nil
 }
 
 // GetServers selects multiple server entries from the database
-func (s *Store) GetServers() ([]models.Server, error) {
+func (s *SQL) GetServers() ([]models.Server, error) {
 	var servers []models.Server
-	err := s.conn.Select(&servers, `SELECT hash, name, description, imageFROM servers`)
+	err := s.conn.Select(&servers, `SELECT hash, name, description, image FROM servers`)
 	return servers, err
 }
 
 // UpdateServer updates ONE server entry with provided 'serverHash' in the database
-func (s *Store) UpdateServer(serverHash string, new *models.Server) error {
-	_, err := s.conn.Exec(`
+func (s *SQL) UpdateServer(serverHash string, new *models.Server) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
 		UPDATE servers
 		SET name = ?, description = ?, image = ?
-		WHERE hash = ?`,
+		WHERE hash = ?`),
 		new.Name,
 		new.Description,
 		new.Image,
@@ -55,12 +60,24 @@ func (s *Store) UpdateServer(serverHash string, new *models.Server) error {
 	return err
 }
 
-// DeleteServer deletes ONE server entry with provided 'serverHash' from the database
-func (s *Store) DeleteServer(serverHash string) error {
-	_, err := s.conn.Exec(`
-		DELETE FROM servers
-		WHERE hash = ?`,
-		serverHash,
-	)
-	return err
+// DeleteServer deletes ONE server entry with provided 'serverHash', its rooms, invites,
+// members and roles from the database
+func (s *SQL) DeleteServer(serverHash string) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM user_roles WHERE role_id IN (SELECT id FROM roles WHERE server = ?)`,
+			`DELETE FROM privileges WHERE role_id IN (SELECT id FROM roles WHERE server = ?)`,
+			`DELETE FROM roles WHERE server = ?`,
+			`DELETE FROM members WHERE server = ?`,
+			`DELETE FROM invites WHERE server = ?`,
+			`DELETE FROM rooms WHERE server = ?`,
+			`DELETE FROM servers WHERE hash = ?`,
+		} {
+			_, err := tx.Exec(tx.Rebind(query), serverHash)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,110 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateSession inserts a new session into the database and deletes all expired
+// sessions
+func (s *SQL) CreateSession(session *models.Session) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(tx.Rebind(`
+			DELETE FROM sessions
+			WHERE expires_at < ?`),
+			time.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(tx.Rebind(`
+			INSERT INTO sessions
+			(id, username, name, ip, user_agent, created_at, last_seen_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			session.ID,
+			session.Username,
+			session.Name,
+			session.IP,
+			session.UserAgent,
+			session.CreatedAt,
+			session.LastSeenAt,
+			session.ExpiresAt,
+		)
+		return err
+	})
+}
+
+// GetSession selects ONE session with provided 'id' from the database
+func (s *SQL) GetSession(id string) (*models.Session, error) {
+	var session models.Session
+	err := s.conn.Get(&session,
+		s.conn.Rebind(`SELECT id, username, name, ip, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE id = ?`),
+		id,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+// GetSessions selects all sessions of the user with provided 'username' from the
+// database, the most recently seen first
+func (s *SQL) GetSessions(username string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.conn.Select(&sessions,
+		s.conn.Rebind(`SELECT id, username, name, ip, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE username = ?
+		ORDER BY last_seen_at DESC`),
+		username,
+	)
+	return sessions, err
+}
+
+// TouchSession updates the time ONE session with provided 'id' was last seen and the
+// time it expires
+func (s *SQL) TouchSession(id string, lastSeenAt, expiresAt time.Time) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE sessions
+		SET last_seen_at = ?, expires_at = ?
+		WHERE id = ?`),
+		lastSeenAt,
+		expiresAt,
+		id,
+	)
+	return err
+}
+
+// DeleteSession deletes ONE session with provided 'id' of the user with provided
+// 'username' from the database
+func (s *SQL) DeleteSession(username, id string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM sessions
+		WHERE username = ? AND id = ?`),
+		username,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+// GetSettings returns the settings. The settings are cached after the first read
+func (s *SQL) GetSettings() (*Settings, error) {
+	s.settingsLock.RLock()
+	if s.settings != nil {
+		settings := *s.settings
+		s.settingsLock.RUnlock()
+		return &settings, nil
+	}
+	s.settingsLock.RUnlock()
+
+	var settings Settings
+	err := s.conn.Get(&settings,
+		`SELECT id, is_installed, superadmin_exists, instance_name, instance_description
+		FROM settings
+		WHERE id = 1`,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	s.settingsLock.Lock()
+	s.settings = &settings
+	s.settingsLock.Unlock()
+
+	return &settings, nil
+}
+
+// SetSettings saves the settings. SuperadminExists can only be changed via
+// ClaimSuperadmin
+func (s *SQL) SetSettings(settings *Settings) error {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE settings
+		SET is_installed = ?, instance_name = ?, instance_description = ?
+		WHERE id = 1`),
+		settings.IsInstalled,
+		settings.InstanceName,
+		settings.InstanceDescription,
+	)
+
+	// Invalidate the cache, the next read fetches the saved settings
+	s.settings = nil
+	return err
+}
+
+// ClaimSuperadmin atomically marks the superadmin as existing. It returns true if the
+// caller claimed the superadmin role, and false if a superadmin already existed
+func (s *SQL) ClaimSuperadmin() (bool, error) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+
+	res, err := s.conn.Exec(`
+		UPDATE settings
+		SET superadmin_exists = true
+		WHERE id = 1 AND superadmin_exists = false`,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	s.settings = nil
+
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/models"
+)
+
+// GetSigningKeys selects all keys of the keyring from the database
+func (s *SQL) GetSigningKeys() ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	err := s.conn.Select(&keys, `SELECT id, algorithm, private_key, created_at, retired_at
+		FROM signing_keys
+		ORDER BY created_at`,
+	)
+	return keys, err
+}
+
+// CreateSigningKey inserts a new key of the keyring into the database
+func (s *SQL) CreateSigningKey(key *models.SigningKey) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		INSERT INTO signing_keys
+		(id, algorithm, private_key, created_at, retired_at)
+		VALUES (?, ?, ?, ?, ?)`),
+		key.ID,
+		key.Algorithm,
+		key.PrivateKey,
+		key.CreatedAt,
+		key.RetiredAt,
+	)
+	return err
+}
+
+// RetireSigningKeys marks all keys except the one with provided 'id' as retired, which
+// are not retired yet
+func (s *SQL) RetireSigningKeys(id string, retiredAt time.Time) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE signing_keys
+		SET retired_at = ?
+		WHERE id <> ? AND retired_at IS NULL`),
+		retiredAt,
+		id,
+	)
+	return err
+}
+
+// DeleteSigningKeys deletes all keys which were retired before 'retiredBefore' from the
+// database
+func (s *SQL) DeleteSigningKeys(retiredBefore time.Time) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM signing_keys
+		WHERE retired_at < ?`),
+		retiredBefore,
+	)
+	return err
+}
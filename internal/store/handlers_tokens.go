@@ -0,0 +1,176 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateRefreshToken inserts a new refresh token into the database
+func (s *SQL) CreateRefreshToken(token *models.RefreshToken) error {
+	return createRefreshToken(s.conn, token)
+}
+
+// GetRefreshToken selects ONE refresh token with provided 'hash' from the database
+func (s *SQL) GetRefreshToken(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := s.conn.Get(&token,
+		s.conn.Rebind(`SELECT hash, family, username, replaced_by, revoked, created_at, expires_at
+		FROM refresh_tokens
+		WHERE hash = ?`),
+		hash,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken marks the refresh token with provided 'hash' as replaced and
+// inserts the 'next' token into the database. Only one of multiple concurrent
+// rotations of the same token succeeds
+func (s *SQL) RotateRefreshToken(hash string, next *models.RefreshToken) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		res, err := tx.Exec(tx.Rebind(`
+			UPDATE refresh_tokens
+			SET replaced_by = ?
+			WHERE hash = ? AND replaced_by = '' AND revoked = ?`),
+			next.Hash,
+			hash,
+			false,
+		)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return ErrNotFound
+		}
+
+		return createRefreshToken(tx, next)
+	})
+}
+
+// RevokeRefreshTokens revokes all refresh tokens with provided 'family'
+func (s *SQL) RevokeRefreshTokens(family string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE refresh_tokens
+		SET revoked = ?
+		WHERE family = ?`),
+		true,
+		family,
+	)
+	return err
+}
+
+// RevokeUserRefreshTokens revokes all refresh tokens of the user with provided
+// 'username'
+func (s *SQL) RevokeUserRefreshTokens(username string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE refresh_tokens
+		SET revoked = ?
+		WHERE username = ?`),
+		true,
+		username,
+	)
+	return err
+}
+
+func createRefreshToken(ext sqlx.Ext, token *models.RefreshToken) error {
+	_, err := ext.Exec(ext.Rebind(`
+		INSERT INTO refresh_tokens
+		(hash, family, username, replaced_by, revoked, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		token.Hash,
+		token.Family,
+		token.Username,
+		token.ReplacedBy,
+		token.Revoked,
+		token.CreatedAt,
+		token.ExpiresAt,
+	)
+	return err
+}
+
+// CreateLoginChallenge inserts a new login challenge into the database
+func (s *SQL) CreateLoginChallenge(challenge *models.LoginChallenge) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		INSERT INTO login_challenges
+		(hash, username, kind, session, expires_at)
+		VALUES (?, ?, ?, ?, ?)`),
+		challenge.Hash,
+		challenge.Username,
+		challenge.Kind,
+		challenge.Session,
+		challenge.ExpiresAt,
+	)
+	return err
+}
+
+// GetLoginChallenge selects ONE login challenge with provided 'hash' from the database
+func (s *SQL) GetLoginChallenge(hash string) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	err := s.conn.Get(&challenge,
+		s.conn.Rebind(`SELECT hash, username, kind, session, expires_at
+		FROM login_challenges
+		WHERE hash = ?`),
+		hash,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &challenge, nil
+}
+
+// UpdateLoginChallenge updates the WebAuthn session of ONE login challenge with
+// provided 'hash'
+func (s *SQL) UpdateLoginChallenge(hash, session string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE login_challenges
+		SET session = ?
+		WHERE hash = ?`),
+		session,
+		hash,
+	)
+	return err
+}
+
+// DeleteLoginChallenge deletes ONE login challenge with provided 'hash' and all expired
+// challenges from the database
+func (s *SQL) DeleteLoginChallenge(hash string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM login_challenges
+		WHERE hash = ?`),
+		hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	_, err = s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM login_challenges
+		WHERE expires_at < ?`),
+		time.Now().UTC(),
+	)
+	return err
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"testing"
+	"time"
+
+	"chapper.dev/server/internal/models"
+)
+
+func TestDeleteLoginChallenge(t *testing.T) {
+	s := newTestSQL(t, 0)
+
+	err := s.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for hash, expiresAt := range map[string]time.Time{
+		"valid":   time.Now().UTC().Add(time.Minute),
+		"other":   time.Now().UTC().Add(time.Minute),
+		"expired": time.Now().UTC().Add(-time.Minute),
+	} {
+		err = s.CreateLoginChallenge(&models.LoginChallenge{
+			Hash:      hash,
+			Username:  "alice",
+			Kind:      models.ChallengePassword,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = s.DeleteLoginChallenge("valid")
+	if err != nil {
+		t.Fatalf("DeleteLoginChallenge() error = %v", err)
+	}
+
+	// Only one of multiple requests with the same challenge deletes it
+	err = s.DeleteLoginChallenge("valid")
+	if err != ErrNotFound {
+		t.Errorf("DeleteLoginChallenge() of a deleted challenge error = %v, want %v", err, ErrNotFound)
+	}
+
+	_, err = s.GetLoginChallenge("expired")
+	if err != ErrNotFound {
+		t.Errorf("GetLoginChallenge() of an expired challenge error = %v, want %v", err, ErrNotFound)
+	}
+
+	_, err = s.GetLoginChallenge("other")
+	if err != nil {
+		t.Errorf("GetLoginChallenge() of another challenge error = %v", err)
+	}
+}
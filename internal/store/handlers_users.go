@@ -6,68 +6,333 @@ package store
 
 import (
 	"chapper.dev/server/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/guregu/null.v4"
 )
 
-func (s *Store) GetUser(username string) (models.User, error) {
+// usernameColumns lists all columns besides users.username which reference a user by
+// the username. RenameUser updates all of them
+var usernameColumns = []struct{ table, column string }{
+	{"user_roles", "username"},
+	{"members", "username"},
+	{"invites", "created_by"},
+	{"refresh_tokens", "username"},
+	{"login_challenges", "username"},
+	{"recovery_codes", "username"},
+	{"webauthn_credentials", "username"},
+	{"mail_tokens", "username"},
+	{"sessions", "username"},
+	{"identities", "username"},
+	{"users", "bot_owner"},
+	{"personal_access_tokens", "username"},
+	{"personal_access_tokens", "created_by"},
+}
+
+func (s *SQL) GetUser(username string) (models.User, error) {
 	var user models.User
 	// TODO <2020/10/12>: Join permissions
 	err := s.conn.Get(&user,
-		`SELECT username, password, email
+		s.conn.Rebind(`SELECT username, password, email, email_verified, disabled, bot, bot_owner, twofa_secret, twofa_verify, twofa_last_step,
+		twofa_algorithm, twofa_digits, twofa_period, webauthn_id
 		FROM users
-		WHERE username = ?`,
+		WHERE username = ?`),
 		username,
 	)
-	return user, err
+	return user, notFound(err)
 }
 
-func (s *Store) GetUserPublicKey(username string) (string, error) {
+func (s *SQL) GetUserPublicKey(username string) (string, error) {
 	var publicKey string
 	err := s.conn.Get(&publicKey,
-		`SELECT publickey
+		s.conn.Rebind(`SELECT publickey
 		FROM users
-		WHERE username = ?`,
+		WHERE username = ?`),
+		username,
+	)
+	return publicKey, notFound(err)
+}
+
+// GetUserServers selects all servers the user with provided 'username' is a member of
+func (s *SQL) GetUserServers(username string) ([]models.Server, error) {
+	var servers []models.Server
+	err := s.conn.Select(&servers, s.conn.Rebind(`
+		SELECT servers.hash, servers.name, servers.description, servers.image
+		FROM servers
+		JOIN members ON members.server = servers.hash
+		WHERE members.username = ?
+		ORDER BY members.joined_at`),
 		username,
 	)
-	return publicKey, err
+	return servers, err
 }
 
-func (s *Store) GetUserServers(username string) error {
-	// TODO <2020/10/12>: re-implement
+// CreateAccount inserts a new user, the identity and the role assignment of the
+// account, if set, into the database in one transaction
+func (s *SQL) CreateAccount(account *Account) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(tx.Rebind(`
+			INSERT INTO users
+			(username, password, email, publickey, bot, bot_owner)
+			VALUES (?, ?, ?, ?, ?, ?)`),
+			account.User.Username,
+			account.User.Password,
+			account.User.Email,
+			account.User.PublicKey,
+			account.BotOwner != "",
+			null.NewString(account.BotOwner, account.BotOwner != ""),
+		)
+		if err != nil {
+			return err
+		}
+
+		if account.Identity != nil {
+			_, err = tx.Exec(tx.Rebind(`
+				INSERT INTO identities
+				(issuer, subject, username, created_at)
+				VALUES (?, ?, ?, ?)`),
+				account.Identity.Issuer,
+				account.Identity.Subject,
+				account.Identity.Username,
+				account.Identity.CreatedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if account.RoleID != 0 {
+			_, err = tx.Exec(tx.Rebind(`
+				INSERT INTO user_roles
+				(username, role_id)
+				VALUES (?, ?)`),
+				account.User.Username,
+				account.RoleID,
+			)
+		}
+		return err
+	})
+}
+
+// UpdatePassword replaces the password hash of the user with provided 'username' if it
+// is still 'oldHash', otherwise it returns ErrNotFound
+func (s *SQL) UpdatePassword(username, oldHash, newHash string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE users
+		SET password = ?
+		WHERE username = ? AND password = ?`),
+		newHash,
+		username,
+		oldHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
 
-func (s *Store) CreateUser(user models.PublicUser) error {
-	_, err := s.conn.Exec(`
-		INSERT INTO users
-		(username, password, email, publickey)
-		VALUES (?, ?, ?, ?)`,
-		user.Username,
-		user.Password,
-		user.Email,
-		user.PublicKey,
+// UpdateEmail changes the email of the user with provided 'username' and marks it as
+// not verified
+func (s *SQL) UpdateEmail(username, email string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE users
+		SET email = ?, email_verified = ?
+		WHERE username = ?`),
+		email,
+		false,
+		username,
+	)
+	return err
+}
+
+// RenameUser changes the username of the user with provided 'username' to 'newName'
+// in the users table and in all tables which reference the user
+func (s *SQL) RenameUser(username, newName string) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		var n int
+		err := tx.Get(&n, tx.Rebind(`SELECT COUNT(*) FROM users WHERE username = ?`), newName)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			return ErrDuplicate
+		}
+
+		res, err := tx.Exec(tx.Rebind(`
+			UPDATE users
+			SET username = ?
+			WHERE username = ?`),
+			newName,
+			username,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return ErrNotFound
+		}
+
+		for _, c := range usernameColumns {
+			_, err = tx.Exec(tx.Rebind(`UPDATE `+c.table+` SET `+c.column+` = ? WHERE `+c.column+` = ?`),
+				newName,
+				username,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UpdateTwoFAVerify updates the pending 2FA enrollment of the user with provided
+// 'username' and resets the time step of the last used code
+func (s *SQL) UpdateTwoFAVerify(username, verify string, params models.TOTPParams) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE users
+		SET twofa_verify = ?, twofa_algorithm = ?, twofa_digits = ?, twofa_period = ?, twofa_last_step = 0
+		WHERE username = ?`),
+		null.NewString(verify, verify != ""),
+		params.Algorithm,
+		params.Digits,
+		params.Period,
+		username,
 	)
 	return err
 }
 
-func (s *Store) UpdateUser(username string, user *models.User) error {
-	_, err := s.conn.Exec(`
+// UpdateTwoFASecret updates the 2FA secret of the user with provided 'username' and
+// removes the pending enrollment. Removing the secret resets the time step of the last
+// used code
+func (s *SQL) UpdateTwoFASecret(username, secret string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
 		UPDATE users
-		SET password = ?, email = ?
-		WHERE username = ?`,
-		user.Password,
-		user.Email,
+		SET twofa_secret = ?, twofa_verify = NULL,
+		twofa_last_step = CASE WHEN ? THEN 0 ELSE twofa_last_step END
+		WHERE username = ?`),
+		null.NewString(secret, secret != ""),
+		secret == "",
 		username,
 	)
 	return err
 }
 
-func (s *Store) UpdateTwoFAVerify(username, verify string) error {
-	_, err := s.conn.Exec(`
+// UpdateTwoFAStep updates the time step of the last used 2FA code of the user with
+// provided 'username' if the new step is after the saved one
+func (s *SQL) UpdateTwoFAStep(username string, step uint64) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
 		UPDATE users
-		SET twofa_verify = ?
-		WHERE username = ?`,
-		verify,
+		SET twofa_last_step = ?
+		WHERE username = ? AND twofa_last_step < ?`),
+		step,
+		username,
+		step,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateWebAuthnID updates the WebAuthn user handle of the user with provided
+// 'username'
+func (s *SQL) UpdateWebAuthnID(username, id string) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE users
+		SET webauthn_id = ?
+		WHERE username = ?`),
+		id,
 		username,
 	)
 	return err
 }
+
+// UpdateEmailVerified marks the email of the user with provided 'username' as verified
+// if it still matches 'email'
+func (s *SQL) UpdateEmailVerified(username, email string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE users
+		SET email_verified = ?
+		WHERE username = ? AND email = ?`),
+		true,
+		username,
+		email,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateUserDisabled sets if the user with provided 'username' is disabled
+func (s *SQL) UpdateUserDisabled(username string, disabled bool) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE users
+		SET disabled = ?
+		WHERE username = ?`),
+		disabled,
+		username,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetBots selects all bots of the user with provided 'owner' from the database
+func (s *SQL) GetBots(owner string) ([]models.User, error) {
+	var bots []models.User
+	err := s.conn.Select(&bots,
+		s.conn.Rebind(`SELECT username, email, email_verified, disabled, bot, bot_owner
+		FROM users
+		WHERE bot = ? AND bot_owner = ?
+		ORDER BY username`),
+		true,
+		owner,
+	)
+	return bots, err
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import "testing"
+
+func TestUpdatePassword(t *testing.T) {
+	s := newTestSQL(t, 0)
+
+	err := s.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(t, s, "INSERT INTO users (username, password, publickey) VALUES ('bob', 'old', '')")
+
+	err = s.UpdatePassword("bob", "old", "new")
+	if err != nil {
+		t.Fatalf("UpdatePassword() error = %v", err)
+	}
+
+	// The password was changed since 'old' was read
+	err = s.UpdatePassword("bob", "old", "stale")
+	if err != ErrNotFound {
+		t.Errorf("UpdatePassword() of a changed password error = %v, want %v", err, ErrNotFound)
+	}
+
+	account, err := s.GetUser("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if account.Password != "new" {
+		t.Errorf("password = %q, want new", account.Password)
+	}
+}
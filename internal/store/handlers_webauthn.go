@@ -0,0 +1,82 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/models"
+)
+
+// CreateWebAuthnCredential inserts a new WebAuthn credential into the database
+func (s *SQL) CreateWebAuthnCredential(credential *models.WebAuthnCredential) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		INSERT INTO webauthn_credentials
+		(id, username, name, public_key, attestation_type, aaguid, sign_count, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		credential.ID,
+		credential.Username,
+		credential.Name,
+		credential.PublicKey,
+		credential.AttestationType,
+		credential.AAGUID,
+		credential.SignCount,
+		credential.CreatedAt,
+	)
+	return err
+}
+
+// GetWebAuthnCredentials selects all WebAuthn credentials of the user with provided
+// 'username' from the database
+func (s *SQL) GetWebAuthnCredentials(username string) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	err := s.conn.Select(&credentials,
+		s.conn.Rebind(`SELECT id, username, name, public_key, attestation_type, aaguid,
+		sign_count, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE username = ?
+		ORDER BY created_at`),
+		username,
+	)
+	return credentials, err
+}
+
+// UpdateWebAuthnSignCount updates the signature counter and the time of the last use
+// of ONE WebAuthn credential with provided 'id'
+func (s *SQL) UpdateWebAuthnSignCount(id string, signCount uint32) error {
+	_, err := s.conn.Exec(s.conn.Rebind(`
+		UPDATE webauthn_credentials
+		SET sign_count = ?, last_used_at = ?
+		WHERE id = ?`),
+		signCount,
+		time.Now().UTC(),
+		id,
+	)
+	return err
+}
+
+// DeleteWebAuthnCredential deletes ONE WebAuthn credential with provided 'id' of the
+// user with provided 'username' from the database
+func (s *SQL) DeleteWebAuthnCredential(username, id string) error {
+	res, err := s.conn.Exec(s.conn.Rebind(`
+		DELETE FROM webauthn_credentials
+		WHERE username = ? AND id = ?`),
+		username,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
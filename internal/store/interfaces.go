@@ -0,0 +1,396 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"chapper.dev/server/internal/models"
+)
+
+// Store combines all store interfaces. Services depend on this interface instead of a
+// concrete database, which allows to swap the SQL store with the in-memory store
+type Store interface {
+	UserStore
+	ServerStore
+	RoomStore
+	RoleStore
+	MemberStore
+	InviteStore
+	SettingsStore
+	AliasStore
+	RefreshTokenStore
+	ChallengeStore
+	RecoveryCodeStore
+	WebAuthnStore
+	MailTokenStore
+	SessionStore
+	SigningKeyStore
+	IdentityStore
+	PersonalAccessTokenStore
+}
+
+// Account is a new user which is created together with the link to its identity at an
+// external identity provider, its bot owner and its role
+type Account struct {
+	User models.PublicUser
+
+	// Identity links the user to an external identity provider, if set
+	Identity *models.Identity
+
+	// BotOwner makes the user a bot of the user identified by BotOwner, if set
+	BotOwner string
+
+	// RoleID is the role assigned to the user, if set
+	RoleID uint
+}
+
+// UserStore provides operations on users
+type UserStore interface {
+	// GetUser returns the user identified by username
+	GetUser(username string) (models.User, error)
+
+	// GetUserPublicKey returns the public key of the user identified by username
+	GetUserPublicKey(username string) (string, error)
+
+	// GetUserServers returns the servers the user identified by username is a member of
+	GetUserServers(username string) ([]models.Server, error)
+
+	// CreateAccount creates a new user, its identity and its role assignment in one
+	// transaction, so that users of identity providers are never left without their
+	// identity and bots never without their owner
+	CreateAccount(account *Account) error
+
+	// UpdatePassword replaces the password hash of the user identified by username. It
+	// returns ErrNotFound if the password hash is no longer oldHash, which means the
+	// password was changed since it was read
+	UpdatePassword(username, oldHash, newHash string) error
+
+	// UpdateEmail changes the email of the user identified by username and marks it as
+	// not verified
+	UpdateEmail(username, email string) error
+
+	// RenameUser changes the username of the user identified by username to newName
+	// in all places which reference the user. It returns ErrDuplicate if newName is
+	// taken
+	RenameUser(username, newName string) error
+
+	// UpdateTwoFAVerify updates the 2FA secret and TOTP parameters of the pending
+	// enrollment of the user identified by username. An empty verify removes the
+	// pending enrollment. The time step of the last used code is reset, because the
+	// codes of the new secret and parameters start a new sequence
+	UpdateTwoFAVerify(username, verify string, params models.TOTPParams) error
+
+	// UpdateTwoFASecret updates the 2FA secret of the user identified by username and
+	// removes the pending enrollment. An empty secret disables 2FA and resets the time
+	// step of the last used code. Enabling keeps it, so that the code which confirmed
+	// the enrollment can't be used again
+	UpdateTwoFASecret(username, secret string) error
+
+	// UpdateTwoFAStep saves the time step of the last used 2FA code of the user
+	// identified by username. It returns ErrNotFound if step is not after the saved
+	// one, which means the code was already used
+	UpdateTwoFAStep(username string, step uint64) error
+
+	// UpdateWebAuthnID sets the WebAuthn user handle of the user identified by username
+	UpdateWebAuthnID(username, id string) error
+
+	// UpdateEmailVerified marks the email of the user identified by username as
+	// verified. It returns ErrNotFound if the email of the user is no longer email
+	UpdateEmailVerified(username, email string) error
+
+	// UpdateUserDisabled sets if the user identified by username is disabled
+	UpdateUserDisabled(username string, disabled bool) error
+
+	// GetBots returns all bots of the user identified by owner
+	GetBots(owner string) ([]models.User, error)
+}
+
+// ServerStore provides operations on virtual servers
+type ServerStore interface {
+	// CreateServer creates a new virtual server
+	CreateServer(server *models.Server) error
+
+	// GetServer returns the virtual server identified by serverHash
+	GetServer(serverHash string) (*models.Server, error)
+
+	// GetServers returns all virtual servers
+	GetServers() ([]models.Server, error)
+
+	// UpdateServer updates the virtual server identified by serverHash
+	UpdateServer(serverHash string, new *models.Server) error
+
+	// DeleteServer deletes the virtual server identified by serverHash, its rooms,
+	// invites, members and roles
+	DeleteServer(serverHash string) error
+}
+
+// RoomStore provides operations on rooms
+type RoomStore interface {
+	// CreateRoom creates a new room
+	CreateRoom(room *models.Room) error
+
+	// GetRoom returns the room identified by roomHash
+	GetRoom(roomHash string) (*models.Room, error)
+
+	// GetRooms returns all rooms of the virtual server identified by serverHash
+	GetRooms(serverHash string) ([]models.Room, error)
+
+	// UpdateRoom updates the room identified by roomHash
+	UpdateRoom(roomHash string, new *models.Room) error
+
+	// DeleteRoom deletes the room identified by roomHash
+	DeleteRoom(roomHash string) error
+}
+
+// RoleStore provides operations on roles and their assignment to users. Roles are
+// either global or scoped to one virtual server
+type RoleStore interface {
+	// CreateRole creates a new role and sets its ID
+	CreateRole(role *models.Role) error
+
+	// GetRole returns the role identified by roleID
+	GetRole(roleID uint) (*models.Role, error)
+
+	// GetRoleByName returns the global role identified by name
+	GetRoleByName(name string) (*models.Role, error)
+
+	// GetRoles returns all global roles
+	GetRoles() ([]models.Role, error)
+
+	// GetServerRoles returns all roles of the virtual server identified by serverHash
+	GetServerRoles(serverHash string) ([]models.Role, error)
+
+	// UpdateRole updates the role identified by roleID
+	UpdateRole(roleID uint, new *models.Role) error
+
+	// DeleteRole deletes the role identified by roleID and all its assignments
+	DeleteRole(roleID uint) error
+
+	// GetUserRoles returns all global roles assigned to the user identified by username
+	GetUserRoles(username string) ([]models.Role, error)
+
+	// GetMemberRoles returns all roles of the virtual server identified by serverHash
+	// assigned to the user identified by username
+	GetMemberRoles(serverHash, username string) ([]models.Role, error)
+
+	// AssignRole assigns the role identified by roleID to the user identified by
+	// username
+	AssignRole(username string, roleID uint) error
+
+	// RemoveRole removes the role identified by roleID from the user identified by
+	// username
+	RemoveRole(username string, roleID uint) error
+
+	// RemoveRoleUnlessLast removes the role identified by roleID from the user
+	// identified by username like RemoveRole. It returns ErrLastHolder if the user is
+	// the only one who holds the role
+	RemoveRoleUnlessLast(username string, roleID uint) error
+}
+
+// MemberStore provides operations on the membership of users in virtual servers
+type MemberStore interface {
+	// AddMember adds the user identified by username to the virtual server identified
+	// by serverHash
+	AddMember(serverHash, username string) error
+
+	// GetMember returns the membership of the user identified by username in the
+	// virtual server identified by serverHash
+	GetMember(serverHash, username string) (*models.Member, error)
+
+	// GetMembers returns all members of the virtual server identified by serverHash
+	GetMembers(serverHash string) ([]models.Member, error)
+
+	// RemoveMember removes the user identified by username and all its server roles
+	// from the virtual server identified by serverHash
+	RemoveMember(serverHash, username string) error
+}
+
+// InviteStore provides operations on invites
+type InviteStore interface {
+	// CreateInvite creates a new invite
+	CreateInvite(invite *models.Invite) error
+
+	// GetInvite returns the invite identified by inviteHash
+	GetInvite(inviteHash string) (*models.Invite, error)
+
+	// DeleteInvite deletes the invite identified by inviteHash. It returns ErrNotFound
+	// if the invite doesn't exist, so that only one caller can use up an invite
+	DeleteInvite(inviteHash string) error
+}
+
+// AliasStore resolves the legacy hashes of servers and rooms, which were replaced by IDs
+type AliasStore interface {
+	// ResolveAlias returns the ID which replaced the legacy hash alias of the provided
+	// kind (AliasServer or AliasRoom)
+	ResolveAlias(kind, alias string) (string, error)
+}
+
+// RefreshTokenStore provides operations on the hashes of refresh tokens
+type RefreshTokenStore interface {
+	// CreateRefreshToken creates a new refresh token
+	CreateRefreshToken(token *models.RefreshToken) error
+
+	// GetRefreshToken returns the refresh token identified by hash
+	GetRefreshToken(hash string) (*models.RefreshToken, error)
+
+	// RotateRefreshToken atomically replaces the refresh token identified by hash with
+	// next. It returns ErrNotFound if the token was already replaced or revoked
+	RotateRefreshToken(hash string, next *models.RefreshToken) error
+
+	// RevokeRefreshTokens revokes all refresh tokens of the provided family
+	RevokeRefreshTokens(family string) error
+
+	// RevokeUserRefreshTokens revokes all refresh tokens of the user identified by
+	// username
+	RevokeUserRefreshTokens(username string) error
+}
+
+// ChallengeStore provides operations on the hashes of 2FA login challenges
+type ChallengeStore interface {
+	// CreateLoginChallenge creates a new login challenge
+	CreateLoginChallenge(challenge *models.LoginChallenge) error
+
+	// GetLoginChallenge returns the login challenge identified by hash
+	GetLoginChallenge(hash string) (*models.LoginChallenge, error)
+
+	// UpdateLoginChallenge updates the WebAuthn session of the login challenge
+	// identified by hash
+	UpdateLoginChallenge(hash, session string) error
+
+	// DeleteLoginChallenge deletes the login challenge identified by hash and all
+	// expired challenges. It returns ErrNotFound if the challenge was already deleted
+	DeleteLoginChallenge(hash string) error
+}
+
+// RecoveryCodeStore provides operations on the hashes of 2FA recovery codes
+type RecoveryCodeStore interface {
+	// SetRecoveryCodes replaces all recovery codes of the user identified by username.
+	// No codes remove all recovery codes of the user
+	SetRecoveryCodes(username string, codes []models.RecoveryCode) error
+
+	// GetRecoveryCode returns the recovery code identified by id of the user
+	// identified by username
+	GetRecoveryCode(username, id string) (*models.RecoveryCode, error)
+
+	// DeleteRecoveryCode deletes the recovery code identified by id of the user
+	// identified by username. It returns ErrNotFound if the code was already deleted
+	DeleteRecoveryCode(username, id string) error
+}
+
+// WebAuthnStore provides operations on the credentials of WebAuthn authenticators
+type WebAuthnStore interface {
+	// CreateWebAuthnCredential creates a new credential
+	CreateWebAuthnCredential(credential *models.WebAuthnCredential) error
+
+	// GetWebAuthnCredentials returns all credentials of the user identified by
+	// username
+	GetWebAuthnCredentials(username string) ([]models.WebAuthnCredential, error)
+
+	// UpdateWebAuthnSignCount updates the signature counter and the time of the last
+	// use of the credential identified by id
+	UpdateWebAuthnSignCount(id string, signCount uint32) error
+
+	// DeleteWebAuthnCredential deletes the credential identified by id of the user
+	// identified by username. It returns ErrNotFound if the user has no such
+	// credential
+	DeleteWebAuthnCredential(username, id string) error
+}
+
+// MailTokenStore provides operations on the hashes of tokens sent by mail
+type MailTokenStore interface {
+	// CreateMailToken creates a new token and deletes all other tokens of the same
+	// kind of the user, so that only the newest mail is valid
+	CreateMailToken(token *models.MailToken) error
+
+	// GetMailToken returns the token identified by hash
+	GetMailToken(hash string) (*models.MailToken, error)
+
+	// DeleteMailToken deletes the token identified by hash and all expired tokens. It
+	// returns ErrNotFound if the token was already deleted
+	DeleteMailToken(hash string) error
+}
+
+// SessionStore provides operations on the logins of users
+type SessionStore interface {
+	// CreateSession creates a new session and deletes all expired sessions
+	CreateSession(session *models.Session) error
+
+	// GetSession returns the session identified by id
+	GetSession(id string) (*models.Session, error)
+
+	// GetSessions returns all sessions of the user identified by username
+	GetSessions(username string) ([]models.Session, error)
+
+	// TouchSession updates the time the session identified by id was last seen and
+	// the time it expires
+	TouchSession(id string, lastSeenAt, expiresAt time.Time) error
+
+	// DeleteSession deletes the session identified by id of the user identified by
+	// username. It returns ErrNotFound if the user has no such session
+	DeleteSession(username, id string) error
+}
+
+// SigningKeyStore provides operations on the keys of the keyring which signs access
+// tokens
+type SigningKeyStore interface {
+	// GetSigningKeys returns all keys, the oldest first
+	GetSigningKeys() ([]models.SigningKey, error)
+
+	// CreateSigningKey creates a new key
+	CreateSigningKey(key *models.SigningKey) error
+
+	// RetireSigningKeys retires all keys except the one identified by id, which are
+	// not retired yet
+	RetireSigningKeys(id string, retiredAt time.Time) error
+
+	// DeleteSigningKeys deletes all keys which were retired before the provided time
+	DeleteSigningKeys(retiredBefore time.Time) error
+}
+
+// IdentityStore provides operations on the links between users and their accounts at
+// external identity providers
+type IdentityStore interface {
+	// GetIdentity returns the identity identified by issuer and subject
+	GetIdentity(issuer, subject string) (*models.Identity, error)
+
+	// GetIdentities returns all identities of issuer
+	GetIdentities(issuer string) ([]models.Identity, error)
+}
+
+// PersonalAccessTokenStore provides operations on the hashes of personal access tokens
+type PersonalAccessTokenStore interface {
+	// CreatePersonalAccessToken creates a new token
+	CreatePersonalAccessToken(token *models.PersonalAccessToken) error
+
+	// GetPersonalAccessToken returns the token identified by hash
+	GetPersonalAccessToken(hash string) (*models.PersonalAccessToken, error)
+
+	// GetPersonalAccessTokens returns all tokens of the user identified by username
+	GetPersonalAccessTokens(username string) ([]models.PersonalAccessToken, error)
+
+	// TouchPersonalAccessToken updates the time the token identified by id was last used
+	TouchPersonalAccessToken(id string, lastUsedAt time.Time) error
+
+	// DeletePersonalAccessToken deletes the token identified by id of the user
+	// identified by username. It returns ErrNotFound if the user has no such token
+	DeletePersonalAccessToken(username, id string) error
+
+	// DeletePersonalAccessTokens deletes all tokens of the user identified by username
+	DeletePersonalAccessTokens(username string) error
+}
+
+// SettingsStore provides access to the instance settings
+type SettingsStore interface {
+	// GetSettings returns the instance settings
+	GetSettings() (*Settings, error)
+
+	// SetSettings saves the instance settings
+	SetSettings(settings *Settings) error
+
+	// ClaimSuperadmin atomically marks the superadmin as existing and returns if the
+	// caller was the first one to claim it
+	ClaimSuperadmin() (bool, error)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// CreatePersonalAccessToken creates a new personal access token
+func (s *Store) CreatePersonalAccessToken(token *models.PersonalAccessToken) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for id, existing := range s.accessTokens {
+		if id == token.ID || existing.Hash == token.Hash {
+			return store.ErrDuplicate
+		}
+	}
+
+	s.accessTokens[token.ID] = *token
+	return nil
+}
+
+// GetPersonalAccessToken returns the personal access token identified by hash
+func (s *Store) GetPersonalAccessToken(hash string) (*models.PersonalAccessToken, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, token := range s.accessTokens {
+		if token.Hash == hash {
+			return &token, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// GetPersonalAccessTokens returns all personal access tokens of the user identified by
+// username ordered by the time they were created
+func (s *Store) GetPersonalAccessTokens(username string) ([]models.PersonalAccessToken, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	tokens := []models.PersonalAccessToken{}
+	for _, token := range s.accessTokens {
+		if token.Username == username {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// TouchPersonalAccessToken updates the time the personal access token identified by id
+// was last used
+func (s *Store) TouchPersonalAccessToken(id string, lastUsedAt time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	token, ok := s.accessTokens[id]
+	if !ok {
+		return nil
+	}
+
+	token.LastUsedAt = null.TimeFrom(lastUsedAt)
+	s.accessTokens[id] = token
+	return nil
+}
+
+// DeletePersonalAccessToken deletes the personal access token identified by id of the
+// user identified by username
+func (s *Store) DeletePersonalAccessToken(username, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	token, ok := s.accessTokens[id]
+	if !ok || token.Username != username {
+		return store.ErrNotFound
+	}
+
+	delete(s.accessTokens, id)
+	return nil
+}
+
+// DeletePersonalAccessTokens deletes all personal access tokens of the user identified
+// by username
+func (s *Store) DeletePersonalAccessTokens(username string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for id, token := range s.accessTokens {
+		if token.Username == username {
+			delete(s.accessTokens, id)
+		}
+	}
+	return nil
+}
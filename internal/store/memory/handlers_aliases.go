@@ -0,0 +1,13 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import "chapper.dev/server/internal/store"
+
+// ResolveAlias always returns store.ErrNotFound, because the in-memory store never
+// contained legacy hashes
+func (s *Store) ResolveAlias(kind, alias string) (string, error) {
+	return "", store.ErrNotFound
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// GetIdentity returns the identity identified by issuer and subject
+func (s *Store) GetIdentity(issuer, subject string) (*models.Identity, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	identity, ok := s.identities[identityKey(&models.Identity{Issuer: issuer, Subject: subject})]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &identity, nil
+}
+
+// GetIdentities returns all identities of issuer
+func (s *Store) GetIdentities(issuer string) ([]models.Identity, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	identities := []models.Identity{}
+	for _, identity := range s.identities {
+		if identity.Issuer == issuer {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+// identityKey returns the key of identity in the map of identities
+func identityKey(identity *models.Identity) string {
+	return identity.Issuer + " " + identity.Subject
+}
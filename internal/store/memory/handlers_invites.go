@@ -0,0 +1,48 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// CreateInvite creates a new invite
+func (s *Store) CreateInvite(invite *models.Invite) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.invites[invite.Hash]; exists {
+		return store.ErrDuplicate
+	}
+
+	s.invites[invite.Hash] = *invite
+	return nil
+}
+
+// GetInvite returns the invite identified by inviteHash
+func (s *Store) GetInvite(inviteHash string) (*models.Invite, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	invite, ok := s.invites[inviteHash]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &invite, nil
+}
+
+// DeleteInvite deletes the invite identified by inviteHash
+func (s *Store) DeleteInvite(inviteHash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.invites[inviteHash]; !ok {
+		return store.ErrNotFound
+	}
+
+	delete(s.invites, inviteHash)
+	return nil
+}
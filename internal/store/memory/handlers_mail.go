@@ -0,0 +1,59 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// CreateMailToken creates a new mail token and deletes all other tokens of the same
+// kind of the user
+func (s *Store) CreateMailToken(token *models.MailToken) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.mailTokens[token.Hash]; exists {
+		return store.ErrDuplicate
+	}
+
+	for hash, t := range s.mailTokens {
+		if t.Username == token.Username && t.Kind == token.Kind {
+			delete(s.mailTokens, hash)
+		}
+	}
+
+	s.mailTokens[token.Hash] = *token
+	return nil
+}
+
+// GetMailToken returns the mail token identified by hash
+func (s *Store) GetMailToken(hash string) (*models.MailToken, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	token, ok := s.mailTokens[hash]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &token, nil
+}
+
+// DeleteMailToken deletes the mail token identified by hash and all expired tokens
+func (s *Store) DeleteMailToken(hash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.mailTokens[hash]; !ok {
+		return store.ErrNotFound
+	}
+
+	for h, token := range s.mailTokens {
+		if h == hash || token.IsExpired() {
+			delete(s.mailTokens, h)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// AddMember adds the user identified by username to the virtual server identified by
+// serverHash
+func (s *Store) AddMember(serverHash, username string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.members[serverHash] == nil {
+		s.members[serverHash] = make(map[string]models.Member)
+	}
+
+	if _, exists := s.members[serverHash][username]; exists {
+		return store.ErrDuplicate
+	}
+
+	s.members[serverHash][username] = models.Member{
+		Server:   serverHash,
+		Username: username,
+		JoinedAt: time.Now().UTC(),
+	}
+	return nil
+}
+
+// GetMember returns the membership of the user identified by username in the virtual
+// server identified by serverHash
+func (s *Store) GetMember(serverHash, username string) (*models.Member, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	member, ok := s.members[serverHash][username]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &member, nil
+}
+
+// GetMembers returns all members of the virtual server identified by serverHash ordered
+// by the time they joined
+func (s *Store) GetMembers(serverHash string) ([]models.Member, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	members := make([]models.Member, 0, len(s.members[serverHash]))
+	for _, member := range s.members[serverHash] {
+		members = append(members, member)
+	}
+
+	sortMembers(members)
+	return members, nil
+}
+
+// RemoveMember removes the user identified by username and all its server roles from
+// the virtual server identified by serverHash
+func (s *Store) RemoveMember(serverHash, username string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for id := range s.userRoles[username] {
+		if s.roles[id].Server == serverHash {
+			delete(s.userRoles[username], id)
+		}
+	}
+
+	delete(s.members[serverHash], username)
+	return nil
+}
+
+// sortMembers orders members by the time they joined and their username
+func sortMembers(members []models.Member) {
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].JoinedAt.Equal(members[j].JoinedAt) {
+			return members[i].Username < members[j].Username
+		}
+		return members[i].JoinedAt.Before(members[j].JoinedAt)
+	})
+}
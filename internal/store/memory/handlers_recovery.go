@@ -0,0 +1,50 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// SetRecoveryCodes replaces all recovery codes of the user identified by username
+func (s *Store) SetRecoveryCodes(username string, codes []models.RecoveryCode) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.recoveryCodes[username] = make(map[string]models.RecoveryCode)
+	for _, code := range codes {
+		code.Username = username
+		s.recoveryCodes[username][code.ID] = code
+	}
+	return nil
+}
+
+// GetRecoveryCode returns the recovery code identified by id of the user identified by
+// username
+func (s *Store) GetRecoveryCode(username, id string) (*models.RecoveryCode, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	code, ok := s.recoveryCodes[username][id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &code, nil
+}
+
+// DeleteRecoveryCode deletes the recovery code identified by id of the user identified
+// by username
+func (s *Store) DeleteRecoveryCode(username, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.recoveryCodes[username][id]; !ok {
+		return store.ErrNotFound
+	}
+
+	delete(s.recoveryCodes[username], id)
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// CreateRole creates a new role and sets its ID
+func (s *Store) CreateRole(role *models.Role) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, existing := range s.roles {
+		if existing.Server == role.Server && existing.Name == role.Name {
+			return store.ErrDuplicate
+		}
+	}
+
+	s.nextRoleID++
+	role.ID = s.nextRoleID
+	role.Privileges.ID = s.nextRoleID
+	role.Privileges.RoleID = s.nextRoleID
+
+	s.roles[role.ID] = *role
+	return nil
+}
+
+// GetRole returns the role identified by roleID
+func (s *Store) GetRole(roleID uint) (*models.Role, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	role, ok := s.roles[roleID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &role, nil
+}
+
+// GetRoleByName returns the global role identified by name
+func (s *Store) GetRoleByName(name string) (*models.Role, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, role := range s.roles {
+		if role.Server == "" && role.Name == name {
+			return &role, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// GetRoles returns all global roles ordered by ID
+func (s *Store) GetRoles() ([]models.Role, error) {
+	return s.GetServerRoles("")
+}
+
+// GetServerRoles returns all roles of the virtual server identified by serverHash
+// ordered by ID
+func (s *Store) GetServerRoles(serverHash string) ([]models.Role, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.sortedRoles(func(role models.Role) bool {
+		return role.Server == serverHash
+	}), nil
+}
+
+// UpdateRole updates the role identified by roleID
+func (s *Store) UpdateRole(roleID uint, new *models.Role) error {
+	s.Lock()
+	defer s.Unlock()
+
+	role, ok := s.roles[roleID]
+	if !ok {
+		return nil
+	}
+
+	privileges := new.Privileges
+	privileges.ID = role.Privileges.ID
+	privileges.RoleID = roleID
+
+	role.Name = new.Name
+	role.Description = new.Description
+	role.Privileges = privileges
+	s.roles[roleID] = role
+	return nil
+}
+
+// DeleteRole deletes the role identified by roleID and all its assignments
+func (s *Store) DeleteRole(roleID uint) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, roles := range s.userRoles {
+		delete(roles, roleID)
+	}
+
+	delete(s.roles, roleID)
+	return nil
+}
+
+// GetUserRoles returns all global roles assigned to the user identified by username
+func (s *Store) GetUserRoles(username string) ([]models.Role, error) {
+	return s.GetMemberRoles("", username)
+}
+
+// GetMemberRoles returns all roles of the virtual server identified by serverHash
+// assigned to the user identified by username
+func (s *Store) GetMemberRoles(serverHash, username string) ([]models.Role, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	assigned := s.userRoles[username]
+	return s.sortedRoles(func(role models.Role) bool {
+		return role.Server == serverHash && assigned[role.ID]
+	}), nil
+}
+
+// AssignRole assigns the role identified by roleID to the user identified by username
+func (s *Store) AssignRole(username string, roleID uint) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.userRoles[username] == nil {
+		s.userRoles[username] = make(map[uint]bool)
+	}
+
+	if s.userRoles[username][roleID] {
+		return store.ErrDuplicate
+	}
+
+	s.userRoles[username][roleID] = true
+	return nil
+}
+
+// RemoveRole removes the role identified by roleID from the user identified by username
+func (s *Store) RemoveRole(username string, roleID uint) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.userRoles[username], roleID)
+	return nil
+}
+
+// RemoveRoleUnlessLast removes the role identified by roleID from the user identified
+// by username if other users hold the role as well
+func (s *Store) RemoveRoleUnlessLast(username string, roleID uint) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for holder, roles := range s.userRoles {
+		if holder != username && roles[roleID] {
+			delete(s.userRoles[username], roleID)
+			return nil
+		}
+	}
+	return store.ErrLastHolder
+}
+
+// sortedRoles returns all roles matching filter ordered by ID. The caller must hold the
+// lock
+func (s *Store) sortedRoles(filter func(models.Role) bool) []models.Role {
+	roles := []models.Role{}
+	for _, role := range s.roles {
+		if filter(role) {
+			roles = append(roles, role)
+		}
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].ID < roles[j].ID
+	})
+	return roles
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// CreateRoom creates a new room
+func (s *Store) CreateRoom(room *models.Room) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.rooms[room.Hash]; exists {
+		return store.ErrDuplicate
+	}
+
+	s.rooms[room.Hash] = *room
+	return nil
+}
+
+// GetRoom returns the room identified by roomHash
+func (s *Store) GetRoom(roomHash string) (*models.Room, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	room, ok := s.rooms[roomHash]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &room, nil
+}
+
+// GetRooms returns all rooms of the virtual server identified by serverHash ordered by
+// name
+func (s *Store) GetRooms(serverHash string) ([]models.Room, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	rooms := []models.Room{}
+	for _, room := range s.rooms {
+		if room.Server == serverHash {
+			rooms = append(rooms, room)
+		}
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].Name < rooms[j].Name
+	})
+	return rooms, nil
+}
+
+// UpdateRoom updates the room identified by roomHash
+func (s *Store) UpdateRoom(roomHash string, new *models.Room) error {
+	s.Lock()
+	defer s.Unlock()
+
+	room, ok := s.rooms[roomHash]
+	if !ok {
+		return nil
+	}
+
+	room.Name = new.Name
+	room.Type = new.Type
+	room.Description = new.Description
+	s.rooms[roomHash] = room
+	return nil
+}
+
+// DeleteRoom deletes the room identified by roomHash
+func (s *Store) DeleteRoom(roomHash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.rooms, roomHash)
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// CreateServer creates a new virtual server
+func (s *Store) CreateServer(server *models.Server) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.servers[server.Hash]; exists {
+		return store.ErrDuplicate
+	}
+
+	s.servers[server.Hash] = *server
+	return nil
+}
+
+// GetServer returns the virtual server identified by serverHash
+func (s *Store) GetServer(serverHash string) (*models.Server, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	server, ok := s.servers[serverHash]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &server, nil
+}
+
+// GetServers returns all virtual servers ordered by hash
+func (s *Store) GetServers() ([]models.Server, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	servers := make([]models.Server, 0, len(s.servers))
+	for _, server := range s.servers {
+		servers = append(servers, server)
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Hash < servers[j].Hash
+	})
+	return servers, nil
+}
+
+// UpdateServer updates the virtual server identified by serverHash
+func (s *Store) UpdateServer(serverHash string, new *models.Server) error {
+	s.Lock()
+	defer s.Unlock()
+
+	server, ok := s.servers[serverHash]
+	if !ok {
+		return nil
+	}
+
+	server.Name = new.Name
+	server.Description = new.Description
+	server.Image = new.Image
+	s.servers[serverHash] = server
+	return nil
+}
+
+// DeleteServer deletes the virtual server identified by serverHash, its rooms, invites,
+// members and roles
+func (s *Store) DeleteServer(serverHash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for id, role := range s.roles {
+		if role.Server != serverHash {
+			continue
+		}
+
+		for _, roles := range s.userRoles {
+			delete(roles, id)
+		}
+		delete(s.roles, id)
+	}
+
+	for hash, room := range s.rooms {
+		if room.Server == serverHash {
+			delete(s.rooms, hash)
+		}
+	}
+
+	for hash, invite := range s.invites {
+		if invite.Server == serverHash {
+			delete(s.invites, hash)
+		}
+	}
+
+	delete(s.members, serverHash)
+	delete(s.servers, serverHash)
+	return nil
+}
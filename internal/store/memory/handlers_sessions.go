@@ -0,0 +1,95 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// CreateSession creates a new session and deletes all expired sessions
+func (s *Store) CreateSession(session *models.Session) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.sessions[session.ID]; exists {
+		return store.ErrDuplicate
+	}
+
+	for id, sess := range s.sessions {
+		if sess.IsExpired() {
+			delete(s.sessions, id)
+		}
+	}
+
+	s.sessions[session.ID] = *session
+	return nil
+}
+
+// GetSession returns the session identified by id
+func (s *Store) GetSession(id string) (*models.Session, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &session, nil
+}
+
+// GetSessions returns all sessions of the user identified by username, the most
+// recently seen first
+func (s *Store) GetSessions(username string) ([]models.Session, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	sessions := []models.Session{}
+	for _, session := range s.sessions {
+		if session.Username == username {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// TouchSession updates the time the session identified by id was last seen and the
+// time it expires
+func (s *Store) TouchSession(id string, lastSeenAt, expiresAt time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+
+	session.LastSeenAt = lastSeenAt
+	session.ExpiresAt = expiresAt
+	s.sessions[id] = session
+	return nil
+}
+
+// DeleteSession deletes the session identified by id of the user identified by
+// username
+func (s *Store) DeleteSession(username, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.Username != username {
+		return store.ErrNotFound
+	}
+
+	delete(s.sessions, id)
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// GetSigningKeys returns all keys of the keyring, the oldest first
+func (s *Store) GetSigningKeys() ([]models.SigningKey, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	keys := []models.SigningKey{}
+	for _, key := range s.signingKeys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// CreateSigningKey saves a new key of the keyring
+func (s *Store) CreateSigningKey(key *models.SigningKey) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.signingKeys[key.ID]; exists {
+		return store.ErrDuplicate
+	}
+
+	s.signingKeys[key.ID] = *key
+	return nil
+}
+
+// RetireSigningKeys retires all keys except the one identified by id
+func (s *Store) RetireSigningKeys(id string, retiredAt time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for keyID, key := range s.signingKeys {
+		if keyID == id || key.RetiredAt.Valid {
+			continue
+		}
+
+		key.RetiredAt = null.TimeFrom(retiredAt)
+		s.signingKeys[keyID] = key
+	}
+	return nil
+}
+
+// DeleteSigningKeys deletes all keys which were retired before the provided time
+func (s *Store) DeleteSigningKeys(retiredBefore time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for id, key := range s.signingKeys {
+		if key.RetiredAt.Valid && key.RetiredAt.Time.Before(retiredBefore) {
+			delete(s.signingKeys, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// CreateRefreshToken creates a new refresh token
+func (s *Store) CreateRefreshToken(token *models.RefreshToken) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.refreshTokens[token.Hash]; exists {
+		return store.ErrDuplicate
+	}
+
+	s.refreshTokens[token.Hash] = *token
+	return nil
+}
+
+// GetRefreshToken returns the refresh token identified by hash
+func (s *Store) GetRefreshToken(hash string) (*models.RefreshToken, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	token, ok := s.refreshTokens[hash]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken atomically replaces the refresh token identified by hash with
+// next
+func (s *Store) RotateRefreshToken(hash string, next *models.RefreshToken) error {
+	s.Lock()
+	defer s.Unlock()
+
+	token, ok := s.refreshTokens[hash]
+	if !ok || token.IsUsed() {
+		return store.ErrNotFound
+	}
+
+	if _, exists := s.refreshTokens[next.Hash]; exists {
+		return store.ErrDuplicate
+	}
+
+	token.ReplacedBy = next.Hash
+	s.refreshTokens[hash] = token
+	s.refreshTokens[next.Hash] = *next
+	return nil
+}
+
+// RevokeRefreshTokens revokes all refresh tokens of the provided family
+func (s *Store) RevokeRefreshTokens(family string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for hash, token := range s.refreshTokens {
+		if token.Family == family {
+			token.Revoked = true
+			s.refreshTokens[hash] = token
+		}
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes all refresh tokens of the user identified by username
+func (s *Store) RevokeUserRefreshTokens(username string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for hash, token := range s.refreshTokens {
+		if token.Username == username {
+			token.Revoked = true
+			s.refreshTokens[hash] = token
+		}
+	}
+	return nil
+}
+
+// CreateLoginChallenge creates a new login challenge
+func (s *Store) CreateLoginChallenge(challenge *models.LoginChallenge) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.challenges[challenge.Hash]; exists {
+		return store.ErrDuplicate
+	}
+
+	s.challenges[challenge.Hash] = *challenge
+	return nil
+}
+
+// GetLoginChallenge returns the login challenge identified by hash
+func (s *Store) GetLoginChallenge(hash string) (*models.LoginChallenge, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	challenge, ok := s.challenges[hash]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &challenge, nil
+}
+
+// UpdateLoginChallenge updates the WebAuthn session of the login challenge identified
+// by hash
+func (s *Store) UpdateLoginChallenge(hash, session string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	challenge, ok := s.challenges[hash]
+	if !ok {
+		return nil
+	}
+
+	challenge.Session = session
+	s.challenges[hash] = challenge
+	return nil
+}
+
+// DeleteLoginChallenge deletes the login challenge identified by hash and all expired
+// challenges
+func (s *Store) DeleteLoginChallenge(hash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.challenges[hash]; !ok {
+		return store.ErrNotFound
+	}
+
+	for h, challenge := range s.challenges {
+		if h == hash || challenge.IsExpired() {
+			delete(s.challenges, h)
+		}
+	}
+	return nil
+}
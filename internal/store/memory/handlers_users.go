@@ -0,0 +1,349 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// GetUser returns the user identified by username
+func (s *Store) GetUser(username string) (models.User, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return models.User{}, store.ErrNotFound
+	}
+	return user, nil
+}
+
+// GetUserPublicKey returns the public key of the user identified by username
+func (s *Store) GetUserPublicKey(username string) (string, error) {
+	user, err := s.GetUser(username)
+	return user.PublicKey, err
+}
+
+// GetUserServers returns the servers the user identified by username is a member of
+// ordered by the time the user joined
+func (s *Store) GetUserServers(username string) ([]models.Server, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	memberships := []models.Member{}
+	for _, members := range s.members {
+		if member, ok := members[username]; ok {
+			memberships = append(memberships, member)
+		}
+	}
+	sortMembers(memberships)
+
+	servers := make([]models.Server, 0, len(memberships))
+	for _, member := range memberships {
+		servers = append(servers, s.servers[member.Server])
+	}
+	return servers, nil
+}
+
+// CreateAccount creates a new user, the identity and the role assignment of the
+// account, if set
+func (s *Store) CreateAccount(account *store.Account) error {
+	s.Lock()
+	defer s.Unlock()
+
+	user := account.User
+	if _, exists := s.users[user.Username]; exists {
+		return store.ErrDuplicate
+	}
+
+	if account.Identity != nil {
+		if _, exists := s.identities[identityKey(account.Identity)]; exists {
+			return store.ErrDuplicate
+		}
+		s.identities[identityKey(account.Identity)] = *account.Identity
+	}
+
+	s.users[user.Username] = models.User{
+		Username:  user.Username,
+		Password:  user.Password,
+		Email:     null.StringFrom(user.Email),
+		Bot:       account.BotOwner != "",
+		BotOwner:  null.NewString(account.BotOwner, account.BotOwner != ""),
+		PublicKey: user.PublicKey,
+	}
+
+	if account.RoleID != 0 {
+		s.userRoles[user.Username] = map[uint]bool{account.RoleID: true}
+	}
+	return nil
+}
+
+// UpdatePassword replaces the password hash of the user identified by username if it
+// is still oldHash, otherwise it returns ErrNotFound
+func (s *Store) UpdatePassword(username, oldHash, newHash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.users[username]
+	if !ok || existing.Password != oldHash {
+		return store.ErrNotFound
+	}
+
+	existing.Password = newHash
+	s.users[username] = existing
+	return nil
+}
+
+// UpdateEmail changes the email of the user identified by username and marks it as
+// not verified
+func (s *Store) UpdateEmail(username, email string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.users[username]
+	if !ok {
+		return nil
+	}
+
+	existing.Email = null.StringFrom(email)
+	existing.EmailVerified = false
+	s.users[username] = existing
+	return nil
+}
+
+// RenameUser changes the username of the user identified by username to newName in
+// all places which reference the user
+func (s *Store) RenameUser(username, newName string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.users[newName]; exists {
+		return store.ErrDuplicate
+	}
+
+	user, ok := s.users[username]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	delete(s.users, username)
+	user.Username = newName
+	s.users[newName] = user
+
+	if roles, ok := s.userRoles[username]; ok {
+		delete(s.userRoles, username)
+		s.userRoles[newName] = roles
+	}
+
+	for _, members := range s.members {
+		if member, ok := members[username]; ok {
+			delete(members, username)
+			member.Username = newName
+			members[newName] = member
+		}
+	}
+
+	for hash, invite := range s.invites {
+		if invite.CreatedBy == username {
+			invite.CreatedBy = newName
+			s.invites[hash] = invite
+		}
+	}
+
+	for hash, token := range s.refreshTokens {
+		if token.Username == username {
+			token.Username = newName
+			s.refreshTokens[hash] = token
+		}
+	}
+
+	for hash, challenge := range s.challenges {
+		if challenge.Username == username {
+			challenge.Username = newName
+			s.challenges[hash] = challenge
+		}
+	}
+
+	if codes, ok := s.recoveryCodes[username]; ok {
+		delete(s.recoveryCodes, username)
+		for id, code := range codes {
+			code.Username = newName
+			codes[id] = code
+		}
+		s.recoveryCodes[newName] = codes
+	}
+
+	for id, credential := range s.credentials {
+		if credential.Username == username {
+			credential.Username = newName
+			s.credentials[id] = credential
+		}
+	}
+
+	for hash, token := range s.mailTokens {
+		if token.Username == username {
+			token.Username = newName
+			s.mailTokens[hash] = token
+		}
+	}
+
+	for id, session := range s.sessions {
+		if session.Username == username {
+			session.Username = newName
+			s.sessions[id] = session
+		}
+	}
+
+	for key, identity := range s.identities {
+		if identity.Username == username {
+			identity.Username = newName
+			s.identities[key] = identity
+		}
+	}
+
+	for name, bot := range s.users {
+		if bot.BotOwner.String == username {
+			bot.BotOwner = null.StringFrom(newName)
+			s.users[name] = bot
+		}
+	}
+
+	for id, token := range s.accessTokens {
+		if token.Username == username {
+			token.Username = newName
+		}
+		if token.CreatedBy == username {
+			token.CreatedBy = newName
+		}
+		s.accessTokens[id] = token
+	}
+
+	return nil
+}
+
+// UpdateTwoFAVerify updates the 2FA secret and TOTP parameters of the pending
+// enrollment of the user identified by username and resets the time step of the last
+// used code
+func (s *Store) UpdateTwoFAVerify(username, verify string, params models.TOTPParams) error {
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.users[username]
+	if !ok {
+		return nil
+	}
+
+	existing.TwoFAVerify = null.NewString(verify, verify != "")
+	existing.TOTPParams = params
+	existing.TwoFALastStep = 0
+	s.users[username] = existing
+	return nil
+}
+
+// UpdateTwoFASecret updates the 2FA secret of the user identified by username and
+// removes the pending enrollment. Removing the secret resets the time step of the last
+// used code
+func (s *Store) UpdateTwoFASecret(username, secret string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.users[username]
+	if !ok {
+		return nil
+	}
+
+	existing.TwoFASecret = null.NewString(secret, secret != "")
+	existing.TwoFAVerify = null.String{}
+	if secret == "" {
+		existing.TwoFALastStep = 0
+	}
+	s.users[username] = existing
+	return nil
+}
+
+// UpdateTwoFAStep saves the time step of the last used 2FA code of the user identified
+// by username if it is after the saved one
+func (s *Store) UpdateTwoFAStep(username string, step uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.users[username]
+	if !ok || step <= existing.TwoFALastStep {
+		return store.ErrNotFound
+	}
+
+	existing.TwoFALastStep = step
+	s.users[username] = existing
+	return nil
+}
+
+// UpdateWebAuthnID sets the WebAuthn user handle of the user identified by username
+func (s *Store) UpdateWebAuthnID(username, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.users[username]
+	if !ok {
+		return nil
+	}
+
+	existing.WebAuthnID = null.StringFrom(id)
+	s.users[username] = existing
+	return nil
+}
+
+// UpdateEmailVerified marks the email of the user identified by username as verified if
+// it still matches email
+func (s *Store) UpdateEmailVerified(username, email string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.users[username]
+	if !ok || existing.Email.String != email {
+		return store.ErrNotFound
+	}
+
+	existing.EmailVerified = true
+	s.users[username] = existing
+	return nil
+}
+
+// UpdateUserDisabled sets if the user identified by username is disabled
+func (s *Store) UpdateUserDisabled(username string, disabled bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.users[username]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	existing.Disabled = disabled
+	s.users[username] = existing
+	return nil
+}
+
+// GetBots returns all bots of the user identified by owner ordered by their username
+func (s *Store) GetBots(owner string) ([]models.User, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	bots := []models.User{}
+	for _, user := range s.users {
+		if user.Bot && user.BotOwner.String == owner {
+			bots = append(bots, user)
+		}
+	}
+
+	sort.Slice(bots, func(i, j int) bool {
+		return bots[i].Username < bots[j].Username
+	})
+	return bots, nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// CreateWebAuthnCredential creates a new WebAuthn credential
+func (s *Store) CreateWebAuthnCredential(credential *models.WebAuthnCredential) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.credentials[credential.ID]; exists {
+		return store.ErrDuplicate
+	}
+
+	s.credentials[credential.ID] = *credential
+	return nil
+}
+
+// GetWebAuthnCredentials returns all WebAuthn credentials of the user identified by
+// username ordered by the time they were created
+func (s *Store) GetWebAuthnCredentials(username string) ([]models.WebAuthnCredential, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	credentials := []models.WebAuthnCredential{}
+	for _, credential := range s.credentials {
+		if credential.Username == username {
+			credentials = append(credentials, credential)
+		}
+	}
+
+	sort.Slice(credentials, func(i, j int) bool {
+		return credentials[i].CreatedAt.Before(credentials[j].CreatedAt)
+	})
+	return credentials, nil
+}
+
+// UpdateWebAuthnSignCount updates the signature counter and the time of the last use
+// of the WebAuthn credential identified by id
+func (s *Store) UpdateWebAuthnSignCount(id string, signCount uint32) error {
+	s.Lock()
+	defer s.Unlock()
+
+	credential, ok := s.credentials[id]
+	if !ok {
+		return nil
+	}
+
+	credential.SignCount = signCount
+	credential.LastUsedAt = null.TimeFrom(time.Now().UTC())
+	s.credentials[id] = credential
+	return nil
+}
+
+// DeleteWebAuthnCredential deletes the WebAuthn credential identified by id of the user
+// identified by username
+func (s *Store) DeleteWebAuthnCredential(username, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	credential, ok := s.credentials[id]
+	if !ok || credential.Username != username {
+		return store.ErrNotFound
+	}
+
+	delete(s.credentials, id)
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package memory provides an in-memory implementation of the store interfaces. It is
+// used to run the server without a database, e.g. in demo mode or in tests
+package memory
+
+import (
+	"sync"
+
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store"
+)
+
+// Store implements store.Store by keeping all data in maps. All methods are safe for
+// concurrent use
+type Store struct {
+	sync.RWMutex
+
+	users     map[string]models.User
+	servers   map[string]models.Server
+	rooms     map[string]models.Room
+	roles     map[uint]models.Role
+	userRoles map[string]map[uint]bool
+	members   map[string]map[string]models.Member
+	invites   map[string]models.Invite
+	settings  store.Settings
+
+	refreshTokens map[string]models.RefreshToken
+	challenges    map[string]models.LoginChallenge
+	recoveryCodes map[string]map[string]models.RecoveryCode
+	credentials   map[string]models.WebAuthnCredential
+	mailTokens    map[string]models.MailToken
+	sessions      map[string]models.Session
+	signingKeys   map[string]models.SigningKey
+	identities    map[string]models.Identity
+	accessTokens  map[string]models.PersonalAccessToken
+
+	nextRoleID uint
+}
+
+// New returns a new in-memory store which only contains the built-in roles
+func New() *Store {
+	s := &Store{
+		users:     make(map[string]models.User),
+		servers:   make(map[string]models.Server),
+		rooms:     make(map[string]models.Room),
+		roles:     make(map[uint]models.Role),
+		userRoles: make(map[string]map[uint]bool),
+		members:   make(map[string]map[string]models.Member),
+		invites:   make(map[string]models.Invite),
+		settings:  *store.DefaultSettings,
+
+		refreshTokens: make(map[string]models.RefreshToken),
+		challenges:    make(map[string]models.LoginChallenge),
+		recoveryCodes: make(map[string]map[string]models.RecoveryCode),
+		credentials:   make(map[string]models.WebAuthnCredential),
+		mailTokens:    make(map[string]models.MailToken),
+		sessions:      make(map[string]models.Session),
+		signingKeys:   make(map[string]models.SigningKey),
+		identities:    make(map[string]models.Identity),
+		accessTokens:  make(map[string]models.PersonalAccessToken),
+	}
+
+	for _, role := range []models.Role{models.Superadmin(), models.Basic()} {
+		role.Builtin = true
+		s.CreateRole(&role)
+	}
+
+	return s
+}
+
+// GetSettings returns a copy of the settings
+func (s *Store) GetSettings() (*store.Settings, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	settings := s.settings
+	return &settings, nil
+}
+
+// SetSettings saves the settings. SuperadminExists can only be changed via
+// ClaimSuperadmin
+func (s *Store) SetSettings(settings *store.Settings) error {
+	s.Lock()
+	defer s.Unlock()
+
+	superadminExists := s.settings.SuperadminExists
+	s.settings = *settings
+	s.settings.SuperadminExists = superadminExists
+	return nil
+}
+
+// ClaimSuperadmin atomically marks the superadmin as existing. It returns true if the
+// caller claimed the superadmin role, and false if a superadmin already existed
+func (s *Store) ClaimSuperadmin() (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.settings.SuperadminExists {
+		return false, nil
+	}
+
+	s.settings.SuperadminExists = true
+	return true, nil
+}
+
+// Ensure Store implements all store interfaces
+var _ store.Store = (*Store)(nil)
@@ -0,0 +1,197 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"chapper.dev/server/internal/store/migrations"
+	"chapper.dev/server/internal/store/schemas"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrSchemaBehind indicates the database schema is older than the schema this
+	// version of the server expects
+	ErrSchemaBehind = errors.New("Database schema is behind, run 'server migrate up'")
+
+	// ErrSchemaAhead indicates the database schema was migrated by a newer version of the
+	// server
+	ErrSchemaAhead = errors.New("Database schema is newer than this server version")
+)
+
+// MigrationState describes if a migration was applied and when
+type MigrationState struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type appliedMigration struct {
+	Version   uint      `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Migrate applies all pending migrations
+func (s *SQL) Migrate() error {
+	_, err := s.MigrateUp(0)
+	return err
+}
+
+// MigrateUp applies at most 'steps' pending migrations in ascending order. A value of 0
+// applies all pending migrations. The applied migrations are returned
+func (s *SQL) MigrateUp(steps int) ([]migrations.Migration, error) {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []migrations.Migration
+	for _, migration := range migrations.All() {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err = s.runMigration(migration.Up,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			migration.Version,
+			migration.Name,
+			time.Now().UTC(),
+		)
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// MigrateDown reverts at most 'steps' applied migrations in descending order. The
+// reverted migrations are returned
+func (s *SQL) MigrateDown(steps int) ([]migrations.Migration, error) {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		all  = migrations.All()
+		done []migrations.Migration
+	)
+
+	for i := len(all) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := all[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err = s.runMigration(migration.Down,
+			`DELETE FROM schema_migrations WHERE version = ?`,
+			migration.Version,
+		)
+		if err != nil {
+			return done, fmt.Errorf("reverting migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// MigrationStatus returns the state of every known migration in ascending order
+func (s *SQL) MigrationStatus() ([]MigrationState, error) {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var states []MigrationState
+	for _, migration := range migrations.All() {
+		state := MigrationState{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+
+		if a, ok := applied[migration.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = a.AppliedAt
+		}
+
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// CheckSchema returns ErrSchemaBehind if there are pending migrations and ErrSchemaAhead
+// if the database contains migrations unknown to this version of the server
+func (s *SQL) CheckSchema() error {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for version := range applied {
+		if version > migrations.Latest() {
+			return ErrSchemaAhead
+		}
+	}
+
+	for _, migration := range migrations.All() {
+		if _, ok := applied[migration.Version]; !ok {
+			return ErrSchemaBehind
+		}
+	}
+
+	return nil
+}
+
+// runMigration runs the step of a migration and updates the migrations table in one
+// transaction
+func (s *SQL) runMigration(step migrations.Step, query string, args ...interface{}) error {
+	return s.transaction(func(tx *sqlx.Tx) error {
+		err := step(tx, s.dialect)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(tx.Rebind(query), args...)
+		return err
+	})
+}
+
+// appliedMigrations returns all applied migrations indexed by version. The migrations
+// table gets created if it doesn't exist yet
+func (s *SQL) appliedMigrations() (map[uint]appliedMigration, error) {
+	_, err := s.conn.Exec(schemas.Migrations(s.dialect))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []appliedMigration
+	err = s.conn.Select(&rows, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chapper.dev/server/internal/config"
+)
+
+// newTestSQL returns a SQLite store in a temporary directory with the migrations up to
+// version applied. Version 0 applies no migrations
+func newTestSQL(t *testing.T, version int) *SQL {
+	dir, err := ioutil.TempDir("", "chapper-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	s, err := New("sqlite", config.StoreOptions{Path: filepath.Join(dir, "chapper.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.conn.Close() })
+
+	if version > 0 {
+		_, err = s.MigrateUp(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return s
+}
+
+// exec executes the statements, like an older version of the server would have
+func exec(t *testing.T, s *SQL, statements ...string) {
+	for _, statement := range statements {
+		_, err := s.conn.Exec(statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrateRoomsOfOnlyServer(t *testing.T) {
+	s := newTestSQL(t, 4)
+	exec(t, s,
+		"INSERT INTO servers (hash, name) VALUES ('chapper', 'Chapper')",
+		"INSERT INTO rooms (hash, name) VALUES ('general', 'General'), ('voice', 'Voice')",
+	)
+
+	_, err := s.MigrateUp(1)
+	if err != nil {
+		t.Fatalf("MigrateUp() error = %v", err)
+	}
+
+	var servers []string
+	err = s.conn.Select(&servers, "SELECT DISTINCT server FROM rooms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(servers) != 1 || servers[0] != "chapper" {
+		t.Errorf("rooms belong to %v, want the only server", servers)
+	}
+}
+
+func TestMigrateRoomsAmbiguous(t *testing.T) {
+	for _, servers := range []string{
+		"",
+		"INSERT INTO servers (hash, name) VALUES ('chapper', 'Chapper'), ('other', 'Other')",
+	} {
+		s := newTestSQL(t, 4)
+		if servers != "" {
+			exec(t, s, servers)
+		}
+		exec(t, s, "INSERT INTO rooms (hash, name) VALUES ('general', 'General')")
+
+		_, err := s.MigrateUp(1)
+		if err == nil || !strings.Contains(err.Error(), "'General' (general)") {
+			t.Fatalf("MigrateUp() error = %v, want an error naming the room", err)
+		}
+
+		// Nothing was changed, the migration runs once the rooms were deleted
+		exec(t, s, "DELETE FROM rooms")
+
+		_, err = s.MigrateUp(1)
+		if err != nil {
+			t.Errorf("MigrateUp() without rooms error = %v", err)
+		}
+	}
+}
+
+func TestMigrateInvitesCreatedBy(t *testing.T) {
+	for name, schema := range map[string]string{
+		"adopted": `CREATE TABLE invites (
+			hash VARCHAR(32) NOT NULL,
+			creayted_by VARCHAR(100) NOT NULL,
+			server VARCHAR(100) NOT NULL,
+			one_time_use BOOLEAN DEFAULT false,
+			expires_at DATETIME DEFAULT NULL,
+			PRIMARY KEY (hash)
+		)`,
+		"new": "",
+	} {
+		s := newTestSQL(t, 0)
+		if schema != "" {
+			exec(t, s, schema, "INSERT INTO invites (hash, creayted_by, server) VALUES ('once', 'alice', 'chapper')")
+		}
+
+		err := s.Migrate()
+		if err != nil {
+			t.Fatalf("%s: Migrate() error = %v", name, err)
+		}
+
+		if schema == "" {
+			exec(t, s, "INSERT INTO invites (hash, created_by, server) VALUES ('once', 'alice', 'chapper')")
+		}
+
+		invite, err := s.GetInvite("once")
+		if err != nil {
+			t.Fatalf("%s: GetInvite() error = %v", name, err)
+		}
+
+		if invite.CreatedBy != "alice" {
+			t.Errorf("%s: invite created by %q, want alice", name, invite.CreatedBy)
+		}
+	}
+}
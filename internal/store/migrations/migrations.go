@@ -0,0 +1,69 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package migrations provides versioned database schema migrations
+package migrations
+
+import (
+	"chapper.dev/server/internal/store/schemas"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration describes one versioned change of the database schema. Each migration can
+// be applied (Up) and reverted (Down)
+type Migration struct {
+	Version uint
+	Name    string
+	Up      Step
+	Down    Step
+}
+
+// Step executes one direction of a migration inside the transaction tx. The dialect d
+// can be used to build driver specific statements
+type Step func(tx *sqlx.Tx, d schemas.Dialect) error
+
+// All returns all migrations ordered by version. New migrations MUST be appended at the
+// end and existing migrations MUST NOT be changed once released
+func All() []Migration {
+	return []Migration{
+		initial,
+		settings,
+		roles,
+		members,
+		rooms,
+		ids,
+		refreshTokens,
+		twofa,
+		recoveryCodes,
+		totp,
+		webauthn,
+		mail,
+		sessions,
+		signingKeys,
+		identities,
+		disabledUsers,
+		personalAccessTokens,
+		invitesCreatedBy,
+	}
+}
+
+// Latest returns the version of the newest migration
+func Latest() uint {
+	all := All()
+	return all[len(all)-1].Version
+}
+
+// Statements returns a step which executes the statements returned by fn in order
+func Statements(fn func(d schemas.Dialect) []string) Step {
+	return func(tx *sqlx.Tx, d schemas.Dialect) error {
+		for _, statement := range fn(d) {
+			_, err := tx.Exec(statement)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
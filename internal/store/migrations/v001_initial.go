@@ -0,0 +1,25 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// initial creates the tables which existed before versioned migrations were introduced.
+// All statements use IF (NOT) EXISTS so that existing installations can be adopted
+var initial = Migration{
+	Version: 1,
+	Name:    "initial",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return schemas.All(d)
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"DROP TABLE IF EXISTS invites",
+			"DROP TABLE IF EXISTS rooms",
+			"DROP TABLE IF EXISTS servers",
+			"DROP TABLE IF EXISTS users",
+		}
+	}),
+}
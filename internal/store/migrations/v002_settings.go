@@ -0,0 +1,29 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// settings creates the settings table with its single row and adds the role column to
+// users. Existing installations already have users, so the superadmin bootstrap is
+// skipped for them
+var settings = Migration{
+	Version: 2,
+	Name:    "settings",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.Settings(d),
+			`INSERT INTO settings (id, is_installed, superadmin_exists)
+			SELECT 1, COUNT(*) > 0, COUNT(*) > 0 FROM users`,
+			"ALTER TABLE users ADD COLUMN role VARCHAR(100) NOT NULL DEFAULT 'User'",
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE users DROP COLUMN role",
+			"DROP TABLE IF EXISTS settings",
+		}
+	}),
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"chapper.dev/server/internal/models"
+	"chapper.dev/server/internal/store/schemas"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// roles creates the roles, privileges and user_roles tables, seeds the built-in roles
+// and moves the role of each user from the users table to user_roles
+var roles = Migration{
+	Version: 3,
+	Name:    "roles",
+	Up: func(tx *sqlx.Tx, d schemas.Dialect) error {
+		err := Statements(func(d schemas.Dialect) []string {
+			return []string{
+				schemas.Roles(d),
+				schemas.Privileges(d),
+				schemas.UserRoles(d),
+			}
+		})(tx, d)
+		if err != nil {
+			return err
+		}
+
+		for _, role := range []models.Role{models.Superadmin(), models.Basic()} {
+			err = seedRole(tx, role)
+			if err != nil {
+				return err
+			}
+		}
+
+		return Statements(func(d schemas.Dialect) []string {
+			return []string{
+				`INSERT INTO user_roles (username, role_id)
+				SELECT users.username, roles.id FROM users
+				JOIN roles ON roles.name = users.role`,
+				"ALTER TABLE users DROP COLUMN role",
+			}
+		})(tx, d)
+	},
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE users ADD COLUMN role VARCHAR(100) NOT NULL DEFAULT 'User'",
+			`UPDATE users SET role = 'Superadmin' WHERE username IN (
+				SELECT user_roles.username FROM user_roles
+				JOIN roles ON roles.id = user_roles.role_id
+				WHERE roles.name = 'Superadmin'
+			)`,
+			"DROP TABLE IF EXISTS user_roles",
+			"DROP TABLE IF EXISTS privileges",
+			"DROP TABLE IF EXISTS roles",
+		}
+	}),
+}
+
+// seedRole inserts a built-in role and its privileges
+func seedRole(tx *sqlx.Tx, role models.Role) error {
+	_, err := tx.Exec(tx.Rebind(`INSERT INTO roles (name, description, builtin) VALUES (?, ?, true)`),
+		role.Name,
+		role.Description,
+	)
+	if err != nil {
+		return err
+	}
+
+	var roleID uint
+	err = tx.Get(&roleID, tx.Rebind(`SELECT id FROM roles WHERE name = ?`), role.Name)
+	if err != nil {
+		return err
+	}
+
+	role.Privileges.RoleID = roleID
+	_, err = tx.NamedExec(`
+		INSERT INTO privileges (
+			role_id, can_create_server, can_delete_server, can_edit_server,
+			can_see_all_servers, can_create_room, can_delete_room, can_edit_room,
+			can_create_invite, can_delete_invite, can_kick_user_from_room,
+			can_kick_user_from_server, can_ban_user_from_room, can_ban_user_from_server,
+			can_create_role, can_delete_role, can_assign_role_to_user,
+			can_remove_role_from_user
+		) VALUES (
+			:role_id, :can_create_server, :can_delete_server, :can_edit_server,
+			:can_see_all_servers, :can_create_room, :can_delete_room, :can_edit_room,
+			:can_create_invite, :can_delete_invite, :can_kick_user_from_room,
+			:can_kick_user_from_server, :can_ban_user_from_room, :can_ban_user_from_server,
+			:can_create_role, :can_delete_role, :can_assign_role_to_user,
+			:can_remove_role_from_user
+		)`, role.Privileges)
+	return err
+}
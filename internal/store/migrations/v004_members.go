@@ -0,0 +1,45 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"chapper.dev/server/internal/constants"
+	"chapper.dev/server/internal/store/schemas"
+)
+
+// members creates the members table and scopes roles to virtual servers. The roles
+// table is rebuilt because the unique constraint on the role name has to include the
+// server, which can't be altered in place on SQLite. Reverting deletes all server
+// roles, but keeps the server column
+var members = Migration{
+	Version: 4,
+	Name:    "members",
+	Up: Statements(func(d schemas.Dialect) []string {
+		statements := []string{
+			schemas.Members(d),
+			schemas.ServerRoles(d, "roles_scoped"),
+			`INSERT INTO roles_scoped (id, name, description, builtin)
+			SELECT id, name, description, builtin FROM roles`,
+			"DROP TABLE roles",
+			"ALTER TABLE roles_scoped RENAME TO roles",
+		}
+
+		// The sequence of the new table starts at 1, because the IDs were copied
+		if d.Driver == constants.StoreDriverPostgres {
+			statements = append(statements,
+				`SELECT setval(pg_get_serial_sequence('roles', 'id'), COALESCE(MAX(id), 1)) FROM roles`,
+			)
+		}
+		return statements
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			`DELETE FROM user_roles WHERE role_id IN (SELECT id FROM roles WHERE server <> '')`,
+			`DELETE FROM privileges WHERE role_id IN (SELECT id FROM roles WHERE server <> '')`,
+			`DELETE FROM roles WHERE server <> ''`,
+			"DROP TABLE IF EXISTS members",
+		}
+	}),
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"chapper.dev/server/internal/store/schemas"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// rooms adds the server column to rooms. Rooms created before rooms were scoped to
+// virtual servers are assigned to the only virtual server. If there are more or no
+// virtual servers the migration can't choose one and refuses to run
+var rooms = Migration{
+	Version: 5,
+	Name:    "rooms",
+	Up: func(tx *sqlx.Tx, d schemas.Dialect) error {
+		var existing []struct {
+			Hash string `db:"hash"`
+			Name string `db:"name"`
+		}
+
+		// Checked before the table is altered, MySQL commits schema changes at once
+		err := tx.Select(&existing, "SELECT hash, name FROM rooms ORDER BY name")
+		if err != nil {
+			return err
+		}
+
+		var servers []string
+		err = tx.Select(&servers, "SELECT hash FROM servers")
+		if err != nil {
+			return err
+		}
+
+		if len(existing) > 0 && len(servers) != 1 {
+			names := make([]string, 0, len(existing))
+			for _, room := range existing {
+				names = append(names, fmt.Sprintf("'%s' (%s)", room.Name, room.Hash))
+			}
+
+			return fmt.Errorf("rooms %s belong to no virtual server and can't be assigned to one of %d virtual servers, delete them and create them again in their virtual server after the migration",
+				strings.Join(names, ", "),
+				len(servers),
+			)
+		}
+
+		_, err = tx.Exec("ALTER TABLE rooms ADD COLUMN server VARCHAR(32) NOT NULL DEFAULT ''")
+		if err != nil {
+			return err
+		}
+
+		if len(existing) == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(tx.Rebind("UPDATE rooms SET server = ?"), servers[0])
+		return err
+	},
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE rooms DROP COLUMN server",
+		}
+	}),
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"chapper.dev/server/internal/modules/id"
+	"chapper.dev/server/internal/store/schemas"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// idReferences lists the statements which replace an old server or room hash with its
+// new ID. Each statement takes the new value followed by the old value
+var idReferences = map[string][]string{
+	"server": {
+		"UPDATE servers SET hash = ? WHERE hash = ?",
+		"UPDATE rooms SET server = ? WHERE server = ?",
+		"UPDATE invites SET server = ? WHERE server = ?",
+		"UPDATE members SET server = ? WHERE server = ?",
+		"UPDATE roles SET server = ? WHERE server = ?",
+	},
+	"room": {
+		"UPDATE rooms SET hash = ? WHERE hash = ?",
+	},
+}
+
+// ids replaces the name based hashes of servers and rooms with time-ordered IDs. The
+// old hashes are kept as aliases, so that existing URLs keep working. Invites keep
+// their hash until they expire
+var ids = Migration{
+	Version: 6,
+	Name:    "ids",
+	Up: func(tx *sqlx.Tx, d schemas.Dialect) error {
+		_, err := tx.Exec(schemas.Aliases(d))
+		if err != nil {
+			return err
+		}
+
+		for kind, table := range map[string]string{"server": "servers", "room": "rooms"} {
+			var hashes []string
+			err = tx.Select(&hashes, "SELECT hash FROM "+table)
+			if err != nil {
+				return err
+			}
+
+			for _, hash := range hashes {
+				newID, err := id.New()
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(tx.Rebind("INSERT INTO aliases (kind, alias, id) VALUES (?, ?, ?)"),
+					kind,
+					hash,
+					newID,
+				)
+				if err != nil {
+					return err
+				}
+
+				err = replaceID(tx, kind, hash, newID)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	},
+	Down: func(tx *sqlx.Tx, d schemas.Dialect) error {
+		var aliases []struct {
+			Kind  string `db:"kind"`
+			Alias string `db:"alias"`
+			ID    string `db:"id"`
+		}
+
+		err := tx.Select(&aliases, "SELECT kind, alias, id FROM aliases")
+		if err != nil {
+			return err
+		}
+
+		for _, alias := range aliases {
+			err = replaceID(tx, alias.Kind, alias.ID, alias.Alias)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec("DROP TABLE IF EXISTS aliases")
+		return err
+	},
+}
+
+// replaceID replaces the server or room identifier 'old' with 'new' in all tables
+func replaceID(tx *sqlx.Tx, kind, old, new string) error {
+	for _, statement := range idReferences[kind] {
+		_, err := tx.Exec(tx.Rebind(statement), new, old)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
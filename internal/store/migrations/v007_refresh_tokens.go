@@ -0,0 +1,23 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// refreshTokens creates the refresh_tokens table
+var refreshTokens = Migration{
+	Version: 7,
+	Name:    "refresh_tokens",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.RefreshTokens(d),
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"DROP TABLE IF EXISTS refresh_tokens",
+		}
+	}),
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"chapper.dev/server/internal/constants"
+	"chapper.dev/server/internal/store/schemas"
+)
+
+// twofa creates the login_challenges table and widens the 2FA columns of users, which
+// are too short for TOTP secrets. twofa_verify holds the secret of an enrollment until
+// it is confirmed. Reverting keeps the wider columns, because existing secrets would
+// not fit anymore
+var twofa = Migration{
+	Version: 8,
+	Name:    "twofa",
+	Up: Statements(func(d schemas.Dialect) []string {
+		statements := []string{
+			schemas.LoginChallenges(d),
+		}
+
+		// SQLite doesn't enforce the length of VARCHAR columns
+		switch d.Driver {
+		case constants.StoreDriverMySQL:
+			statements = append(statements,
+				"ALTER TABLE users MODIFY twofa_secret VARCHAR(64) DEFAULT NULL",
+				"ALTER TABLE users MODIFY twofa_verify VARCHAR(64) DEFAULT NULL",
+			)
+		case constants.StoreDriverPostgres:
+			statements = append(statements,
+				"ALTER TABLE users ALTER COLUMN twofa_secret TYPE VARCHAR(64)",
+				"ALTER TABLE users ALTER COLUMN twofa_verify TYPE VARCHAR(64)",
+			)
+		}
+		return statements
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"DROP TABLE IF EXISTS login_challenges",
+		}
+	}),
+}
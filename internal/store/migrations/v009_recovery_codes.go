@@ -0,0 +1,23 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// recoveryCodes creates the recovery_codes table
+var recoveryCodes = Migration{
+	Version: 9,
+	Name:    "recovery_codes",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.RecoveryCodes(d),
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"DROP TABLE IF EXISTS recovery_codes",
+		}
+	}),
+}
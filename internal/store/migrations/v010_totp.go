@@ -0,0 +1,30 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// totp adds the TOTP parameters and the time step of the last used code to users. The
+// defaults match the parameters which were used for all enrollments before
+var totp = Migration{
+	Version: 10,
+	Name:    "totp",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE users ADD COLUMN twofa_algorithm VARCHAR(10) NOT NULL DEFAULT 'SHA1'",
+			"ALTER TABLE users ADD COLUMN twofa_digits INTEGER NOT NULL DEFAULT 6",
+			"ALTER TABLE users ADD COLUMN twofa_period INTEGER NOT NULL DEFAULT 30",
+			"ALTER TABLE users ADD COLUMN twofa_last_step BIGINT NOT NULL DEFAULT 0",
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE users DROP COLUMN twofa_algorithm",
+			"ALTER TABLE users DROP COLUMN twofa_digits",
+			"ALTER TABLE users DROP COLUMN twofa_period",
+			"ALTER TABLE users DROP COLUMN twofa_last_step",
+		}
+	}),
+}
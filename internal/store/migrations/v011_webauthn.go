@@ -0,0 +1,31 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// webauthn creates the webauthn_credentials table, adds the WebAuthn user handle to
+// users and lets login challenges carry the session of a WebAuthn ceremony
+var webauthn = Migration{
+	Version: 11,
+	Name:    "webauthn",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.WebAuthnCredentials(d),
+			"ALTER TABLE users ADD COLUMN webauthn_id VARCHAR(64) DEFAULT NULL",
+			"ALTER TABLE login_challenges ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'password'",
+			"ALTER TABLE login_challenges ADD COLUMN session VARCHAR(4096) NOT NULL DEFAULT ''",
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"DELETE FROM login_challenges WHERE kind <> 'password'",
+			"ALTER TABLE login_challenges DROP COLUMN session",
+			"ALTER TABLE login_challenges DROP COLUMN kind",
+			"ALTER TABLE users DROP COLUMN webauthn_id",
+			"DROP TABLE IF EXISTS webauthn_credentials",
+		}
+	}),
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// mail creates the mail_tokens table and adds the verification state of the email
+// address to users
+var mail = Migration{
+	Version: 12,
+	Name:    "mail",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.MailTokens(d),
+			"ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT false",
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE users DROP COLUMN email_verified",
+			"DROP TABLE IF EXISTS mail_tokens",
+		}
+	}),
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// sessions creates the sessions table. Logins which still have refresh tokens become
+// sessions without device information, so that they stay valid
+var sessions = Migration{
+	Version: 13,
+	Name:    "sessions",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.Sessions(d),
+			`INSERT INTO sessions (id, username, created_at, last_seen_at, expires_at)
+			SELECT family, username, MIN(created_at), MAX(created_at), MAX(expires_at)
+			FROM refresh_tokens
+			WHERE revoked = false
+			GROUP BY family, username`,
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"DROP TABLE IF EXISTS sessions",
+		}
+	}),
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// signingKeys creates the signing_keys table. The keyring generates the first key on
+// the next start
+var signingKeys = Migration{
+	Version: 14,
+	Name:    "signing_keys",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.SigningKeys(d),
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"DROP TABLE IF EXISTS signing_keys",
+		}
+	}),
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// identities creates the identities table, which links users created by single
+// sign-on to their accounts at the identity provider
+var identities = Migration{
+	Version: 15,
+	Name:    "identities",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.Identities(d),
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"DROP TABLE IF EXISTS identities",
+		}
+	}),
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// disabledUsers adds the disabled state to users, which is set for users who were
+// removed from an external directory
+var disabledUsers = Migration{
+	Version: 16,
+	Name:    "disabled_users",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT false",
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE users DROP COLUMN disabled",
+		}
+	}),
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import "chapper.dev/server/internal/store/schemas"
+
+// personalAccessTokens creates the personal_access_tokens table and adds the bot flag
+// and the owner of bots to users
+var personalAccessTokens = Migration{
+	Version: 17,
+	Name:    "personal_access_tokens",
+	Up: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			schemas.PersonalAccessTokens(d),
+			"ALTER TABLE users ADD COLUMN bot BOOLEAN NOT NULL DEFAULT false",
+			"ALTER TABLE users ADD COLUMN bot_owner VARCHAR(100) DEFAULT NULL",
+		}
+	}),
+	Down: Statements(func(d schemas.Dialect) []string {
+		return []string{
+			"ALTER TABLE users DROP COLUMN bot_owner",
+			"ALTER TABLE users DROP COLUMN bot",
+			"DROP TABLE IF EXISTS personal_access_tokens",
+		}
+	}),
+}
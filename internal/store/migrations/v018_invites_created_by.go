@@ -0,0 +1,54 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"chapper.dev/server/internal/constants"
+	"chapper.dev/server/internal/store/schemas"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// invitesCreatedBy renames the misspelled column creayted_by of invites to created_by.
+// Installations adopted by the initial migration kept the old name, because the table
+// already existed. Reverting keeps the corrected name, which newer installations had
+// from the start
+var invitesCreatedBy = Migration{
+	Version: 18,
+	Name:    "invites_created_by",
+	Up: func(tx *sqlx.Tx, d schemas.Dialect) error {
+		var query string
+		switch d.Driver {
+		case constants.StoreDriverMySQL:
+			query = `SELECT COUNT(*) FROM information_schema.columns
+				WHERE table_schema = DATABASE() AND table_name = 'invites' AND column_name = 'creayted_by'`
+		case constants.StoreDriverPostgres:
+			query = `SELECT COUNT(*) FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'invites' AND column_name = 'creayted_by'`
+		default:
+			query = "SELECT COUNT(*) FROM pragma_table_info('invites') WHERE name = 'creayted_by'"
+		}
+
+		// A failed query would abort the transaction in PostgreSQL, so the column is
+		// looked up instead of selected
+		var misspelled int
+		err := tx.Get(&misspelled, query)
+		if err != nil || misspelled == 0 {
+			return err
+		}
+
+		// RENAME COLUMN requires MySQL 8.0 or MariaDB 10.5
+		statement := "ALTER TABLE invites RENAME COLUMN creayted_by TO created_by"
+		if d.Driver == constants.StoreDriverMySQL {
+			statement = "ALTER TABLE invites CHANGE creayted_by created_by VARCHAR(100) NOT NULL"
+		}
+
+		_, err = tx.Exec(statement)
+		return err
+	},
+	Down: func(tx *sqlx.Tx, d schemas.Dialect) error {
+		return nil
+	},
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package schemas
+
+import "fmt"
+
+// Aliases returns the schema of the table which maps legacy hashes of servers and rooms
+// to their IDs in the provided dialect
+func Aliases(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS aliases (
+	kind VARCHAR(10) NOT NULL,
+	alias VARCHAR(32) NOT NULL,
+	id VARCHAR(32) NOT NULL,
+	PRIMARY KEY (kind, alias)
+) %s;
+`, d.TableOptions)
+}
@@ -0,0 +1,21 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package schemas
+
+import "fmt"
+
+// Identities returns the schema of the table which links users to their accounts at
+// external identity providers in the provided dialect
+func Identities(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS identities (
+	issuer VARCHAR(255) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	username VARCHAR(100) NOT NULL,
+	created_at %s NOT NULL,
+	PRIMARY KEY (issuer, subject)
+) %s;
+`, d.DateTime, d.TableOptions)
+}
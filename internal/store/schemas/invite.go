@@ -4,13 +4,18 @@
 
 package schemas
 
-const Invites = `
+import "fmt"
+
+// Invites returns the invites schema in the provided dialect
+func Invites(d Dialect) string {
+	return fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS invites (
 	hash VARCHAR(32) NOT NULL,
-	creayted_by VARCHAR(100) NOT NULL,
+	created_by VARCHAR(100) NOT NULL,
 	server VARCHAR(100) NOT NULL,
 	one_time_use BOOLEAN DEFAULT false,
-	expires_at DATETIME DEFAULT NULL,
+	expires_at %s DEFAULT NULL,
 	PRIMARY KEY (hash)
-);
-`
+) %s;
+`, d.DateTime, d.TableOptions)
+}
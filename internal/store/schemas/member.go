@@ -0,0 +1,20 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package schemas
+
+import "fmt"
+
+// Members returns the schema of the table which assigns users to virtual servers in
+// the provided dialect
+func Members(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS members (
+	server VARCHAR(32) NOT NULL,
+	username VARCHAR(100) NOT NULL,
+	joined_at %s NOT NULL,
+	PRIMARY KEY (server, username)
+) %s;
+`, d.DateTime, d.TableOptions)
+}
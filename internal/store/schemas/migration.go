@@ -0,0 +1,20 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package schemas
+
+import "fmt"
+
+// Migrations returns the schema of the table which keeps track of applied migrations in
+// the provided dialect
+func Migrations(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	applied_at %s NOT NULL,
+	PRIMARY KEY (version)
+) %s;
+`, d.DateTime, d.TableOptions)
+}
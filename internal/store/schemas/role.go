@@ -0,0 +1,80 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package schemas
+
+import "fmt"
+
+// Roles returns the roles schema in the provided dialect
+func Roles(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS roles (
+	id %s,
+	name VARCHAR(100) NOT NULL,
+	description TEXT DEFAULT NULL,
+	builtin BOOLEAN NOT NULL DEFAULT false,
+	PRIMARY KEY (id),
+	UNIQUE (name)
+) %s;
+`, d.AutoIncrement, d.TableOptions)
+}
+
+// ServerRoles returns the schema of the roles table after roles were scoped to virtual
+// servers in the provided dialect. Role names are unique per server, global roles use
+// an empty server. The table name is configurable to allow rebuilding the roles table
+func ServerRoles(d Dialect, table string) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	id %s,
+	server VARCHAR(32) NOT NULL DEFAULT '',
+	name VARCHAR(100) NOT NULL,
+	description TEXT DEFAULT NULL,
+	builtin BOOLEAN NOT NULL DEFAULT false,
+	PRIMARY KEY (id),
+	UNIQUE (server, name)
+) %s;
+`, table, d.AutoIncrement, d.TableOptions)
+}
+
+// Privileges returns the privileges schema in the provided dialect. Each role has
+// exactly one row of privileges
+func Privileges(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS privileges (
+	id %s,
+	role_id INTEGER NOT NULL,
+	can_create_server BOOLEAN NOT NULL DEFAULT false,
+	can_delete_server BOOLEAN NOT NULL DEFAULT false,
+	can_edit_server BOOLEAN NOT NULL DEFAULT false,
+	can_see_all_servers BOOLEAN NOT NULL DEFAULT false,
+	can_create_room BOOLEAN NOT NULL DEFAULT false,
+	can_delete_room BOOLEAN NOT NULL DEFAULT false,
+	can_edit_room BOOLEAN NOT NULL DEFAULT false,
+	can_create_invite BOOLEAN NOT NULL DEFAULT false,
+	can_delete_invite BOOLEAN NOT NULL DEFAULT false,
+	can_kick_user_from_room BOOLEAN NOT NULL DEFAULT false,
+	can_kick_user_from_server BOOLEAN NOT NULL DEFAULT false,
+	can_ban_user_from_room BOOLEAN NOT NULL DEFAULT false,
+	can_ban_user_from_server BOOLEAN NOT NULL DEFAULT false,
+	can_create_role BOOLEAN NOT NULL DEFAULT false,
+	can_delete_role BOOLEAN NOT NULL DEFAULT false,
+	can_assign_role_to_user BOOLEAN NOT NULL DEFAULT false,
+	can_remove_role_from_user BOOLEAN NOT NULL DEFAULT false,
+	PRIMARY KEY (id),
+	UNIQUE (role_id)
+) %s;
+`, d.AutoIncrement, d.TableOptions)
+}
+
+// UserRoles returns the schema of the table which assigns roles to users in the
+// provided dialect
+func UserRoles(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS user_roles (
+	username VARCHAR(100) NOT NULL,
+	role_id INTEGER NOT NULL,
+	PRIMARY KEY (username, role_id)
+) %s;
+`, d.TableOptions)
+}
@@ -4,12 +4,17 @@
 
 package schemas
 
-const Rooms = `
+import "fmt"
+
+// Rooms returns the rooms schema in the provided dialect
+func Rooms(d Dialect) string {
+	return fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS rooms (
 	hash VARCHAR(32) NOT NULL,
 	name VARCHAR(100) NOT NULL,
 	type VARCHAR(10) DEFAULT NULL,
 	description TEXT DEFAULT NULL,
 	PRIMARY KEY (hash)
-);
-`
+) %s;
+`, d.TableOptions)
+}
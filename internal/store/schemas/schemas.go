@@ -5,6 +5,46 @@
 // Package schemas provides database schemas
 package schemas
 
-func All() []string {
-	return []string{Users, Servers, Rooms, Invites}
+import (
+	"chapper.dev/server/internal/constants"
+)
+
+// Dialect describes the differences in SQL syntax between the supported database
+// drivers
+type Dialect struct {
+	Driver        string
+	DateTime      string
+	AutoIncrement string
+	TableOptions  string
+}
+
+var (
+	// MySQL is the dialect used by MySQL and MariaDB
+	MySQL = Dialect{
+		Driver:        constants.StoreDriverMySQL,
+		DateTime:      "DATETIME",
+		AutoIncrement: "INTEGER NOT NULL AUTO_INCREMENT",
+		TableOptions:  constants.StoreTableOptions,
+	}
+
+	// Postgres is the dialect used by PostgreSQL
+	Postgres = Dialect{
+		Driver:        constants.StoreDriverPostgres,
+		DateTime:      "TIMESTAMP",
+		AutoIncrement: "SERIAL",
+		TableOptions:  "",
+	}
+
+	// SQLite is the dialect used by SQLite
+	SQLite = Dialect{
+		Driver:        constants.StoreDriverSQLite,
+		DateTime:      "DATETIME",
+		AutoIncrement: "INTEGER NOT NULL",
+		TableOptions:  "",
+	}
+)
+
+// All returns all schemas in the provided dialect
+func All(d Dialect) []string {
+	return []string{Users(d), Servers(d), Rooms(d), Invites(d)}
 }
@@ -4,12 +4,17 @@
 
 package schemas
 
-const Servers = `
+import "fmt"
+
+// Servers returns the servers schema in the provided dialect
+func Servers(d Dialect) string {
+	return fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS servers (
 	hash VARCHAR(32) NOT NULL,
 	name VARCHAR(100) NOT NULL,
 	description TEXT DEFAULT NULL,
 	image VARCHAR(32) DEFAULT NULL,
 	PRIMARY KEY (hash)
-);
-`
+) %s;
+`, d.TableOptions)
+}
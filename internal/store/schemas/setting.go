@@ -0,0 +1,22 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package schemas
+
+import "fmt"
+
+// Settings returns the settings schema in the provided dialect. The table only ever
+// contains one row with the id 1
+func Settings(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS settings (
+	id INTEGER NOT NULL,
+	is_installed BOOLEAN NOT NULL DEFAULT false,
+	superadmin_exists BOOLEAN NOT NULL DEFAULT false,
+	instance_name VARCHAR(100) NOT NULL DEFAULT '',
+	instance_description TEXT DEFAULT NULL,
+	PRIMARY KEY (id)
+) %s;
+`, d.TableOptions)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package schemas
+
+import "fmt"
+
+// RefreshTokens returns the schema of the table which stores the SHA-256 hashes of
+// refresh tokens in the provided dialect. Tokens which were rotated out of the same
+// login share the family
+func RefreshTokens(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	hash VARCHAR(64) NOT NULL,
+	family VARCHAR(32) NOT NULL,
+	username VARCHAR(100) NOT NULL,
+	replaced_by VARCHAR(64) NOT NULL DEFAULT '',
+	revoked BOOLEAN NOT NULL DEFAULT false,
+	created_at %s NOT NULL,
+	expires_at %s NOT NULL,
+	PRIMARY KEY (hash)
+) %s;
+`, d.DateTime, d.DateTime, d.TableOptions)
+}
+
+// LoginChallenges returns the schema of the table which stores the SHA-256 hashes of
+// the challenges users with 2FA receive after providing their password in the provided
+// dialect
+func LoginChallenges(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS login_challenges (
+	hash VARCHAR(64) NOT NULL,
+	username VARCHAR(100) NOT NULL,
+	expires_at %s NOT NULL,
+	PRIMARY KEY (hash)
+) %s;
+`, d.DateTime, d.TableOptions)
+}
+
+// RecoveryCodes returns the schema of the table which stores the hashes of 2FA recovery
+// codes in the provided dialect
+func RecoveryCodes(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	username VARCHAR(100) NOT NULL,
+	id VARCHAR(8) NOT NULL,
+	hash VARCHAR(512) NOT NULL,
+	PRIMARY KEY (username, id)
+) %s;
+`, d.TableOptions)
+}
+
+// WebAuthnCredentials returns the schema of the table which stores the public key
+// credentials of WebAuthn authenticators in the provided dialect
+func WebAuthnCredentials(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+	id VARCHAR(255) NOT NULL,
+	username VARCHAR(100) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	public_key VARCHAR(1024) NOT NULL,
+	attestation_type VARCHAR(32) NOT NULL,
+	aaguid VARCHAR(32) NOT NULL,
+	sign_count BIGINT NOT NULL DEFAULT 0,
+	created_at %s NOT NULL,
+	last_used_at %s DEFAULT NULL,
+	PRIMARY KEY (id)
+) %s;
+`, d.DateTime, d.DateTime, d.TableOptions)
+}
+
+// MailTokens returns the schema of the table which stores the SHA-256 hashes of the
+// tokens sent by mail to verify email addresses and to reset passwords in the
+// provided dialect
+func MailTokens(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS mail_tokens (
+	hash VARCHAR(64) NOT NULL,
+	username VARCHAR(100) NOT NULL,
+	kind VARCHAR(20) NOT NULL,
+	email VARCHAR(100) NOT NULL,
+	expires_at %s NOT NULL,
+	PRIMARY KEY (hash)
+) %s;
+`, d.DateTime, d.TableOptions)
+}
+
+// Sessions returns the schema of the table which stores the logins of users in the
+// provided dialect. The ID of a session is the family of its refresh tokens
+func Sessions(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS sessions (
+	id VARCHAR(32) NOT NULL,
+	username VARCHAR(100) NOT NULL,
+	name VARCHAR(100) NOT NULL DEFAULT '',
+	ip VARCHAR(45) NOT NULL DEFAULT '',
+	user_agent VARCHAR(255) NOT NULL DEFAULT '',
+	created_at %s NOT NULL,
+	last_seen_at %s NOT NULL,
+	expires_at %s NOT NULL,
+	PRIMARY KEY (id)
+) %s;
+`, d.DateTime, d.DateTime, d.DateTime, d.TableOptions)
+}
+
+// SigningKeys returns the schema of the table which stores the keys of the keyring
+// which signs access tokens in the provided dialect
+func SigningKeys(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS signing_keys (
+	id VARCHAR(32) NOT NULL,
+	algorithm VARCHAR(10) NOT NULL,
+	private_key TEXT NOT NULL,
+	created_at %s NOT NULL,
+	retired_at %s DEFAULT NULL,
+	PRIMARY KEY (id)
+) %s;
+`, d.DateTime, d.DateTime, d.TableOptions)
+}
+
+// PersonalAccessTokens returns the schema of the table which stores the SHA-256 hashes
+// of personal access tokens and their scopes in the provided dialect
+func PersonalAccessTokens(d Dialect) string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+	id VARCHAR(32) NOT NULL,
+	hash VARCHAR(64) NOT NULL,
+	username VARCHAR(100) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	scopes VARCHAR(512) NOT NULL,
+	created_by VARCHAR(100) NOT NULL,
+	created_at %s NOT NULL,
+	last_used_at %s DEFAULT NULL,
+	expires_at %s DEFAULT NULL,
+	PRIMARY KEY (id),
+	UNIQUE (hash)
+) %s;
+`, d.DateTime, d.DateTime, d.DateTime, d.TableOptions)
+}
@@ -4,7 +4,11 @@
 
 package schemas
 
-const Users = `
+import "fmt"
+
+// Users returns the users schema in the provided dialect
+func Users(d Dialect) string {
+	return fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS users (
 	username VARCHAR(100) NOT NULL,
 	password VARCHAR(512) NOT NULL,
@@ -13,5 +17,6 @@ CREATE TABLE IF NOT EXISTS users (
 	twofa_secret VARCHAR(16) DEFAULT NULL,
 	twofa_verify VARCHAR(16) DEFAULT NULL,
 	PRIMARY KEY (username)
-);
-`
+) %s;
+`, d.TableOptions)
+}
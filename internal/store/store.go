@@ -6,9 +6,14 @@
 package store
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"chapper.dev/server/internal/config"
 	"chapper.dev/server/internal/constants"
@@ -16,43 +21,90 @@ import (
 
 	_ "github.com/go-sql-driver/mysql" // MySQL driver
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"           // PostgreSQL driver
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+	"gopkg.in/guregu/null.v4"
 )
 
-// Store wraps a sqlx database connection
-type Store struct {
-	conn     *sqlx.DB
-	settings *Settings
+// SQL implements Store on top of a sqlx database connection
+type SQL struct {
+	conn         *sqlx.DB
+	dialect      schemas.Dialect
+	settings     *Settings
+	settingsLock sync.RWMutex
 }
 
 var (
 	// ErrInvalidDatabaseType indicates the provided database type is not supported
 	ErrInvalidDatabaseType = errors.New("Invalid database type")
+
+	// ErrNotFound indicates the requested entry doesn't exist
+	ErrNotFound = errors.New("Not found")
+
+	// ErrDuplicate indicates an entry with the same key already exists
+	ErrDuplicate = errors.New("Duplicate entry")
+
+	// ErrLastHolder indicates the user is the only one who holds the role
+	ErrLastHolder = errors.New("Last holder of the role")
 )
 
 // Settings holds settings data
 type Settings struct {
-	ID               uint
-	IsInstalled      bool
-	SuperadminExists bool
+	ID                  uint        `json:"-" db:"id"`
+	IsInstalled         bool        `json:"is_installed" db:"is_installed"`
+	SuperadminExists    bool        `json:"superadmin_exists" db:"superadmin_exists"`
+	InstanceName        string      `json:"instance_name" db:"instance_name"`
+	InstanceDescription null.String `json:"instance_description" db:"instance_description"`
 }
 
 // DefaultSettings provide the default values for settings
 var DefaultSettings = &Settings{
+	ID:               1,
 	IsInstalled:      false,
 	SuperadminExists: false,
 }
 
-// New returns a new store instance
-func New(t string, options config.StoreOptions) (*Store, error) {
+// New returns a new SQL store instance for the database type t
+func New(t string, options config.StoreOptions) (*SQL, error) {
 	switch strings.ToLower(t) {
 	case "mysql":
-		conn, err := sqlx.Open("mysql", DSN(options))
+		conn, err := sqlx.Open(constants.StoreDriverMySQL, DSN(options))
+		if err != nil {
+			return nil, err
+		}
+
+		return &SQL{
+			conn:    conn,
+			dialect: schemas.MySQL,
+		}, nil
+	case "postgres", "postgresql":
+		conn, err := sqlx.Open(constants.StoreDriverPostgres, PostgresDSN(options))
+		if err != nil {
+			return nil, err
+		}
+
+		return &SQL{
+			conn:    conn,
+			dialect: schemas.Postgres,
+		}, nil
+	case "sqlite", "sqlite3":
+		err := os.MkdirAll(filepath.Dir(options.Path), 0755)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := sqlx.Open(constants.StoreDriverSQLite, SQLiteDSN(options))
 		if err != nil {
 			return nil, err
 		}
 
-		return &Store{
-			conn: conn,
+		// SQLite only supports one writer at a time, serialize all access to avoid
+		// 'database is locked' errors
+		conn.SetMaxOpenConns(1)
+
+		return &SQL{
+			conn:    conn,
+			dialect: schemas.SQLite,
 		}, nil
 	default:
 		return nil, ErrInvalidDatabaseType
@@ -72,28 +124,76 @@ func DSN(options config.StoreOptions) string {
 	)
 }
 
-// Migrate migrates the neccesary database tables
-func (s *Store) Migrate() error {
-	for _, scheme := range schemas.All() {
-		_, err := s.conn.Exec(scheme)
-		if err != nil {
-			return err
-		}
+// PostgresDSN returns a data source name for a PostgreSQL database connection, refer
+// https://pkg.go.dev/github.com/lib/pq#hdr-Connection_String_Parameters
+func PostgresDSN(options config.StoreOptions) string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s&%s",
+		url.QueryEscape(options.User),
+		url.QueryEscape(options.Password),
+		options.Host,
+		options.Port,
+		options.Database,
+		options.SSLMode,
+		constants.StorePostgresParams,
+	)
+}
+
+// SQLiteDSN returns a data source name for a SQLite database file, refer
+// https://github.com/mattn/go-sqlite3#connection-string
+func SQLiteDSN(options config.StoreOptions) string {
+	return fmt.Sprintf("file:%s?%s",
+		options.Path,
+		constants.StoreSQLiteParams,
+	)
+}
+
+// Dialect returns the SQL dialect of the underlying database
+func (s *SQL) Dialect() schemas.Dialect {
+	return s.dialect
+}
+
+// notFound maps the 'no rows' error of the sql package to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
 	}
-	return nil
+	return err
 }
 
-// GetSettings returns the settings or creates a new default entry in the database
-func (s *Store) GetSettings() (*Settings, error) {
-	if s.settings != nil {
-		return s.settings, nil
+// Ensure SQL implements all store interfaces
+var _ Store = (*SQL)(nil)
+
+// transaction runs fn inside a transaction. The transaction is rolled back if fn returns
+// an error and committed otherwise
+func (s *SQL) transaction(fn func(tx *sqlx.Tx) error) error {
+	tx, err := s.conn.Beginx()
+	if err != nil {
+		return err
 	}
-	// TODO <2020/10/12>: Re-implement this
-	return nil, nil
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// SetSettings sets settings and saves them
-// func (s *Store) SetSettings(settings *Settings) error {
-// 	s.settings = settings
-// 	return s.Ctx().Model(settings).Updates(settings).Error
-// }
+// insert executes the insert query and returns the generated ID of the new row.
+// PostgreSQL doesn't support LastInsertId, the ID is returned via RETURNING instead
+func (s *SQL) insert(ext sqlx.Ext, query string, args ...interface{}) (uint, error) {
+	if s.dialect.Driver == constants.StoreDriverPostgres {
+		var id uint
+		err := ext.QueryRowx(ext.Rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := ext.Exec(ext.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	return uint(id), err
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2021-present Techassi
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package testutil provides the environment shared by the tests of the services and the
+// router: a validated config, a logger, the memory store and a keyring
+package testutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chapper.dev/server/internal/config"
+	"chapper.dev/server/internal/log"
+	"chapper.dev/server/internal/modules/jwt"
+	"chapper.dev/server/internal/store/memory"
+)
+
+// Env is the environment of one test. Files are written to a temporary directory which
+// is removed when the test finished
+type Env struct {
+	Config *config.Config
+	Logger *log.Logger
+	Store  *memory.Store
+	Keys   *jwt.Keyring
+}
+
+// New returns a new environment. The config can be changed by configure before it is
+// validated
+func New(t testing.TB, configure func(*config.Config)) *Env {
+	dir, err := ioutil.TempDir("", "chapper-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	cfg := config.NewDefault()
+	cfg.Log.Path = filepath.Join(dir, "chapper.log")
+	cfg.Router.AvatarPath = dir
+	cfg.Router.WebPath = dir
+
+	// Cheap password hashes keep the tests fast
+	cfg.Hash.Argon2Memory = 64
+	cfg.Hash.Argon2Iterations = 1
+	cfg.Hash.Argon2Parallelism = 1
+
+	if configure != nil {
+		configure(cfg)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := log.New(cfg.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := memory.New()
+	keys, err := jwt.NewKeyring(s, cfg.Router.KeyringOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Env{
+		Config: cfg,
+		Logger: logger,
+		Store:  s,
+		Keys:   keys,
+	}
+}
@@ -8,6 +8,7 @@ package bridge
 import (
 	"errors"
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/websocket"
 	"github.com/pion/webrtc/v2"
@@ -34,15 +35,19 @@ var connectionConfig = webrtc.Configuration{
 	SDPSemantics: webrtc.SDPSemanticsUnifiedPlanWithFallback,
 }
 
-// Bridge keeps track of active rooms
+// Bridge keeps track of active rooms and of the users connected by each login session
 type Bridge struct {
 	rooms map[string]*Room
+
+	sessionsLock sync.Mutex
+	sessions     map[string]map[*User]bool
 }
 
 // NewBridge returns a new bridge
 func NewBridge() *Bridge {
 	return &Bridge{
-		rooms: make(map[string]*Room),
+		rooms:    make(map[string]*Room),
+		sessions: make(map[string]map[*User]bool),
 	}
 }
 
@@ -95,8 +100,9 @@ func (b *Bridge) RemoveRoom(roomHash string) error {
 	return nil
 }
 
-// Connect connects a user with 'username' to room and sets up the sognaling websocket
-func (b *Bridge) Connect(username, roomHash string, w http.ResponseWriter, r *http.Request) error {
+// Connect connects a user with 'username' to room and sets up the sognaling websocket.
+// The connection is closed when the login session with 'sessionID' is closed
+func (b *Bridge) Connect(username, sessionID, roomHash string, w http.ResponseWriter, r *http.Request) error {
 	conn, err := wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return err
@@ -120,6 +126,8 @@ func (b *Bridge) Connect(username, roomHash string, w http.ResponseWriter, r *ht
 	user := NewUser(username, conn, pc, room)
 	user.AddListeners()
 
+	b.track(sessionID, user)
+
 	user.room.Join(user)
 
 	go user.startRead()
@@ -127,3 +135,42 @@ func (b *Bridge) Connect(username, roomHash string, w http.ResponseWriter, r *ht
 
 	return user.SendEventUser()
 }
+
+// CloseSession disconnects all users which connected with the login session with 'id'.
+// Closing the websocket makes the user leave the room and closes the peer connection
+func (b *Bridge) CloseSession(id string) {
+	b.sessionsLock.Lock()
+	users := b.sessions[id]
+	delete(b.sessions, id)
+	b.sessionsLock.Unlock()
+
+	for user := range users {
+		user.conn.Close()
+	}
+}
+
+// track remembers the session of user until the user disconnects
+func (b *Bridge) track(sessionID string, user *User) {
+	if sessionID == "" {
+		return
+	}
+
+	b.sessionsLock.Lock()
+	if b.sessions[sessionID] == nil {
+		b.sessions[sessionID] = make(map[*User]bool)
+	}
+	b.sessions[sessionID][user] = true
+	b.sessionsLock.Unlock()
+
+	go func() {
+		<-user.done
+
+		b.sessionsLock.Lock()
+		defer b.sessionsLock.Unlock()
+
+		delete(b.sessions[sessionID], user)
+		if len(b.sessions[sessionID]) == 0 {
+			delete(b.sessions, sessionID)
+		}
+	}()
+}
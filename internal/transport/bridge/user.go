@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
@@ -44,6 +45,7 @@ type User struct {
 	room          *Room                    // The room the user is in
 	conn          *websocket.Conn          // The underlying websocket connection to exchange data (signaling)
 	send          chan []byte              // Channel for outbound messages
+	done          chan struct{}            // Closed when the websocket connection is closed
 	pc            *webrtc.PeerConnection   // WebRTC peer connection
 	inTracks      map[uint32]*webrtc.Track // Incoming tracks (microphone)
 	inTracksLock  sync.RWMutex             // Incoming tracks lock
@@ -75,6 +77,7 @@ func NewUser(username string, conn *websocket.Conn, pc *webrtc.PeerConnection, r
 		room:      room,
 		conn:      conn,
 		send:      make(chan []byte, 256),
+		done:      make(chan struct{}),
 		pc:        pc,
 		inTracks:  make(map[uint32]*webrtc.Track),
 		outTracks: make(map[uint32]*webrtc.Track),
@@ -198,6 +201,7 @@ func (u *User) startRead() {
 		u.pc.Close()
 		u.room.Leave(u)
 		u.conn.Close()
+		close(u.done)
 	}()
 
 	u.conn.SetReadLimit(maxMessageSize)
@@ -465,7 +469,7 @@ func (u *User) WriteRTP(pkt *rtp.Packet) error {
 
 // AddTrack adds track to peer connection
 func (u *User) AddTrack(ssrc uint32) error {
-	track, err := u.pc.NewTrack(webrtc.DefaultPayloadTypeOpus, ssrc, string(ssrc), string(ssrc))
+	track, err := u.pc.NewTrack(webrtc.DefaultPayloadTypeOpus, ssrc, strconv.FormatUint(uint64(ssrc), 10), strconv.FormatUint(uint64(ssrc), 10))
 	if err != nil {
 		return err
 	}
@@ -126,18 +126,20 @@ func (h *Hub) AuthenticatePeer(username, token string) error {
 	return nil
 }
 
-// NewPeer creates, registers and returns a new peer. If opening the websocket connection
-// fails, an error is returned
-func (h *Hub) NewPeer(username string, w http.ResponseWriter, r *http.Request) (*Peer, error) {
+// NewPeer creates, registers and returns a new peer which connected with the login
+// session identified by sessionID. If opening the websocket connection fails, an error
+// is returned
+func (h *Hub) NewPeer(username, sessionID string, w http.ResponseWriter, r *http.Request) (*Peer, error) {
 	ws, err := h.wsFactory.Upgrade(w, r, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	peer := &Peer{
-		Username: username,
-		ws:       ws,
-		hub:      h,
+		Username:  username,
+		SessionID: sessionID,
+		ws:        ws,
+		hub:       h,
 	}
 
 	h.Lock()
@@ -147,6 +149,20 @@ func (h *Hub) NewPeer(username string, w http.ResponseWriter, r *http.Request) (
 	return peer, nil
 }
 
+// CloseSession disconnects and removes all peers which connected with the login session
+// identified by id
+func (h *Hub) CloseSession(id string) {
+	h.Lock()
+	defer h.Unlock()
+
+	for username, peer := range h.peers {
+		if peer.SessionID == id {
+			peer.ws.Close()
+			delete(h.peers, username)
+		}
+	}
+}
+
 // RegisterMessages registers an array of messages
 func (h *Hub) RegisterMessages(messages []Message) error {
 	for _, message := range messages {
@@ -12,13 +12,14 @@ import (
 var peerCtx = log.NewContext("messaging-peer")
 
 // Peer describes one client connected to the hub. Each peer has a unique username to
-// identify itself, a token to authenticate and the underlying websocket connection for
-// real-time communication
+// identify itself, the login session it connected with, a token to authenticate and the
+// underlying websocket connection for real-time communication
 type Peer struct {
-	Username string
-	Token    string
-	ws       *websocket.Conn
-	hub      *Hub
+	Username  string
+	SessionID string
+	Token     string
+	ws        *websocket.Conn
+	hub       *Hub
 }
 
 // Authenticate authenticates a peer. If the authentication fails, an error is returned